@@ -0,0 +1,175 @@
+// Package api is the local JSON HTTP API of `gorss serve`. It lists feeds and
+// articles, marks articles read or starred, adds and removes feeds, triggers
+// refreshes and reports health and summaries. It listens only on this
+// machine (loopback or a unix socket) and every request needs a bearer token;
+// other machines reach it through SSH.
+//
+// All paths are under /api/v1:
+//
+//	GET    /health                  scheduler and feed status
+//	GET    /feeds                   configured feeds with article counts
+//	POST   /feeds                   subscribe: {"name", "url", "full_text", "encoding"}
+//	DELETE /feeds/{name}            unsubscribe
+//	POST   /refresh                 fetch now: {"feeds": [...]}, all if empty
+//	GET    /articles                ?feed= &since= &filter= &unread= &starred= &limit= &content=
+//	GET    /articles/{id}           one article with its content
+//	PATCH  /articles/{id}           {"read": bool, "starred": bool}, either may be left out
+//	POST   /articles/mark           {"ids": [...] or "feed": name, "read": bool, "starred": bool}
+//	GET    /summaries               generated feed summaries
+//	GET    /summaries/{feed}
+//
+// Errors are returned as {"error": message} with a 4xx or 5xx status: 400
+// for an invalid request, 403 for feeds managed by a sync server, 404 for an
+// unknown feed or article, 409 for a feed that is already subscribed and 500
+// when the config or reading state can't be written.
+//
+// The TUI is not a client of this API. A TUI using the daemon's profile
+// shares its caches and reading state files, which are merged on every
+// save. A TUI in another terminal or on another machine attaches to the
+// daemon through its Google Reader API instead: give it a profile with
+// backend.type greader, backend.url set to http://<serve.greader.listen>
+// (through an SSH tunnel if need be) and the serve.greader username and
+// password, and it syncs feeds, articles and marks with the daemon, queueing
+// changes made while the daemon is unreachable.
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+// Daemon is the part of `gorss serve` the API controls: the feeds it
+// schedules and their fetches
+type Daemon interface {
+	Feeds() []config.Feed
+	Status() Status
+	// Refresh fetches the feeds now and reports the result of each
+	Refresh(feeds []config.Feed) []RefreshResult
+	// AddFeed subscribes to a feed, in the config file as well
+	AddFeed(f config.Feed) error
+	// RemoveFeed unsubscribes from a feed, in the config file as well
+	RemoveFeed(name string) (config.Feed, error)
+}
+
+// ErrFeedsManaged is returned (wrapped) by Daemon.AddFeed and RemoveFeed when
+// the feeds are managed elsewhere, e.g. on a sync server
+var ErrFeedsManaged = errors.New("feeds can't be changed here")
+
+// Status is the state of the fetch scheduler
+type Status struct {
+	Started     time.Time
+	LastRefresh time.Time // end of the last poll, zero before the first
+	NextRefresh time.Time
+	Feeds       map[string]FeedStatus // keyed by feed name
+}
+
+// FeedStatus is the result of the last fetch of a feed
+type FeedStatus struct {
+	LastFetch *time.Time `json:"last_fetch,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	Push      bool       `json:"push"` // updated by WebSub instead of polling
+}
+
+// RefreshResult is the outcome of fetching one feed
+type RefreshResult struct {
+	Feed  string `json:"feed"`
+	New   int    `json:"new"`
+	Error string `json:"error,omitempty"`
+}
+
+// Health is the response of GET /health
+type Health struct {
+	Status      string     `json:"status"` // "ok", or "degraded" if a feed failed its last fetch
+	Started     time.Time  `json:"started"`
+	Uptime      int64      `json:"uptime_seconds"`
+	LastRefresh *time.Time `json:"last_refresh,omitempty"`
+	NextRefresh *time.Time `json:"next_refresh,omitempty"`
+	Feeds       int        `json:"feeds"`
+	Articles    int        `json:"articles"`
+	Failing     []string   `json:"failing,omitempty"`
+}
+
+// Feed is a configured feed in GET /feeds
+type Feed struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	FullText bool   `json:"full_text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Articles int    `json:"articles"`
+	Unread   int    `json:"unread"`
+	FeedStatus
+}
+
+// Article is an article with its reading state
+type Article struct {
+	ID          string      `json:"id"`
+	Feed        string      `json:"feed"`
+	Title       string      `json:"title"`
+	Link        string      `json:"link,omitempty"`
+	Published   time.Time   `json:"published"`
+	Updated     *time.Time  `json:"updated,omitempty"`
+	Authors     []string    `json:"authors,omitempty"`
+	Categories  []string    `json:"categories,omitempty"`
+	Enclosures  []Enclosure `json:"enclosures,omitempty"`
+	CommentsURL string      `json:"comments_url,omitempty"`
+	Read        bool        `json:"read"`
+	Starred     bool        `json:"starred"`
+	ContentType string      `json:"content_type,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Content     string      `json:"content,omitempty"`
+}
+
+// Enclosure is a media file attached to an article
+type Enclosure struct {
+	URL    string `json:"url"`
+	Type   string `json:"type,omitempty"`
+	Length int64  `json:"length,omitempty"`
+}
+
+// Summary is a generated feed summary
+type Summary struct {
+	Feed         string    `json:"feed"`
+	Summary      string    `json:"summary"`
+	Generated    time.Time `json:"generated"`
+	ArticleCount int       `json:"article_count"`
+}
+
+// Mark is the body of PATCH /articles/{id} and POST /articles/mark; nil
+// fields are left as they are
+type Mark struct {
+	IDs     []string `json:"ids,omitempty"`
+	Feed    string   `json:"feed,omitempty"`
+	Read    *bool    `json:"read,omitempty"`
+	Starred *bool    `json:"starred,omitempty"`
+}
+
+func newArticle(a feed.Article, state *feed.State, content bool) Article {
+	out := Article{
+		ID:          a.ID,
+		Feed:        a.FeedName,
+		Title:       a.Title,
+		Link:        a.Link,
+		Published:   a.Published,
+		Authors:     a.Authors,
+		Categories:  a.Categories,
+		CommentsURL: a.CommentsURL,
+		Read:        state.IsRead(a.ID),
+		Starred:     state.IsStarred(a.ID),
+	}
+	if !a.Updated.IsZero() {
+		updated := a.Updated
+		out.Updated = &updated
+	}
+	for _, e := range a.Enclosures {
+		out.Enclosures = append(out.Enclosures, Enclosure{URL: e.URL, Type: e.Type, Length: e.Length})
+	}
+	if content {
+		out.ContentType = a.ContentType
+		out.Description = a.Description
+		out.Content = a.Content
+	}
+	return out
+}
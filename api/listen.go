@@ -0,0 +1,87 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JohanLi233/gorss/config"
+)
+
+// TokenFile returns where the generated token is kept
+func TokenFile(stateDir string) string {
+	return filepath.Join(stateDir, "api_token")
+}
+
+// Token returns the configured token, or the one in the state directory,
+// generating it on first use. The file is readable only by its owner, so
+// local clients of the same user can pick it up.
+func Token(cfg config.APIConfig, stateDir string) (string, error) {
+	if cfg.Token != "" {
+		return cfg.Token, nil
+	}
+	path := TokenFile(stateDir)
+	if data, err := os.ReadFile(path); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save API token: %w", err)
+	}
+	return token, nil
+}
+
+// Listen opens the listener for serve.api.listen: a loopback TCP address or
+// "unix:/path". A stale socket file from an earlier run is replaced, and the
+// socket is made accessible to its owner only.
+func Listen(addr string) (net.Listener, error) {
+	if err := config.CheckAPIListen(addr); err != nil {
+		return nil, err
+	}
+	socket, ok := strings.CutPrefix(addr, "unix:")
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+
+	if _, err := os.Stat(socket); err == nil {
+		// Refuse to take over a socket another gorss is still serving on
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohanLi233/gorss/config"
+)
+
+func TestListenLoopbackOnly(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:0", ":0", "[::]:0", "192.168.1.10:0", "example.com:0", "127.0.0.1", "unix:"} {
+		if l, err := Listen(addr); err == nil {
+			l.Close()
+			t.Errorf("Listen(%q) succeeded", addr)
+		}
+	}
+	for _, addr := range []string{"127.0.0.1:0", "localhost:0"} {
+		l, err := Listen(addr)
+		if err != nil {
+			t.Errorf("Listen(%q): %v", addr, err)
+			continue
+		}
+		if ip := l.Addr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+			t.Errorf("Listen(%q) is on %v", addr, ip)
+		}
+		l.Close()
+	}
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "run", "api.sock")
+	l, err := Listen("unix:" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode %v, want a socket with 0600", info.Mode())
+	}
+
+	// A socket that is being served on isn't taken over
+	if l2, err := Listen("unix:" + socket); err == nil {
+		l2.Close()
+		t.Error("listened on a socket in use")
+	}
+
+	// A stale socket left by a crashed process is replaced. Closing a unix
+	// listener removes its file, so make a stale one by hand.
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	if _, err := os.Stat(socket); err != nil {
+		t.Fatal(err)
+	}
+	l, err = Listen("unix:" + socket)
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	l.Close()
+}
+
+func TestToken(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	if token, err := Token(config.APIConfig{Token: "configured"}, dir); err != nil || token != "configured" {
+		t.Errorf("Token = %q, %v, want the configured token", token, err)
+	}
+
+	token, err := Token(config.APIConfig{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 64 {
+		t.Errorf("generated token %q", token)
+	}
+	info, err := os.Stat(TokenFile(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode %v, want 0600", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(TokenFile(dir))
+	if strings.TrimSpace(string(data)) != token {
+		t.Errorf("token file %q, want %q", data, token)
+	}
+	if again, err := Token(config.APIConfig{}, dir); err != nil || again != token {
+		t.Errorf("second Token = %q, %v, want the saved %q", again, err, token)
+	}
+}
@@ -0,0 +1,448 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+// Prefix is the path all endpoints are under
+const Prefix = "/api/v1"
+
+// maxBody limits request bodies; they are small JSON objects
+const maxBody = 1 << 20
+
+// Server serves the API
+type Server struct {
+	daemon   Daemon
+	fm       *feed.FeedManager
+	stateDir string
+	token    string
+	mux      *http.ServeMux
+}
+
+// NewServer creates the API server. The reading state is loaded from
+// stateDir for every request, so changes made in the TUI show up at once.
+func NewServer(d Daemon, fm *feed.FeedManager, stateDir, token string) *Server {
+	s := &Server{daemon: d, fm: fm, stateDir: stateDir, token: token, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET "+Prefix+"/health", s.health)
+	s.mux.HandleFunc("GET "+Prefix+"/feeds", s.listFeeds)
+	s.mux.HandleFunc("POST "+Prefix+"/feeds", s.addFeed)
+	s.mux.HandleFunc("DELETE "+Prefix+"/feeds/{name}", s.removeFeed)
+	s.mux.HandleFunc("POST "+Prefix+"/refresh", s.refresh)
+	s.mux.HandleFunc("GET "+Prefix+"/articles", s.listArticles)
+	s.mux.HandleFunc("GET "+Prefix+"/articles/{id}", s.getArticle)
+	s.mux.HandleFunc("PATCH "+Prefix+"/articles/{id}", s.markArticle)
+	s.mux.HandleFunc("POST "+Prefix+"/articles/mark", s.markArticles)
+	s.mux.HandleFunc("GET "+Prefix+"/summaries", s.listSummaries)
+	s.mux.HandleFunc("GET "+Prefix+"/summaries/{feed}", s.getSummary)
+	return s
+}
+
+// ServeHTTP checks the bearer token and dispatches the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gorss"`)
+		writeError(w, http.StatusUnauthorized, "missing or wrong token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// apiError is an error with the status to report it with
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &apiError{http.StatusNotFound, fmt.Sprintf(format, args...)}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// fail reports err, with its status if it is an apiError and 500 otherwise
+func fail(w http.ResponseWriter, err error) {
+	var e *apiError
+	if errors.As(err, &e) {
+		writeError(w, e.status, e.msg)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+// readJSON decodes the request body into v; an empty body leaves v alone
+func readJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) loadState() (*feed.State, error) {
+	state, _, err := feed.LoadState(s.stateDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load read state: %w", err)
+	}
+	return state, nil
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	status := s.daemon.Status()
+	h := Health{
+		Status:   "ok",
+		Started:  status.Started,
+		Uptime:   int64(time.Since(status.Started) / time.Second),
+		Feeds:    len(s.daemon.Feeds()),
+		Articles: len(s.fm.GetArticles()),
+	}
+	if !status.LastRefresh.IsZero() {
+		h.LastRefresh = &status.LastRefresh
+	}
+	if !status.NextRefresh.IsZero() {
+		h.NextRefresh = &status.NextRefresh
+	}
+	for name, fs := range status.Feeds {
+		if fs.LastError != "" {
+			h.Failing = append(h.Failing, name)
+		}
+	}
+	if len(h.Failing) > 0 {
+		sort.Strings(h.Failing)
+		h.Status = "degraded"
+	}
+	writeJSON(w, http.StatusOK, h)
+}
+
+func (s *Server) listFeeds(w http.ResponseWriter, r *http.Request) {
+	state, err := s.loadState()
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	status := s.daemon.Status()
+	feeds := s.daemon.Feeds()
+	index := make(map[string]int)
+	out := make([]Feed, 0, len(feeds))
+	for i, f := range feeds {
+		index[strings.ToLower(f.Name)] = i
+		out = append(out, Feed{Name: f.Name, URL: f.URL, FullText: f.FullText, Encoding: f.Encoding, FeedStatus: status.Feeds[f.Name]})
+	}
+	for _, a := range s.fm.GetArticles() {
+		i, ok := index[strings.ToLower(a.SourceName())]
+		if !ok {
+			continue
+		}
+		out[i].Articles++
+		if !state.IsRead(a.ID) {
+			out[i].Unread++
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) addFeed(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name     string `json:"name"`
+		URL      string `json:"url"`
+		FullText bool   `json:"full_text"`
+		Encoding string `json:"encoding"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		fail(w, err)
+		return
+	}
+	if body.Name == "" || body.URL == "" {
+		writeError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+	f := config.Feed{Name: body.Name, URL: body.URL, FullText: body.FullText, Encoding: body.Encoding}
+	if err := config.CheckFeed(f); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.daemon.AddFeed(f); err != nil {
+		fail(w, feedError(err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, Feed{Name: f.Name, URL: f.URL, FullText: f.FullText, Encoding: f.Encoding})
+}
+
+func (s *Server) removeFeed(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := s.findFeed(name); !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no feed named %q", name))
+		return
+	}
+	if _, err := s.daemon.RemoveFeed(name); err != nil {
+		fail(w, feedError(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// feedError gives an error from adding or removing a feed its status; the
+// rest, such as a config file that can't be written, are the server's
+func feedError(err error) error {
+	var dup *config.DuplicateFeedError
+	switch {
+	case errors.As(err, &dup):
+		return &apiError{http.StatusConflict, err.Error()}
+	case errors.Is(err, ErrFeedsManaged):
+		return &apiError{http.StatusForbidden, err.Error()}
+	}
+	return err
+}
+
+func (s *Server) findFeed(name string) (config.Feed, bool) {
+	for _, f := range s.daemon.Feeds() {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return config.Feed{}, false
+}
+
+func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Feeds []string `json:"feeds"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		fail(w, err)
+		return
+	}
+	feeds := s.daemon.Feeds()
+	if len(body.Feeds) > 0 {
+		feeds = nil
+		for _, name := range body.Feeds {
+			f, ok := s.findFeed(name)
+			if !ok {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("no feed named %q", name))
+				return
+			}
+			feeds = append(feeds, f)
+		}
+	}
+	writeJSON(w, http.StatusOK, s.daemon.Refresh(feeds))
+}
+
+func (s *Server) listArticles(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var cutoff time.Time
+	if since := q.Get("since"); since != "" {
+		var err error
+		if cutoff, err = feed.ParseSince(since, time.Now()); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since: "+err.Error())
+			return
+		}
+	}
+	flags := make(map[string]bool)
+	for _, name := range []string{"unread", "starred", "content"} {
+		if v := q.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: expected true or false", name))
+				return
+			}
+			flags[name] = b
+		}
+	}
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit: expected a non-negative number")
+			return
+		}
+		limit = n
+	}
+	feedName := q.Get("feed")
+	if feedName != "" {
+		if _, ok := s.findFeed(feedName); !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("no feed named %q", feedName))
+			return
+		}
+	}
+
+	state, err := s.loadState()
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	filter := feed.ParseFilter(q.Get("filter"))
+	filter.Unread = filter.Unread || flags["unread"]
+	filter.Starred = filter.Starred || flags["starred"]
+
+	var articles []feed.Article
+	for _, a := range s.fm.GetArticles() {
+		if feedName != "" && !strings.EqualFold(a.SourceName(), feedName) {
+			continue
+		}
+		if !cutoff.IsZero() && a.Published.Before(cutoff) {
+			continue
+		}
+		if filter.MatchState(a, state) {
+			articles = append(articles, a)
+		}
+	}
+	sort.SliceStable(articles, func(i, j int) bool { return articles[i].Published.After(articles[j].Published) })
+	if limit > 0 && len(articles) > limit {
+		articles = articles[:limit]
+	}
+
+	out := make([]Article, 0, len(articles))
+	for _, a := range articles {
+		out = append(out, newArticle(a, state, flags["content"]))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getArticle(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.fm.Article(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no article with id %q", r.PathValue("id")))
+		return
+	}
+	state, err := s.loadState()
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newArticle(a, state, true))
+}
+
+func (s *Server) markArticle(w http.ResponseWriter, r *http.Request) {
+	var m Mark
+	if err := readJSON(r, &m); err != nil {
+		fail(w, err)
+		return
+	}
+	if len(m.IDs) > 0 || m.Feed != "" {
+		writeError(w, http.StatusBadRequest, "ids and feed are only allowed in POST /articles/mark")
+		return
+	}
+	m.IDs = []string{r.PathValue("id")}
+	articles, err := s.mark(m)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, articles[0])
+}
+
+func (s *Server) markArticles(w http.ResponseWriter, r *http.Request) {
+	var m Mark
+	if err := readJSON(r, &m); err != nil {
+		fail(w, err)
+		return
+	}
+	if (len(m.IDs) > 0) == (m.Feed != "") {
+		writeError(w, http.StatusBadRequest, "give either ids or feed")
+		return
+	}
+	articles, err := s.mark(m)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"marked": len(articles)})
+}
+
+// mark applies m to the articles it names and returns them with their new state
+func (s *Server) mark(m Mark) ([]Article, error) {
+	if m.Read == nil && m.Starred == nil {
+		return nil, badRequest("nothing to change; set read or starred")
+	}
+
+	var articles []feed.Article
+	if m.Feed != "" {
+		if _, ok := s.findFeed(m.Feed); !ok {
+			return nil, notFound("no feed named %q", m.Feed)
+		}
+		for _, a := range s.fm.GetArticles() {
+			if strings.EqualFold(a.SourceName(), m.Feed) {
+				articles = append(articles, a)
+			}
+		}
+	}
+	for _, id := range m.IDs {
+		a, ok := s.fm.Article(id)
+		if !ok {
+			return nil, notFound("no article with id %q", id)
+		}
+		articles = append(articles, a)
+	}
+
+	state, err := s.loadState()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Article, 0, len(articles))
+	for _, a := range articles {
+		if m.Read != nil {
+			state.SetRead(a.ID, *m.Read)
+		}
+		if m.Starred != nil {
+			state.SetStarred(a.ID, *m.Starred)
+		}
+		out = append(out, newArticle(a, state, false))
+	}
+	if err := state.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save read state: %w", err)
+	}
+	return out, nil
+}
+
+func (s *Server) listSummaries(w http.ResponseWriter, r *http.Request) {
+	out := []Summary{}
+	for _, f := range s.daemon.Feeds() {
+		if sum, ok := s.fm.GetSummary(f.Name); ok {
+			out = append(out, Summary{Feed: f.Name, Summary: sum.Summary, Generated: sum.Generated, ArticleCount: sum.ArticleCount})
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getSummary(w http.ResponseWriter, r *http.Request) {
+	f, ok := s.findFeed(r.PathValue("feed"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no feed named %q", r.PathValue("feed")))
+		return
+	}
+	sum, ok := s.fm.GetSummary(f.Name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no summary for %q", f.Name))
+		return
+	}
+	writeJSON(w, http.StatusOK, Summary{Feed: f.Name, Summary: sum.Summary, Generated: sum.Generated, ArticleCount: sum.ArticleCount})
+}
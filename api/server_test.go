@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+// fakeDaemon answers AddFeed and RemoveFeed with err
+type fakeDaemon struct {
+	feeds []config.Feed
+	err   error
+}
+
+func (d *fakeDaemon) Feeds() []config.Feed                        { return d.feeds }
+func (d *fakeDaemon) Status() Status                              { return Status{} }
+func (d *fakeDaemon) Refresh(feeds []config.Feed) []RefreshResult { return nil }
+
+func (d *fakeDaemon) AddFeed(f config.Feed) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.feeds = append(d.feeds, f)
+	return nil
+}
+
+func (d *fakeDaemon) RemoveFeed(name string) (config.Feed, error) {
+	return config.Feed{}, d.err
+}
+
+func serve(t *testing.T, d Daemon, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	dir := t.TempDir()
+	s := NewServer(d, feed.NewFeedManager(nil, dir), dir, "secret")
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestAddFeedStatus(t *testing.T) {
+	existing := config.Feed{Name: "Go", URL: "https://go.dev/blog/feed.atom"}
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"added", `{"name":"Lobsters","url":"https://lobste.rs/rss"}`, nil, http.StatusCreated},
+		{"missing url", `{"name":"Lobsters"}`, nil, http.StatusBadRequest},
+		{"no scheme", `{"name":"Lobsters","url":"lobste.rs/rss"}`, nil, http.StatusBadRequest},
+		{"reserved name", `{"name":"All","url":"https://lobste.rs/rss"}`, nil, http.StatusBadRequest},
+		{"unknown encoding", `{"name":"Lobsters","url":"https://lobste.rs/rss","encoding":"nope"}`, nil, http.StatusBadRequest},
+		{"duplicate", `{"name":"go","url":"https://lobste.rs/rss"}`, &config.DuplicateFeedError{Existing: existing}, http.StatusConflict},
+		{"managed", `{"name":"Lobsters","url":"https://lobste.rs/rss"}`, fmt.Errorf("%w: on the server", ErrFeedsManaged), http.StatusForbidden},
+		{"write failed", `{"name":"Lobsters","url":"https://lobste.rs/rss"}`, errors.New("permission denied"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDaemon{feeds: []config.Feed{existing}, err: tt.err}
+			w := serve(t, d, "POST", Prefix+"/feeds", tt.body)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestRemoveFeedStatus(t *testing.T) {
+	existing := config.Feed{Name: "Go", URL: "https://go.dev/blog/feed.atom"}
+	tests := []struct {
+		name string
+		feed string
+		err  error
+		want int
+	}{
+		{"removed", "go", nil, http.StatusNoContent},
+		{"unknown", "Lobsters", nil, http.StatusNotFound},
+		{"managed", "Go", fmt.Errorf("%w: on the server", ErrFeedsManaged), http.StatusForbidden},
+		{"write failed", "Go", errors.New("permission denied"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDaemon{feeds: []config.Feed{existing}, err: tt.err}
+			w := serve(t, d, "DELETE", Prefix+"/feeds/"+tt.feed, "")
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestTokenRequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"no header", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer secreT", http.StatusUnauthorized},
+		{"prefix of the token", "Bearer secre", http.StatusUnauthorized},
+		{"empty token", "Bearer ", http.StatusUnauthorized},
+		{"other scheme", "Basic c2VjcmV0", http.StatusUnauthorized},
+		{"token without scheme", "secret", http.StatusUnauthorized},
+		{"right token", "Bearer secret", http.StatusOK},
+	}
+	dir := t.TempDir()
+	s := NewServer(&fakeDaemon{}, feed.NewFeedManager(nil, dir), dir, "secret")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", Prefix+"/feeds", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body)
+			}
+			if challenge := w.Header().Get("WWW-Authenticate"); tt.want == http.StatusUnauthorized && challenge != `Bearer realm="gorss"` {
+				t.Errorf("WWW-Authenticate = %q", challenge)
+			}
+		})
+	}
+}
@@ -0,0 +1,662 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JohanLi233/gorss/api"
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/export"
+	"github.com/JohanLi233/gorss/feed"
+	"github.com/JohanLi233/gorss/greader"
+	"github.com/JohanLi233/gorss/publish"
+	"github.com/JohanLi233/gorss/ui"
+)
+
+// The commands in this file work without a terminal, for cron jobs and shell
+// pipelines. Results go to stdout, problems to stderr. The exit status is 0
+// on success, 1 on failure and 2 for a wrong command line.
+
+// usageError is a wrong command line; main exits with status 2. It is empty
+// when the flag package has already printed the problem.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// newFlagSet creates the flag set of a subcommand. Parse errors are returned
+// instead of exiting, so they become usage errors.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gorss %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the arguments of a subcommand; -h prints its usage and
+// is not an error
+func parseFlags(fs *flag.FlagSet, args []string) (bool, error) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return false, nil
+		}
+		return false, usageError("")
+	}
+	return true, nil
+}
+
+// loadFeeds loads the config and the cached articles
+func loadFeeds(paths config.Paths) (*config.Config, *feed.FeedManager) {
+	cfg, err := config.LoadConfig(paths)
+	if err != nil {
+		reportConfigError(err)
+	}
+	for _, p := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: config %s\n", p)
+	}
+	// With a sync backend the feeds are the server's subscriptions
+	cfg.Feeds = greader.Feeds(cfg)
+	fm := feed.NewFeedManager(cfg.Feeds, paths.CacheDir)
+	fm.SetStripParams(cfg.Links.StripParams)
+	printWarnings(fm)
+	return cfg, fm
+}
+
+// loadState loads the read state of the profile
+func loadState(paths config.Paths) (*feed.State, error) {
+	state, warning, err := feed.LoadState(paths.StateDir)
+	if warning != "" {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load read state: %w", err)
+	}
+	return state, nil
+}
+
+func printWarnings(fm *feed.FeedManager) {
+	for _, w := range fm.TakeWarnings() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+}
+
+// findFeed looks up a configured feed by name, ignoring case
+func findFeed(feeds []config.Feed, name string) (config.Feed, bool) {
+	for _, f := range feeds {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return config.Feed{}, false
+}
+
+// runFetch implements `gorss fetch`: it refreshes the feeds and reports how
+// many new articles each one had. It fails if any feed could not be fetched.
+// With a sync backend it syncs with the server instead.
+func runFetch(paths config.Paths, args []string) error {
+	fs := newFlagSet("fetch", "fetch [--feed name] [-q]")
+	feedName := fs.String("feed", "", "fetch only this feed")
+	quiet := fs.Bool("q", false, "print only errors")
+	if ok, err := parseFlags(fs, args); !ok {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("fetch takes no arguments")
+	}
+
+	cfg, fm := loadFeeds(paths)
+	if cfg.Backend.Remote() {
+		if *feedName != "" {
+			return usageError("--feed can't be used with a sync backend: the server fetches the feeds")
+		}
+		return runSync(cfg, fm, *quiet)
+	}
+	feeds := cfg.Feeds
+	if *feedName != "" {
+		f, ok := findFeed(cfg.Feeds, *feedName)
+		if !ok {
+			return fmt.Errorf("no feed named %q", *feedName)
+		}
+		feeds = []config.Feed{f}
+	}
+
+	failed := 0
+	for _, f := range feeds {
+		if f.URL == "" {
+			continue
+		}
+		added, err := fm.RefreshFeed(f)
+		printWarnings(fm)
+		if err != nil {
+			failed++
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		if !*quiet {
+			fmt.Printf("%s: %d new\n", f.Name, added)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d feeds failed", failed, len(feeds))
+	}
+	return nil
+}
+
+// runSync syncs with the server of the sync backend. Changes that could not
+// be pushed stay queued for the next sync.
+func runSync(cfg *config.Config, fm *feed.FeedManager, quiet bool) error {
+	syncer := greader.NewSyncer(cfg.Backend, fm, cfg.Paths)
+	result, err := syncer.Sync()
+	for _, w := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	printWarnings(fm)
+	if err != nil {
+		if n, perr := syncer.Pending(); perr == nil && n > 0 {
+			return fmt.Errorf("%w (%d local changes queued for the next sync)", err, n)
+		}
+		return err
+	}
+	if !quiet {
+		fmt.Printf("synced %d feeds: %d new, %d changes sent, %d from the server\n",
+			len(result.Feeds), result.New, result.Pushed, result.Pulled)
+		if result.Dropped > 0 {
+			fmt.Printf("%d changes were refused by the server\n", result.Dropped)
+		}
+	}
+	return nil
+}
+
+// errRemoteFeeds is returned when changing feeds that a sync server manages
+var errRemoteFeeds = fmt.Errorf("%w: they are managed on the sync server (backend.type: greader); subscribe or unsubscribe there", api.ErrFeedsManaged)
+
+// runFeedsCommand implements `gorss feeds list|add|remove`
+func runFeedsCommand(paths config.Paths, args []string) error {
+	const usage = "usage: gorss feeds list [--json] | add <name> <url> [--full-text] [--encoding charset] | remove <name>"
+	if len(args) == 0 {
+		return usageError(usage)
+	}
+	switch args[0] {
+	case "list":
+		return runFeedsList(paths, args[1:])
+	case "add":
+		return runFeedsAdd(paths, args[1:])
+	case "remove", "rm":
+		return runFeedsRemove(paths, args[1:])
+	}
+	return usageError(usage)
+}
+
+// feedJSON is a feed in `gorss feeds list --json`
+type feedJSON struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	FullText bool   `json:"full_text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+func runFeedsList(paths config.Paths, args []string) error {
+	fs := newFlagSet("feeds list", "feeds list [--json]")
+	asJSON := fs.Bool("json", false, "print the feeds as JSON")
+	if ok, err := parseFlags(fs, args); !ok {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(paths)
+	if err != nil {
+		reportConfigError(err)
+	}
+	cfg.Feeds = greader.Feeds(cfg)
+	if *asJSON {
+		out := []feedJSON{}
+		for _, f := range cfg.Feeds {
+			out = append(out, feedJSON{Name: f.Name, URL: f.URL, FullText: f.FullText, Encoding: f.Encoding})
+		}
+		return writeJSON(os.Stdout, out)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, f := range cfg.Feeds {
+		fmt.Fprintf(tw, "%s\t%s\n", f.Name, f.URL)
+	}
+	return tw.Flush()
+}
+
+func runFeedsAdd(paths config.Paths, args []string) error {
+	fs := newFlagSet("feeds add", "feeds add [--full-text] [--encoding charset] <name> <url>")
+	fullText := fs.Bool("full-text", false, "fetch the full article for truncated feeds")
+	encoding := fs.String("encoding", "", "force a character set, e.g. gbk")
+	if ok, err := parseFlags(fs, args); !ok {
+		return err
+	}
+	// Allow the flags after the name and URL as well
+	var positional []string
+	for rest := fs.Args(); len(rest) > 0; rest = fs.Args() {
+		positional = append(positional, rest[0])
+		if ok, err := parseFlags(fs, rest[1:]); !ok {
+			return err
+		}
+	}
+	if len(positional) != 2 {
+		return usageError("usage: gorss feeds add [--full-text] [--encoding charset] <name> <url>")
+	}
+	name, url := positional[0], positional[1]
+
+	// Creates the default config if there is none yet
+	cfg, err := config.LoadConfig(paths)
+	if err != nil {
+		reportConfigError(err)
+	}
+	if cfg.Backend.Remote() {
+		return errRemoteFeeds
+	}
+	err = config.AddFeed(paths.ConfigFile, config.Feed{Name: name, URL: url, FullText: *fullText, Encoding: *encoding})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Added %s\n", name)
+	return nil
+}
+
+func runFeedsRemove(paths config.Paths, args []string) error {
+	if len(args) != 1 {
+		return usageError("usage: gorss feeds remove <name>")
+	}
+	cfg, err := config.LoadConfig(paths)
+	if err != nil {
+		reportConfigError(err)
+	}
+	if cfg.Backend.Remote() {
+		return errRemoteFeeds
+	}
+	removed, err := config.RemoveFeed(paths.ConfigFile, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", removed.Name)
+	return nil
+}
+
+// articleJSON is an article in `gorss articles --json`
+type articleJSON struct {
+	ID         string    `json:"id"`
+	Feed       string    `json:"feed"`
+	Title      string    `json:"title"`
+	Link       string    `json:"link"`
+	Published  time.Time `json:"published"`
+	Authors    []string  `json:"authors,omitempty"`
+	Categories []string  `json:"categories,omitempty"`
+	Read       bool      `json:"read"`
+	Starred    bool      `json:"starred"`
+}
+
+// selection picks articles from the cache; `articles` and `export` share it
+type selection struct {
+	feed    *string
+	since   *string
+	filter  *string
+	unread  *bool
+	starred *bool
+	limit   *int
+}
+
+func addSelectionFlags(fs *flag.FlagSet) selection {
+	return selection{
+		feed:    fs.String("feed", "", "only articles of this feed"),
+		since:   fs.String("since", "", "only articles published within this duration (e.g. 90m, 24h, 7d, 2w) or since a date (2006-01-02)"),
+		filter:  fs.String("filter", "", `only articles matching a filter query, as with / in the TUI (e.g. "author:alice tag:go")`),
+		unread:  fs.Bool("unread", false, "only unread articles"),
+		starred: fs.Bool("starred", false, "only starred articles"),
+		limit:   fs.Int("limit", 0, "at most this many articles"),
+	}
+}
+
+// articles returns the selected articles, newest first. ids, if given,
+// limits the selection to articles with these IDs (or unique prefixes).
+func (s selection) articles(cfg *config.Config, fm *feed.FeedManager, state *feed.State, ids []string) ([]feed.Article, error) {
+	var cutoff time.Time
+	if *s.since != "" {
+		var err error
+		if cutoff, err = feed.ParseSince(*s.since, time.Now()); err != nil {
+			return nil, usageError("invalid --since: " + err.Error())
+		}
+	}
+	if *s.feed != "" {
+		if _, ok := findFeed(cfg.Feeds, *s.feed); !ok {
+			return nil, fmt.Errorf("no feed named %q", *s.feed)
+		}
+	}
+	filter := feed.ParseFilter(*s.filter)
+	filter.Unread = filter.Unread || *s.unread
+	filter.Starred = filter.Starred || *s.starred
+
+	candidates := fm.GetArticles()
+	if len(ids) > 0 {
+		candidates = nil
+		for _, id := range ids {
+			a, err := findArticle(fm, id)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, a)
+		}
+	}
+
+	var articles []feed.Article
+	for _, a := range candidates {
+		if *s.feed != "" && !strings.EqualFold(a.FeedName, *s.feed) && !strings.EqualFold(a.Source, *s.feed) {
+			continue
+		}
+		if !cutoff.IsZero() && a.Published.Before(cutoff) {
+			continue
+		}
+		if !filter.MatchState(a, state) {
+			continue
+		}
+		articles = append(articles, a)
+	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].Published.After(articles[j].Published)
+	})
+	if *s.limit > 0 && len(articles) > *s.limit {
+		articles = articles[:*s.limit]
+	}
+	return articles, nil
+}
+
+// runArticles implements `gorss articles`: it lists cached articles, newest
+// first. It doesn't fetch; run `gorss fetch` first for fresh results.
+func runArticles(paths config.Paths, args []string) error {
+	fs := newFlagSet("articles", "articles [--feed name] [--since 24h] [--filter query] [--unread] [--starred] [--limit n] [--json]")
+	sel := addSelectionFlags(fs)
+	asJSON := fs.Bool("json", false, "print the articles as JSON")
+	if ok, err := parseFlags(fs, args); !ok {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("articles takes no arguments; use --feed to pick a feed")
+	}
+
+	cfg, fm := loadFeeds(paths)
+	state, err := loadState(paths)
+	if err != nil {
+		return err
+	}
+	articles, err := sel.articles(cfg, fm, state, nil)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		out := []articleJSON{}
+		for _, a := range articles {
+			out = append(out, articleJSON{
+				ID:         a.ID,
+				Feed:       a.FeedName,
+				Title:      a.Title,
+				Link:       a.Link,
+				Published:  a.Published,
+				Authors:    a.Authors,
+				Categories: a.Categories,
+				Read:       state.IsRead(a.ID),
+				Starred:    state.IsStarred(a.ID),
+			})
+		}
+		return writeJSON(os.Stdout, out)
+	}
+
+	// One article per line, tab-separated, so the output can go through cut and awk
+	for _, a := range articles {
+		fmt.Printf("%s\t%s\t%s\t%s\n", a.ID, a.Published.Format("2006-01-02 15:04"),
+			ui.SanitizeLine(a.FeedName), ui.SanitizeLine(a.Title))
+	}
+	return nil
+}
+
+// findArticle looks up an article by its ID or a unique prefix of it
+func findArticle(fm *feed.FeedManager, id string) (feed.Article, error) {
+	id = strings.ToLower(id)
+	var matches []feed.Article
+	for _, a := range fm.GetArticles() {
+		if a.ID == id {
+			return a, nil
+		}
+		if strings.HasPrefix(a.ID, id) {
+			matches = append(matches, a)
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return feed.Article{}, fmt.Errorf("no article with id %q", id)
+	case len(matches) > 1:
+		for _, a := range matches {
+			fmt.Fprintf(os.Stderr, "%s\t%s\n", a.ID, ui.SanitizeLine(a.Title))
+		}
+		return feed.Article{}, fmt.Errorf("id %q matches %d articles", id, len(matches))
+	}
+	return matches[0], nil
+}
+
+// runOpen implements `gorss open <id>`: it prints an article as plain text
+// and marks it read. Any unique prefix of the ID will do.
+func runOpen(paths config.Paths, args []string) error {
+	fs := newFlagSet("open", "open [--link] [--keep-unread] <id>")
+	linkOnly := fs.Bool("link", false, "print only the article's link")
+	keepUnread := fs.Bool("keep-unread", false, "don't mark the article read")
+	if ok, err := parseFlags(fs, args); !ok {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("usage: gorss open [--link] [--keep-unread] <id>")
+	}
+
+	_, fm := loadFeeds(paths)
+	a, err := findArticle(fm, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *linkOnly {
+		if a.Link == "" {
+			return fmt.Errorf("article %s has no link", a.ID)
+		}
+		fmt.Println(ui.SanitizeLine(a.Link))
+	} else {
+		fmt.Print(ui.ArticleText(a))
+	}
+
+	if *keepUnread {
+		return nil
+	}
+	state, err := loadState(paths)
+	if err != nil {
+		return err
+	}
+	state.SetRead(a.ID, true)
+	if err := state.Save(); err != nil {
+		return fmt.Errorf("failed to save read state: %w", err)
+	}
+	return nil
+}
+
+// runStar implements `gorss star <id>...` and `gorss unstar <id>...`
+func runStar(paths config.Paths, args []string, starred bool) error {
+	if len(args) == 0 {
+		if starred {
+			return usageError("usage: gorss star <id>...")
+		}
+		return usageError("usage: gorss unstar <id>...")
+	}
+
+	_, fm := loadFeeds(paths)
+	state, err := loadState(paths)
+	if err != nil {
+		return err
+	}
+	for _, id := range args {
+		a, err := findArticle(fm, id)
+		if err != nil {
+			return err
+		}
+		state.SetStarred(a.ID, starred)
+	}
+	if err := state.Save(); err != nil {
+		return fmt.Errorf("failed to save read state: %w", err)
+	}
+	return nil
+}
+
+// runExport implements `gorss export`: it writes the selected articles, or
+// the ones given by ID, in one of the export formats. Single-file formats go
+// to stdout unless --out names a file; Markdown writes one note per article
+// into the --out directory.
+func runExport(paths config.Paths, args []string) error {
+	fs := newFlagSet("export", "export --format markdown|json|ndjson|csv|html|epub [--out path] [selection flags] [id...]")
+	formatName := fs.String("format", "", "export format (default export.format from the config, or markdown)")
+	out := fs.String("out", "", "file to write, or directory for markdown (default stdout, or the current directory for markdown)")
+	title := fs.String("title", "", `book title for epub (default "gorss <date>")`)
+	noImages := fs.Bool("no-images", false, "don't download and embed images in epub")
+	sel := addSelectionFlags(fs)
+	if ok, err := parseFlags(fs, args); !ok {
+		return err
+	}
+
+	cfg, fm := loadFeeds(paths)
+	if *formatName == "" {
+		*formatName = cfg.Export.FormatOrDefault()
+	}
+	format, err := export.ParseFormat(*formatName)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	state, err := loadState(paths)
+	if err != nil {
+		return err
+	}
+	articles, err := sel.articles(cfg, fm, state, fs.Args())
+	if err != nil {
+		return err
+	}
+	prepared := export.Prepare(articles, state, ui.ArticleBody)
+	write := func(w io.Writer) error { return export.Write(w, format, prepared) }
+	if format == export.EPUB {
+		opts := export.EPUBOptions{Title: *title, Images: !*noImages}
+		write = func(w io.Writer) error { return export.WriteEPUB(w, prepared, opts) }
+	}
+
+	switch {
+	case format == export.Markdown:
+		dir := *out
+		if dir == "" {
+			dir = "."
+		}
+		written, err := export.WriteMarkdown(dir, prepared)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d article(s) to %s\n", len(written), dir)
+	case *out == "" || *out == "-":
+		if format == export.EPUB {
+			if fi, err := os.Stdout.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+				return usageError("not writing an EPUB to the terminal; use --out book.epub")
+			}
+		}
+		return write(os.Stdout)
+	default:
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		if err := write(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d article(s) to %s\n", len(prepared), *out)
+	}
+	return nil
+}
+
+// runPublish implements `gorss publish`: it writes the published feeds as
+// files, e.g. from cron after `gorss fetch`, for any web server to serve
+func runPublish(paths config.Paths, args []string) error {
+	fs := newFlagSet("publish", "publish [--dir dir] [--format atom,rss,json] [--out path] [name...]")
+	dir := fs.String("dir", "", "directory to write <name>.atom, .rss and .json to (default publish.dir)")
+	formatList := fs.String("format", "", "comma-separated formats to write (default atom,rss,json)")
+	out := fs.String("out", "", "write a single feed in a single format to this file, - for stdout")
+	quiet := fs.Bool("q", false, "print only errors")
+	if ok, err := parseFlags(fs, args); !ok {
+		return err
+	}
+
+	var formats []publish.Format
+	if *formatList != "" {
+		for _, name := range strings.Split(*formatList, ",") {
+			format, err := publish.ParseFormat(name)
+			if err != nil {
+				return usageError(err.Error())
+			}
+			formats = append(formats, format)
+		}
+	}
+
+	cfg, fm := loadFeeds(paths)
+	state, err := loadState(paths)
+	if err != nil {
+		return err
+	}
+	p := publish.New(cfg, fm, func() (*feed.State, error) { return state, nil })
+	names := fs.Args()
+
+	if *out != "" {
+		if len(names) != 1 || len(formats) != 1 {
+			return usageError("--out writes one feed in one format: gorss publish --format atom --out path <name>")
+		}
+		if *out == "-" {
+			return p.Render(os.Stdout, names[0], formats[0])
+		}
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		if err := p.Render(f, names[0], formats[0]); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	if *dir == "" {
+		*dir = cfg.Publish.Directory()
+	}
+	if *dir == "" {
+		return usageError("no output directory; set publish.dir in the config or use --dir")
+	}
+	written, err := p.WriteFiles(*dir, names, formats)
+	if err != nil {
+		return err
+	}
+	if !*quiet {
+		for _, path := range written {
+			fmt.Println(path)
+		}
+	}
+	return nil
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
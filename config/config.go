@@ -4,40 +4,284 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-
-	"github.com/spf13/viper"
+	"strings"
 )
 
 // Feed represents an RSS feed configuration
 type Feed struct {
-	Name string `mapstructure:"name"`
-	URL  string `mapstructure:"url"`
+	Name     string `mapstructure:"name"`
+	URL      string `mapstructure:"url"`
+	FullText bool   `mapstructure:"full_text"` // fetch the full article for truncated feeds
+	Encoding string `mapstructure:"encoding"`  // force a character set (e.g. "gbk") for feeds with wrong headers
 }
 
 // Config represents the application configuration
 type Config struct {
-	Feeds  []Feed       `mapstructure:"feeds"`
-	Ollama OllamaConfig `mapstructure:"ollama"`
+	Feeds     []Feed                 `mapstructure:"feeds"`
+	Ollama    OllamaConfig           `mapstructure:"ollama"`
+	Serve     ServeConfig            `mapstructure:"serve"`
+	Downloads DownloadConfig         `mapstructure:"downloads"`
+	Export    ExportConfig           `mapstructure:"export"`
+	Publish   PublishConfig          `mapstructure:"publish"`
+	Backend   BackendConfig          `mapstructure:"backend"`
+	Links     LinkConfig             `mapstructure:"links"`
+	Keys      KeyConfig              `mapstructure:"keys"`
+	Theme     string                 `mapstructure:"theme"` // built-in theme, theme in Themes or theme file; empty follows the terminal
+	Themes    map[string]ThemeConfig `mapstructure:"themes"`
+
+	Paths    Paths     `mapstructure:"-"` // where this config was loaded from
+	Warnings []Problem `mapstructure:"-"` // problems that didn't stop the config from loading
 }
 
 // OllamaConfig represents configuration for the Ollama LLM integration
 type OllamaConfig struct {
-	Enabled     bool   `mapstructure:"enabled"`
+	Enabled     *bool  `mapstructure:"enabled"` // nil when unset, which means enabled
 	URL         string `mapstructure:"url"`
 	Model       string `mapstructure:"model"`
 	MaxArticles int    `mapstructure:"max_articles"`
 	Timeout     int    `mapstructure:"timeout"`
 }
 
-// LoadConfig loads the configuration from the default location
-func LoadConfig() (*Config, error) {
+// DefaultOllamaConfig returns the LLM settings used when the config has no ollama section
+func DefaultOllamaConfig() OllamaConfig {
+	enabled := true
+	return OllamaConfig{
+		Enabled:     &enabled,
+		URL:         "http://localhost:11434",
+		Model:       "qwen3:32b",
+		MaxArticles: 100,
+		Timeout:     3000,
+	}
+}
+
+// IsEnabled reports whether the LLM features are on; they are unless the
+// config says "enabled: false"
+func (o OllamaConfig) IsEnabled() bool {
+	return o.Enabled == nil || *o.Enabled
+}
+
+// WithDefaults fills in the settings missing from the config with the
+// defaults, field by field
+func (o OllamaConfig) WithDefaults() OllamaConfig {
+	settings := DefaultOllamaConfig()
+	if o.Enabled != nil {
+		enabled := *o.Enabled
+		settings.Enabled = &enabled
+	}
+	if o.URL != "" {
+		settings.URL = o.URL
+	}
+	if o.Model != "" {
+		settings.Model = o.Model
+	}
+	if o.MaxArticles > 0 {
+		settings.MaxArticles = o.MaxArticles
+	}
+	if o.Timeout > 0 {
+		settings.Timeout = o.Timeout
+	}
+	return settings
+}
+
+// DownloadConfig represents configuration for enclosure downloads and playback
+type DownloadConfig struct {
+	Dir         string `mapstructure:"dir"`         // where enclosures are saved, defaults to ~/Downloads/gorss
+	Concurrency int    `mapstructure:"concurrency"` // maximum parallel downloads
+	Player      string `mapstructure:"player"`      // external command used to play enclosures, e.g. "mpv"
+}
+
+// Directory returns the download directory with "~" expanded, falling back to ~/Downloads/gorss
+func (d DownloadConfig) Directory() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	if d.Dir == "" {
+		return filepath.Join(homeDir, "Downloads", "gorss")
+	}
+	return expandHome(d.Dir, homeDir)
+}
+
+// PlayerCommand returns the configured player split into program and arguments
+func (d DownloadConfig) PlayerCommand() []string {
+	if fields := strings.Fields(d.Player); len(fields) > 0 {
+		return fields
+	}
+	return []string{"mpv"}
+}
+
+// ExportConfig represents configuration for exporting articles from the TUI
+type ExportConfig struct {
+	Dir    string `mapstructure:"dir"`    // where exports are written, defaults to ~/Documents/gorss
+	Format string `mapstructure:"format"` // format suggested in the export prompt, defaults to markdown
+}
+
+// ExportFormats are the formats articles can be exported to. Markdown writes
+// one file per article; the others write a single file.
+var ExportFormats = []string{"markdown", "json", "ndjson", "csv", "html", "epub"}
+
+// Directory returns the export directory with "~" expanded, falling back to ~/Documents/gorss
+func (e ExportConfig) Directory() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		homeDir = "."
+	}
+	if e.Dir == "" {
+		return filepath.Join(homeDir, "Documents", "gorss")
 	}
+	return expandHome(e.Dir, homeDir)
+}
 
-	configDir := filepath.Join(homeDir, ".config", "gorss")
-	configPath := filepath.Join(configDir, "config.yaml")
+// FormatOrDefault returns the configured export format, or markdown
+func (e ExportConfig) FormatOrDefault() string {
+	if e.Format == "" {
+		return ExportFormats[0]
+	}
+	return strings.ToLower(e.Format)
+}
+
+// PublishConfig represents configuration for re-publishing articles as feeds
+type PublishConfig struct {
+	Dir     string          `mapstructure:"dir"`      // where `gorss publish` writes the feed files
+	Listen  string          `mapstructure:"listen"`   // address `gorss serve` serves the feeds on, e.g. ":8086"
+	BaseURL string          `mapstructure:"base_url"` // public URL of the feeds, for self links; defaults to http://<listen>
+	Limit   int             `mapstructure:"limit"`    // articles per feed, defaults to 50
+	Feeds   []PublishedFeed `mapstructure:"feeds"`
+}
+
+// PublishedFeed is a feed gorss publishes. It contains the articles of the
+// listed sources (all if none) that match the filter, newest first.
+type PublishedFeed struct {
+	Name        string   `mapstructure:"name"` // file and URL name, e.g. "go" for go.atom
+	Title       string   `mapstructure:"title"`
+	Description string   `mapstructure:"description"`
+	Sources     []string `mapstructure:"sources"` // configured feeds to take articles from
+	Filter      string   `mapstructure:"filter"`  // filter query as in the TUI, e.g. "tag:go is:starred"
+	Limit       int      `mapstructure:"limit"`   // overrides publish.limit
+}
+
+// DefaultPublishLimit is the number of articles per published feed
+const DefaultPublishLimit = 50
+
+// PublishedFeeds returns the configured published feeds, or "all" (every
+// article) and "starred" if there are none
+func (p PublishConfig) PublishedFeeds() []PublishedFeed {
+	if len(p.Feeds) > 0 {
+		return p.Feeds
+	}
+	return []PublishedFeed{
+		{Name: "all", Title: "gorss: all articles"},
+		{Name: "starred", Title: "gorss: starred articles", Filter: "is:starred"},
+	}
+}
+
+// Directory returns the publish directory with "~" expanded, or "" if none is set
+func (p PublishConfig) Directory() string {
+	if p.Dir == "" {
+		return ""
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return expandHome(p.Dir, homeDir)
+}
+
+// BackendConfig selects where articles come from. The default "local"
+// backend fetches the configured feeds itself; "greader" syncs
+// subscriptions, articles and read and starred marks with a FreshRSS or
+// Miniflux server through its Google Reader API instead.
+type BackendConfig struct {
+	Type     string `mapstructure:"type"` // "local" (default) or "greader"
+	URL      string `mapstructure:"url"`  // API endpoint, e.g. https://rss.example.com/api/greader.php
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"` // API password (FreshRSS) or account password (Miniflux)
+	Limit    int    `mapstructure:"limit"`    // newest articles pulled per sync, defaults to 1000
+}
+
+// Backend types
+const (
+	BackendLocal   = "local"
+	BackendGReader = "greader"
+)
+
+// DefaultSyncLimit is the number of articles pulled per sync
+const DefaultSyncLimit = 1000
+
+// Remote reports whether feeds are managed by a sync server rather than the config
+func (b BackendConfig) Remote() bool {
+	return strings.EqualFold(b.Type, BackendGReader)
+}
+
+// LimitOrDefault returns the configured sync limit, or DefaultSyncLimit
+func (b BackendConfig) LimitOrDefault() int {
+	if b.Limit <= 0 {
+		return DefaultSyncLimit
+	}
+	return b.Limit
+}
+
+// LinkConfig represents configuration for rewriting article links on fetch
+type LinkConfig struct {
+	// StripParams lists query parameters removed from links as glob patterns,
+	// optionally limited to a domain: "utm_*", "fbclid", "youtube.com:si".
+	// Unset uses the built-in list; an empty list disables stripping.
+	StripParams []string `mapstructure:"strip_params"`
+}
+
+// KeyConfig overrides key bindings as view -> action -> keys, e.g.
+// keys.articles.multi_select: ["x"]. See DefaultKeys for the views and
+// actions; an empty list unbinds an action.
+type KeyConfig map[string]map[string][]string
+
+// ServeConfig represents configuration for the long-running `gorss serve` mode
+type ServeConfig struct {
+	RefreshInterval int           `mapstructure:"refresh_interval"` // minutes between polls of feeds without push
+	WebSub          WebSubConfig  `mapstructure:"websub"`
+	API             APIConfig     `mapstructure:"api"`
+	GReader         GReaderConfig `mapstructure:"greader"`
+}
+
+// GReaderConfig represents configuration for the Google Reader compatible API
+// that mobile apps (Reeder, NetNewsWire, ...) sync with
+type GReaderConfig struct {
+	Listen   string `mapstructure:"listen"` // e.g. ":8088"; empty disables it. Put it behind TLS when phones reach it over the internet
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// APIConfig represents configuration for the local JSON API of `gorss serve`
+type APIConfig struct {
+	Listen string `mapstructure:"listen"` // loopback address such as "127.0.0.1:8087", or "unix:/path/to/socket"; empty disables the API
+	Token  string `mapstructure:"token"`  // bearer token clients must send; generated into the state directory if empty
+}
+
+// WebSubConfig represents configuration for WebSub (PubSubHubbub) push subscriptions
+type WebSubConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	Listen       string `mapstructure:"listen"`        // local address for the callback server, e.g. ":8085"
+	CallbackURL  string `mapstructure:"callback_url"`  // public URL hubs use to reach the callback server
+	Secret       string `mapstructure:"secret"`        // used to derive per-subscription HMAC secrets
+	LeaseSeconds int    `mapstructure:"lease_seconds"` // requested subscription lease
+}
+
+// defaultConfig is written when a profile has no config file yet
+const defaultConfig = `feeds:
+  - name: "gorss"
+    url: "https://github.com/JohanLi233/gorss/releases"
+ollama:
+  enabled: true
+  url: "http://localhost:11434"
+  model: "qwen3:32b"
+  max_articles: 100
+  timeout: 30
+`
+
+// LoadConfig loads the configuration file of a profile, creating a default
+// one if it doesn't exist yet
+func LoadConfig(paths Paths) (*Config, error) {
+	configPath := paths.ConfigFile
+	configDir := filepath.Dir(configPath)
 
 	// Check if config file exists, if not create a default one
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -45,33 +289,30 @@ func LoadConfig() (*Config, error) {
 			return nil, fmt.Errorf("failed to create config directory: %w", err)
 		}
 
-		defaultConfig := `feeds:
-		- name: "gorss"
-		url: "https://github.com/JohanLi233/gorss/releases"
-		ollama:
-		enabled: true
-		url: "http://localhost:11434"
-		model: "qwen3:32b "
-		max_articles: 100
-		timeout: 30
-		`
 		if err := os.WriteFile(configPath, []byte(defaultConfig), 0644); err != nil {
 			return nil, fmt.Errorf("failed to create default config file: %w", err)
 		}
 		fmt.Printf("Created default config file at %s\n", configPath)
 	}
 
-	viper.SetConfigFile(configPath)
-	viper.SetConfigType("yaml")
-
-	if err := viper.ReadInConfig(); err != nil {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	// Validate first: viper's own errors don't say where the problem is
+	problems := checkYAML(data)
+	if HasErrors(problems) {
+		return nil, &ValidationError{File: configPath, Problems: problems}
+	}
+
+	// A private viper instance, so the config watcher can reload concurrently
+	config, err := decode(data)
+	if err != nil {
+		return nil, err
 	}
+	config.Paths = paths
+	config.Warnings = problems
 
-	return &config, nil
+	return config, nil
 }
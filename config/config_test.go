@@ -0,0 +1,50 @@
+package config
+
+import "testing"
+
+func TestOllamaWithDefaults(t *testing.T) {
+	defaults := DefaultOllamaConfig()
+	tests := []struct {
+		name    string
+		yaml    string
+		enabled bool
+		url     string
+		model   string
+		timeout int
+	}{
+		{"no section", "feeds: []\n", true, defaults.URL, defaults.Model, defaults.Timeout},
+		{"only enabled false", "ollama:\n  enabled: false\n", false, defaults.URL, defaults.Model, defaults.Timeout},
+		{"only enabled true", "ollama:\n  enabled: true\n", true, defaults.URL, defaults.Model, defaults.Timeout},
+		{"only model", "ollama:\n  model: llama3\n", true, defaults.URL, "llama3", defaults.Timeout},
+		{"disabled with url", "ollama:\n  enabled: false\n  url: http://gpu:11434\n  timeout: 60\n", false, "http://gpu:11434", defaults.Model, 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problems := checkYAML([]byte(tt.yaml)); len(problems) > 0 {
+				t.Errorf("checkYAML: %v", problems)
+			}
+			cfg, err := decode([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := cfg.Ollama.WithDefaults()
+			if got.IsEnabled() != tt.enabled || got.URL != tt.url || got.Model != tt.model || got.Timeout != tt.timeout {
+				t.Errorf("WithDefaults() = enabled %v, %q, %q, timeout %d; want enabled %v, %q, %q, timeout %d",
+					got.IsEnabled(), got.URL, got.Model, got.Timeout, tt.enabled, tt.url, tt.model, tt.timeout)
+			}
+			if got.MaxArticles != defaults.MaxArticles {
+				t.Errorf("MaxArticles = %d, want the default %d", got.MaxArticles, defaults.MaxArticles)
+			}
+		})
+	}
+}
+
+func TestOllamaWithDefaultsDoesNotAlias(t *testing.T) {
+	enabled := false
+	o := OllamaConfig{Enabled: &enabled}
+	got := o.WithDefaults()
+	enabled = true
+	if got.IsEnabled() {
+		t.Error("WithDefaults shares Enabled with its receiver")
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultKeys returns the built-in key bindings as view -> action -> keys.
+// Bindings in "global" apply to every view unless the view binds the same
+// keys itself. A key is written the way bubbletea names it ("j", "ctrl+c",
+// "pgdown", "space"); several characters in a row ("gg", "ZZ") or keys
+// separated by spaces ("ctrl+w j") form a sequence.
+func DefaultKeys() KeyConfig {
+	return KeyConfig{
+		"global": {
+			"quit":      {"q", "ctrl+c", "ZZ"},
+			"refresh":   {"r"},
+			"config":    {"c"},
+			"downloads": {"D"},
+			"down":      {"j", "down"},
+			"up":        {"k", "up"},
+			"top":       {"gg", "home"},
+			"bottom":    {"G", "end"},
+			"page_down": {"ctrl+f", "pgdown"},
+			"page_up":   {"ctrl+b", "pgup"},
+			"back":      {"h", "tab", "left"},
+			"open":      {"l", "right", "enter"},
+			"theme":     {"T"},
+		},
+		"feeds": {
+			"ask":    {"a"},
+			"filter": {"/"},
+		},
+		"articles": {
+			"ask":          {"a"},
+			"filter":       {"/"},
+			"multi_select": {"v"},
+			"select":       {"space"},
+			"star":         {"s"},
+			"export":       {"e"},
+		},
+		"article": {
+			"ask":       {"a"},
+			"full_text": {"f"},
+			"comments":  {"t"},
+			"download":  {"d"},
+			"play":      {"p"},
+			"star":      {"s"},
+			"export":    {"e"},
+		},
+		"comments": {
+			"toggle": {"enter", "space", "l", "right"},
+			"back":   {"t", "esc", "tab", "h", "left"},
+		},
+		"downloads": {
+			"back": {"esc", "D", "tab", "h", "left"},
+		},
+		"summary": {
+			"back": {"esc", "tab", "h", "left"},
+		},
+		"themes": {
+			"back": {"esc", "tab", "h", "left"},
+		},
+	}
+}
+
+// namedKeys are the multi-character key names bubbletea reports
+var namedKeys = map[string]bool{
+	"up": true, "down": true, "left": true, "right": true,
+	"enter": true, "esc": true, "tab": true, "shift+tab": true,
+	"backspace": true, "delete": true, "insert": true,
+	"home": true, "end": true, "pgup": true, "pgdown": true,
+	"space": true,
+}
+
+// KeySequence splits a key binding from the config into the keys that have
+// to be pressed in order, named the way bubbletea's KeyMsg.String() does
+func KeySequence(spec string) ([]string, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty key")
+	}
+
+	var parts []string
+	if spec == " " || isKeyName(spec) {
+		parts = []string{spec}
+	} else if fields := strings.Fields(spec); len(fields) > 1 {
+		parts = fields
+	} else {
+		// "gg", "ZZ": one key per character
+		for _, r := range spec {
+			parts = append(parts, string(r))
+		}
+	}
+
+	for i, p := range parts {
+		if !isKeyName(p) && utf8.RuneCountInString(p) != 1 {
+			return nil, fmt.Errorf("unknown key %q", p)
+		}
+		if p == "space" {
+			parts[i] = " "
+		}
+	}
+	return parts, nil
+}
+
+// isKeyName reports whether s names a single key longer than one character
+func isKeyName(s string) bool {
+	if namedKeys[s] {
+		return true
+	}
+	if mod, k, ok := strings.Cut(s, "+"); ok && k != "" {
+		// ctrl+x, alt+x, ctrl+up, ...
+		return (mod == "ctrl" || mod == "alt" || mod == "shift") && (utf8.RuneCountInString(k) == 1 || isKeyName(k))
+	}
+	var n int
+	if _, err := fmt.Sscanf(s, "f%d", &n); err == nil && fmt.Sprintf("f%d", n) == s {
+		return n >= 1 && n <= 20
+	}
+	return false
+}
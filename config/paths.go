@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Paths locates the files of one gorss profile. Every other package gets its
+// directories from here instead of building paths under the home directory.
+type Paths struct {
+	Profile    string // "" for the default profile
+	ConfigFile string // config.yaml
+	CacheDir   string // article, summary and full-text caches; safe to delete
+	StateDir   string // data that should survive clearing the cache
+}
+
+// ResolvePaths works out the config file and data directories.
+//
+// The config file is, in order: configFile (the --config flag), $GORSS_CONFIG,
+// or config.yaml in the profile's config directory. The profile is the
+// profile argument (the --profile flag) or $GORSS_PROFILE. Directories follow
+// the XDG base directory spec, defaulting to ~/.config, ~/.cache and
+// ~/.local/state. The default profile lives directly in the gorss directories;
+// named profiles live in profiles/<name> below them.
+func ResolvePaths(configFile, profile string) (Paths, error) {
+	if profile == "" {
+		profile = os.Getenv("GORSS_PROFILE")
+	}
+	if profile != "" && (profile == "." || profile == ".." || strings.ContainsAny(profile, `/\`)) {
+		return Paths{}, fmt.Errorf("invalid profile name %q", profile)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return Paths{}, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	dir := func(env string, fallback ...string) string {
+		base := os.Getenv(env)
+		if !filepath.IsAbs(base) {
+			// The spec says relative values are invalid and should be ignored
+			base = filepath.Join(append([]string{homeDir}, fallback...)...)
+		}
+		base = filepath.Join(base, "gorss")
+		if profile != "" {
+			base = filepath.Join(base, "profiles", profile)
+		}
+		return base
+	}
+
+	p := Paths{
+		Profile:  profile,
+		CacheDir: dir("XDG_CACHE_HOME", ".cache"),
+		StateDir: dir("XDG_STATE_HOME", ".local", "state"),
+	}
+
+	if configFile == "" {
+		configFile = os.Getenv("GORSS_CONFIG")
+	}
+	if configFile == "" {
+		configFile = filepath.Join(dir("XDG_CONFIG_HOME", ".config"), "config.yaml")
+	}
+	p.ConfigFile = expandHome(configFile, homeDir)
+
+	return p, nil
+}
+
+// expandHome replaces a leading "~" with the home directory
+func expandHome(path, homeDir string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir, path[1:])
+	}
+	return path
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ThemeConfig describes a color theme. Colors sets the palette the styles are
+// built from; Styles overrides single styles by name. A theme with a Base
+// starts from that theme and changes only what it sets.
+type ThemeConfig struct {
+	Base   string                 `mapstructure:"base"`
+	Colors map[string]string      `mapstructure:"colors"`
+	Styles map[string]StyleConfig `mapstructure:"styles"`
+}
+
+// StyleConfig overrides the attributes of one style; unset fields keep the
+// theme's value
+type StyleConfig struct {
+	Foreground       string `mapstructure:"foreground"`
+	Background       string `mapstructure:"background"`
+	BorderForeground string `mapstructure:"border_foreground"`
+	Bold             *bool  `mapstructure:"bold"`
+	Italic           *bool  `mapstructure:"italic"`
+	Underline        *bool  `mapstructure:"underline"`
+	Reverse          *bool  `mapstructure:"reverse"`
+}
+
+// ThemeColors are the palette entries a theme can set
+var ThemeColors = []string{
+	"subtle",    // borders, help and metadata
+	"highlight", // titles and headings
+	"special",   // selection and links
+	"alert",     // messages in the config view
+	"accent",    // multi-select and quotes
+	"text",      // normal list items
+	"muted",     // preformatted text
+	"bar_text",  // text on the title and status bars
+	"error",     // errors in the status bar
+	"frame",     // the ask view border
+	"info",      // the question in the ask view
+	"success",   // the answer title in the ask view
+}
+
+// ThemeStyles are the names of the styles a theme can override
+var ThemeStyles = []string{
+	"title", "status_bar", "status_error", "status_multi_select",
+	"feed_list", "selected_feed", "normal_feed",
+	"article_list", "selected_article", "selected_multi_article", "normal_article",
+	"article_view", "article_title", "article_meta", "enclosure",
+	"comment_author", "comment_guide", "help",
+	"config_view", "config_title", "config_content",
+	"selected_config_item", "normal_config_item",
+	"selected_config_form", "normal_config_form",
+	"config_help", "config_message",
+	"gemini_heading1", "gemini_heading2", "gemini_heading3",
+	"gemini_link", "gemini_link_url", "gemini_quote", "gemini_pre",
+	"ask_box", "ask_prompt", "ask_answer_title",
+}
+
+// BuiltinThemes returns the themes that ship with gorss. "dark" and "light"
+// are the original look; "mono" uses no colors at all and is used when
+// NO_COLOR is set.
+func BuiltinThemes() map[string]ThemeConfig {
+	yes := true
+	reverse := StyleConfig{Reverse: &yes}
+	return map[string]ThemeConfig{
+		"dark": {Colors: map[string]string{
+			"subtle": "#383838", "highlight": "#7D56F4", "special": "#73F59F",
+			"alert": "#FF5A6E", "accent": "#BFA48A", "text": "#DDDDDD",
+			"muted": "#B0B0B0", "bar_text": "#FFFFFF", "error": "#FF0000",
+			"frame": "#4B7BEC", "info": "#2E86DE", "success": "#10AC84",
+		}},
+		"light": {Colors: map[string]string{
+			"subtle": "#D9DCCF", "highlight": "#874BFD", "special": "#43BF6D",
+			"alert": "#FD4659", "accent": "#EED9C4", "text": "#1A1A1A",
+			"muted": "#5A5A5A", "bar_text": "#FFFFFF", "error": "#FF0000",
+			"frame": "#4B7BEC", "info": "#2E86DE", "success": "#10AC84",
+		}},
+		"high-contrast": {Colors: map[string]string{
+			"subtle": "#FFFFFF", "highlight": "#FFFF00", "special": "#00FFFF",
+			"alert": "#FF0000", "accent": "#FF00FF", "text": "#FFFFFF",
+			"muted": "#FFFFFF", "bar_text": "#000000", "error": "#FF0000",
+			"frame": "#FFFFFF", "info": "#FFFF00", "success": "#00FF00",
+		}, Styles: map[string]StyleConfig{
+			// Red on white is hard to read; invert it instead
+			"status_error": {Foreground: "#FFFFFF", Background: "#FF0000"},
+		}},
+		"solarized-dark": {Colors: map[string]string{
+			"subtle": "#586E75", "highlight": "#268BD2", "special": "#859900",
+			"alert": "#DC322F", "accent": "#B58900", "text": "#93A1A1",
+			"muted": "#839496", "bar_text": "#FDF6E3", "error": "#DC322F",
+			"frame": "#6C71C4", "info": "#2AA198", "success": "#859900",
+		}},
+		"solarized-light": {Colors: map[string]string{
+			"subtle": "#93A1A1", "highlight": "#268BD2", "special": "#859900",
+			"alert": "#DC322F", "accent": "#CB4B16", "text": "#586E75",
+			"muted": "#657B83", "bar_text": "#FDF6E3", "error": "#DC322F",
+			"frame": "#6C71C4", "info": "#2AA198", "success": "#859900",
+		}},
+		"mono": {Colors: map[string]string{}, Styles: map[string]StyleConfig{
+			"selected_feed":          reverse,
+			"selected_article":       reverse,
+			"selected_multi_article": {Underline: &yes},
+			"selected_config_item":   reverse,
+			"selected_config_form":   reverse,
+			"status_bar":             reverse,
+			"title":                  reverse,
+		}},
+	}
+}
+
+// ThemeDir is where theme files (<name>.yaml) of a profile are looked up
+func (p Paths) ThemeDir() string {
+	return filepath.Join(filepath.Dir(p.ConfigFile), "themes")
+}
+
+// ThemeNames lists the built-in themes, those defined in the config and
+// the theme files of the profile
+func ThemeNames(cfg *Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	var builtin []string
+	for name := range BuiltinThemes() {
+		builtin = append(builtin, name)
+	}
+	sort.Strings(builtin)
+	for _, name := range builtin {
+		add(name)
+	}
+
+	var custom []string
+	for name := range cfg.Themes {
+		custom = append(custom, name)
+	}
+	if files, err := filepath.Glob(filepath.Join(cfg.Paths.ThemeDir(), "*.yaml")); err == nil {
+		for _, f := range files {
+			custom = append(custom, strings.TrimSuffix(filepath.Base(f), ".yaml"))
+		}
+	}
+	sort.Strings(custom)
+	for _, name := range custom {
+		add(name)
+	}
+	return names
+}
+
+// ResolveTheme looks up a theme by name in the config, the theme files and
+// the built-in themes (in that order) and flattens its chain of bases
+func ResolveTheme(cfg *Config, name string) (ThemeConfig, error) {
+	return resolveTheme(cfg, name, nil)
+}
+
+func resolveTheme(cfg *Config, name string, seen []string) (ThemeConfig, error) {
+	for _, s := range seen {
+		if s == name {
+			return ThemeConfig{}, fmt.Errorf("theme %q is its own base (%s)", name, strings.Join(append(seen, name), " -> "))
+		}
+	}
+	seen = append(seen, name)
+
+	theme, ok := cfg.Themes[strings.ToLower(name)]
+	if !ok {
+		var err error
+		theme, ok, err = loadThemeFile(filepath.Join(cfg.Paths.ThemeDir(), name+".yaml"))
+		if err != nil {
+			return ThemeConfig{}, err
+		}
+	}
+	if !ok {
+		builtin, ok := BuiltinThemes()[name]
+		if !ok {
+			return ThemeConfig{}, fmt.Errorf("unknown theme %q", name)
+		}
+		return builtin, nil
+	}
+
+	base := ThemeConfig{}
+	if theme.Base != "" {
+		var err error
+		if base, err = resolveTheme(cfg, theme.Base, seen); err != nil {
+			return ThemeConfig{}, err
+		}
+	} else if b, ok := BuiltinThemes()[name]; ok {
+		// A custom theme named like a built-in one changes it
+		base = b
+	} else {
+		base = BuiltinThemes()["dark"]
+	}
+	return overlayTheme(base, theme), nil
+}
+
+// overlayTheme applies the colors and styles set in t on top of base
+func overlayTheme(base, t ThemeConfig) ThemeConfig {
+	out := ThemeConfig{Colors: make(map[string]string), Styles: make(map[string]StyleConfig)}
+	for k, v := range base.Colors {
+		out.Colors[k] = v
+	}
+	for k, v := range t.Colors {
+		out.Colors[k] = v
+	}
+	for k, v := range base.Styles {
+		out.Styles[k] = v
+	}
+	for k, v := range t.Styles {
+		s := out.Styles[k]
+		if v.Foreground != "" {
+			s.Foreground = v.Foreground
+		}
+		if v.Background != "" {
+			s.Background = v.Background
+		}
+		if v.BorderForeground != "" {
+			s.BorderForeground = v.BorderForeground
+		}
+		if v.Bold != nil {
+			s.Bold = v.Bold
+		}
+		if v.Italic != nil {
+			s.Italic = v.Italic
+		}
+		if v.Underline != nil {
+			s.Underline = v.Underline
+		}
+		if v.Reverse != nil {
+			s.Reverse = v.Reverse
+		}
+		out.Styles[k] = s
+	}
+	return out
+}
+
+// loadThemeFile reads a theme file; ok is false if it doesn't exist
+func loadThemeFile(path string) (theme ThemeConfig, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ThemeConfig{}, false, nil
+	}
+	if err != nil {
+		return ThemeConfig{}, false, err
+	}
+	if problems := checkThemeYAML(data); HasErrors(problems) {
+		return ThemeConfig{}, false, &ValidationError{File: path, Problems: problems}
+	}
+	v, err := newViper(data)
+	if err != nil {
+		return ThemeConfig{}, false, err
+	}
+	if err := v.Unmarshal(&theme); err != nil {
+		return ThemeConfig{}, false, fmt.Errorf("failed to parse theme %s: %w", path, err)
+	}
+	return theme, true, nil
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// validColor accepts "#rgb", "#rrggbb", an ANSI color number 0-255, or ""
+// for no color
+func validColor(s string) bool {
+	if s == "" || hexColor.MatchString(s) {
+		return true
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
@@ -0,0 +1,729 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	minOllamaTimeout = 1    // seconds
+	maxOllamaTimeout = 3600 // an hour is already far beyond any sensible request
+)
+
+// Problem is one issue found in a config file
+type Problem struct {
+	Line    int    // 1-based, 0 if unknown
+	Path    string // where in the config, e.g. "feeds[2].url"
+	Message string
+	Warning bool // the config still loads, e.g. an unknown key
+}
+
+// String formats the problem as "line 12: feeds[2].url: message"
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", p.Line)
+	}
+	if p.Path != "" {
+		b.WriteString(p.Path + ": ")
+	}
+	b.WriteString(p.Message)
+	if p.Warning {
+		b.WriteString(" (warning)")
+	}
+	return b.String()
+}
+
+// ValidationError is returned when a config file has problems that stop it from loading
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := []string{fmt.Sprintf("%s has %d problem(s):", displayPath(e.File), len(e.Problems))}
+	for _, p := range e.Problems {
+		lines = append(lines, "  "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors reports whether any problem is more than a warning
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if !p.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// Check validates a config file against the Config schema and reports every
+// problem found, with line numbers. The error is only for unreadable files.
+func Check(configPath string) ([]Problem, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	problems := checkYAML(data)
+	if !HasErrors(problems) {
+		problems = append(problems, checkThemeName(data, configPath)...)
+	}
+	return problems, nil
+}
+
+// checkThemeName checks that the selected theme exists and that its theme
+// file and bases load, which needs the location of the config file
+func checkThemeName(data []byte, configPath string) []Problem {
+	cfg, err := decode(data)
+	if err != nil || cfg.Theme == "" {
+		return nil
+	}
+	cfg.Paths = Paths{ConfigFile: configPath}
+	if _, err := ResolveTheme(cfg, cfg.Theme); err != nil {
+		var doc yaml.Node
+		yaml.Unmarshal(data, &doc)
+		var line int
+		if len(doc.Content) > 0 {
+			line = lineOf(mappingValue(doc.Content[0], "theme"))
+		}
+		return []Problem{{Line: line, Path: "theme", Message: strings.ReplaceAll(err.Error(), "\n", " ")}}
+	}
+	return nil
+}
+
+// checkThemeYAML validates a theme file
+func checkThemeYAML(data []byte) []Problem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []Problem{{Message: strings.TrimPrefix(err.Error(), "yaml: ")}}
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	c := &checker{}
+	c.checkType(doc.Content[0], reflect.TypeOf(ThemeConfig{}), "")
+	c.checkTheme(doc.Content[0], "")
+	sort.SliceStable(c.problems, func(i, j int) bool { return c.problems[i].Line < c.problems[j].Line })
+	return c.problems
+}
+
+// yamlLine extracts the line number from yaml.v3 syntax errors
+var yamlLine = regexp.MustCompile(`line (\d+)`)
+
+// checkYAML validates a config document
+func checkYAML(data []byte) []Problem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		if line := tabLine(data); line > 0 {
+			// The parser's own message ("found character that cannot start any token") doesn't say why
+			return []Problem{{Line: line, Message: "YAML does not allow tabs for indentation; use spaces"}}
+		}
+		p := Problem{Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+		if m := yamlLine.FindStringSubmatch(err.Error()); m != nil {
+			p.Line, _ = strconv.Atoi(m[1])
+			p.Message = strings.TrimPrefix(p.Message, m[0]+": ")
+		}
+		return []Problem{p}
+	}
+	if len(doc.Content) == 0 {
+		// An empty file is a valid, empty config
+		return nil
+	}
+
+	c := &checker{}
+	root := doc.Content[0]
+	c.checkType(root, reflect.TypeOf(Config{}), "")
+	c.checkFeeds(mappingValue(root, "feeds"))
+	c.checkOllama(mappingValue(root, "ollama"))
+	c.checkDownloads(mappingValue(root, "downloads"))
+	c.checkExport(mappingValue(root, "export"))
+	c.checkPublish(mappingValue(root, "publish"), mappingValue(root, "feeds"))
+	c.checkServe(mappingValue(root, "serve"))
+	c.checkBackend(mappingValue(root, "backend"))
+	c.checkLinks(mappingValue(root, "links"))
+	c.checkKeys(mappingValue(root, "keys"))
+	if themes := mappingValue(root, "themes"); themes != nil && themes.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(themes.Content); i += 2 {
+			c.checkTheme(themes.Content[i+1], "themes."+themes.Content[i].Value)
+		}
+	}
+
+	sort.SliceStable(c.problems, func(i, j int) bool { return c.problems[i].Line < c.problems[j].Line })
+	return c.problems
+}
+
+// tabLine returns the first line indented with a tab
+func tabLine(data []byte) int {
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if bytes.Contains(line[:len(line)-len(trimmed)], []byte("\t")) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// checker collects problems while walking the YAML tree
+type checker struct {
+	problems []Problem
+}
+
+func (c *checker) errorf(n *yaml.Node, path, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Line: lineOf(n), Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) warnf(n *yaml.Node, path, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Line: lineOf(n), Path: path, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+func lineOf(n *yaml.Node) int {
+	if n == nil {
+		return 0
+	}
+	return n.Line
+}
+
+// checkType checks that a node has the shape of t: known keys only, lists
+// where lists are expected and scalars of the right kind. The schema comes
+// from the mapstructure tags, so it can't drift from what viper decodes.
+func (c *checker) checkType(n *yaml.Node, t reflect.Type, at string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null" {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			c.errorf(n, at, "expected a mapping of keys to values")
+			return
+		}
+		fields := structFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			field, ok := fields[strings.ToLower(key.Value)]
+			if !ok {
+				msg := fmt.Sprintf("unknown key %q", key.Value)
+				if s := closest(key.Value, fields); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				c.warnf(key, at, "%s", msg)
+				continue
+			}
+			c.checkType(value, field.Type, join(at, key.Value))
+		}
+
+	case reflect.Ptr:
+		c.checkType(n, t.Elem(), at)
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			c.errorf(n, at, "expected a mapping of keys to values")
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			c.checkType(n.Content[i+1], t.Elem(), join(at, n.Content[i].Value))
+		}
+
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			c.errorf(n, at, "expected a list")
+			return
+		}
+		for i, item := range n.Content {
+			c.checkType(item, t.Elem(), fmt.Sprintf("%s[%d]", at, i))
+		}
+
+	case reflect.Bool:
+		if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!bool" {
+			c.errorf(n, at, "expected true or false, got %q", n.Value)
+		}
+
+	case reflect.Int, reflect.Int64:
+		if _, err := strconv.Atoi(n.Value); n.Kind != yaml.ScalarNode || err != nil {
+			c.errorf(n, at, "expected a whole number, got %q", n.Value)
+		}
+
+	case reflect.String:
+		if n.Kind != yaml.ScalarNode {
+			c.errorf(n, at, "expected a single value, not a list or mapping")
+		}
+	}
+}
+
+// structFields maps the mapstructure keys of a struct to its fields
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := strings.Split(f.Tag.Get("mapstructure"), ",")[0]
+		if key == "-" || !f.IsExported() {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(f.Name)
+		}
+		fields[key] = f
+	}
+	return fields
+}
+
+// closest suggests the known key nearest to a misspelt one
+func closest[V any](key string, fields map[string]V) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(strings.ToLower(key), name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func join(at, key string) string {
+	if at == "" {
+		return key
+	}
+	return at + "." + key
+}
+
+// mappingValue returns the value node of key in a mapping, or nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if strings.EqualFold(n.Content[i].Value, key) {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// intValue returns the integer value of key in a mapping
+func intValue(n *yaml.Node, key string) (int, *yaml.Node, bool) {
+	v := mappingValue(n, key)
+	if v == nil {
+		return 0, nil, false
+	}
+	i, err := strconv.Atoi(v.Value)
+	return i, v, err == nil
+}
+
+func (c *checker) checkFeeds(feeds *yaml.Node) {
+	if feeds == nil || feeds.Kind != yaml.SequenceNode {
+		return
+	}
+	seen := make(map[string]int)
+	for i, item := range feeds.Content {
+		at := fmt.Sprintf("feeds[%d]", i)
+
+		name := mappingValue(item, "name")
+		feedURL := mappingValue(item, "url")
+		if isLegacyAll(name, feedURL) {
+			c.warnf(item, at, `the "All" entry without a url was written by older versions of gorss and is ignored; it can be removed`)
+			continue
+		}
+		switch {
+		case name == nil || strings.TrimSpace(name.Value) == "":
+			c.errorf(item, at, "feed has no name")
+		case strings.EqualFold(name.Value, "All"):
+			c.errorf(name, at+".name", `"All" is reserved for the combined article list`)
+		default:
+			key := strings.ToLower(strings.TrimSpace(name.Value))
+			if first, dup := seen[key]; dup {
+				c.errorf(name, at+".name", "duplicate feed name %q (first used on line %d)", name.Value, first)
+			} else {
+				seen[key] = name.Line
+			}
+		}
+
+		if feedURL == nil || strings.TrimSpace(feedURL.Value) == "" {
+			c.errorf(item, at, "feed has no url")
+		} else if msg := checkFeedURL(feedURL.Value); msg != "" {
+			c.errorf(feedURL, at+".url", "%s", msg)
+		}
+
+		if enc := mappingValue(item, "encoding"); enc != nil && enc.Value != "" {
+			if e, _ := charset.Lookup(enc.Value); e == nil {
+				c.errorf(enc, at+".encoding", "unknown character encoding %q", enc.Value)
+			}
+		}
+	}
+}
+
+// CheckFeed checks a feed about to be subscribed to, the way Check checks the
+// feeds in a config file
+func CheckFeed(f Feed) error {
+	switch {
+	case strings.TrimSpace(f.Name) == "":
+		return errors.New("feed has no name")
+	case strings.EqualFold(strings.TrimSpace(f.Name), "All"):
+		return errors.New(`"All" is reserved for the combined article list`)
+	case strings.TrimSpace(f.URL) == "":
+		return errors.New("feed has no url")
+	}
+	if msg := checkFeedURL(f.URL); msg != "" {
+		return errors.New(msg)
+	}
+	if f.Encoding != "" {
+		if e, _ := charset.Lookup(f.Encoding); e == nil {
+			return fmt.Errorf("unknown character encoding %q", f.Encoding)
+		}
+	}
+	return nil
+}
+
+// isLegacyAll reports whether a feed entry is the placeholder for the combined
+// article list that older versions of the TUI saved along with the real feeds
+func isLegacyAll(name, feedURL *yaml.Node) bool {
+	return name != nil && strings.EqualFold(strings.TrimSpace(name.Value), "All") &&
+		(feedURL == nil || strings.TrimSpace(feedURL.Value) == "")
+}
+
+// checkFeedURL returns why a feed URL is unusable, or ""
+func checkFeedURL(raw string) string {
+	if strings.HasPrefix(raw, "maildir:") {
+		if strings.TrimPrefix(raw, "maildir:") == "" {
+			return "maildir: URL needs a directory, e.g. maildir:~/Mail/newsletters"
+		}
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Sprintf("invalid URL: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "gemini":
+	case "":
+		return fmt.Sprintf("URL %q has no scheme; did you mean https://%s?", raw, raw)
+	default:
+		return fmt.Sprintf("unsupported URL scheme %q (use http, https, gemini or maildir)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Sprintf("URL %q has no host", raw)
+	}
+	return ""
+}
+
+func (c *checker) checkOllama(ollama *yaml.Node) {
+	if ollama == nil {
+		return
+	}
+	if timeout, n, ok := intValue(ollama, "timeout"); ok && timeout != 0 &&
+		(timeout < minOllamaTimeout || timeout > maxOllamaTimeout) {
+		c.errorf(n, "ollama.timeout", "timeout is in seconds and must be between %d and %d, got %d",
+			minOllamaTimeout, maxOllamaTimeout, timeout)
+	}
+	if maxArticles, n, ok := intValue(ollama, "max_articles"); ok && maxArticles < 0 {
+		c.errorf(n, "ollama.max_articles", "must not be negative")
+	}
+	if n := mappingValue(ollama, "url"); n != nil && n.Value != "" {
+		if u, err := url.Parse(n.Value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			c.errorf(n, "ollama.url", "expected an http(s) URL such as http://localhost:11434, got %q", n.Value)
+		}
+	}
+}
+
+func (c *checker) checkDownloads(downloads *yaml.Node) {
+	if concurrency, n, ok := intValue(downloads, "concurrency"); ok && concurrency < 0 {
+		c.errorf(n, "downloads.concurrency", "must not be negative")
+	}
+}
+
+func (c *checker) checkExport(export *yaml.Node) {
+	format := mappingValue(export, "format")
+	if format == nil || format.Value == "" {
+		return
+	}
+	for _, f := range ExportFormats {
+		if strings.EqualFold(format.Value, f) {
+			return
+		}
+	}
+	c.errorf(format, "export.format", "unknown format %q, expected one of %s", format.Value, strings.Join(ExportFormats, ", "))
+}
+
+var publishName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func (c *checker) checkPublish(publish, feeds *yaml.Node) {
+	if publish == nil {
+		return
+	}
+	if limit, n, ok := intValue(publish, "limit"); ok && limit < 0 {
+		c.errorf(n, "publish.limit", "must not be negative")
+	}
+	if n := mappingValue(publish, "base_url"); n != nil && n.Value != "" {
+		if u, err := url.Parse(n.Value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			c.errorf(n, "publish.base_url", "expected an http(s) URL, got %q", n.Value)
+		}
+	}
+
+	known := make(map[string]bool)
+	if feeds != nil && feeds.Kind == yaml.SequenceNode {
+		for _, item := range feeds.Content {
+			if name := mappingValue(item, "name"); name != nil {
+				known[strings.ToLower(name.Value)] = true
+			}
+		}
+	}
+	published := mappingValue(publish, "feeds")
+	if published == nil || published.Kind != yaml.SequenceNode {
+		return
+	}
+	seen := make(map[string]int)
+	for i, item := range published.Content {
+		at := fmt.Sprintf("publish.feeds[%d]", i)
+		name := mappingValue(item, "name")
+		switch {
+		case name == nil || name.Value == "":
+			c.errorf(item, at, "published feed has no name")
+		case !publishName.MatchString(name.Value):
+			c.errorf(name, at+".name", "%q can only use letters, digits, - and _, since it names files and URLs", name.Value)
+		default:
+			key := strings.ToLower(name.Value)
+			if first, dup := seen[key]; dup {
+				c.errorf(name, at+".name", "duplicate published feed %q (first used on line %d)", name.Value, first)
+			} else {
+				seen[key] = name.Line
+			}
+		}
+		if limit, n, ok := intValue(item, "limit"); ok && limit < 0 {
+			c.errorf(n, at+".limit", "must not be negative")
+		}
+		if sources := mappingValue(item, "sources"); sources != nil && sources.Kind == yaml.SequenceNode {
+			for j, s := range sources.Content {
+				c.checkName(s, fmt.Sprintf("%s.sources[%d]", at, j), "feed", known)
+			}
+		}
+	}
+}
+
+func (c *checker) checkServe(serve *yaml.Node) {
+	if interval, n, ok := intValue(serve, "refresh_interval"); ok && interval < 0 {
+		c.errorf(n, "serve.refresh_interval", "must not be negative")
+	}
+	websub := mappingValue(serve, "websub")
+	if enabled := mappingValue(websub, "enabled"); enabled != nil && enabled.Value == "true" {
+		if cb := mappingValue(websub, "callback_url"); cb == nil || cb.Value == "" {
+			c.errorf(enabled, "serve.websub", "callback_url is required when WebSub is enabled")
+		} else if u, err := url.Parse(cb.Value); err != nil || !u.IsAbs() {
+			c.errorf(cb, "serve.websub.callback_url", "expected an absolute URL reachable by hubs, got %q", cb.Value)
+		}
+	}
+	if greader := mappingValue(serve, "greader"); greader != nil {
+		if listen := mappingValue(greader, "listen"); listen != nil && listen.Value != "" {
+			for _, key := range []string{"username", "password"} {
+				if v := mappingValue(greader, key); v == nil || v.Value == "" {
+					c.errorf(listen, "serve.greader", "%s is required when listen is set", key)
+				}
+			}
+		}
+	}
+	if listen := mappingValue(mappingValue(serve, "api"), "listen"); listen != nil && listen.Value != "" {
+		if err := CheckAPIListen(listen.Value); err != nil {
+			c.errorf(listen, "serve.api.listen", "%v", err)
+		}
+	}
+}
+
+func (c *checker) checkBackend(backend *yaml.Node) {
+	t := mappingValue(backend, "type")
+	if t == nil || t.Value == "" || strings.EqualFold(t.Value, BackendLocal) {
+		return
+	}
+	if !strings.EqualFold(t.Value, BackendGReader) {
+		c.errorf(t, "backend.type", "unknown backend %q, expected %s or %s", t.Value, BackendLocal, BackendGReader)
+		return
+	}
+	if n := mappingValue(backend, "url"); n == nil || n.Value == "" {
+		c.errorf(t, "backend", "url is required for the %s backend", BackendGReader)
+	} else if u, err := url.Parse(n.Value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.errorf(n, "backend.url", "expected an http(s) URL, got %q", n.Value)
+	}
+	for _, key := range []string{"username", "password"} {
+		if v := mappingValue(backend, key); v == nil || v.Value == "" {
+			c.errorf(t, "backend", "%s is required for the %s backend", key, BackendGReader)
+		}
+	}
+	if limit, n, ok := intValue(backend, "limit"); ok && limit < 0 {
+		c.errorf(n, "backend.limit", "must not be negative")
+	}
+}
+
+// CheckAPIListen checks that the API listens only on this machine: on a
+// loopback address or a unix socket. Other machines reach it through SSH.
+func CheckAPIListen(listen string) error {
+	if socket, ok := strings.CutPrefix(listen, "unix:"); ok {
+		if socket == "" {
+			return fmt.Errorf("expected unix:/path/to/socket")
+		}
+		return nil
+	}
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return fmt.Errorf("expected host:port or unix:/path/to/socket, got %q", listen)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%q is not a loopback address; the API only listens on this machine (use SSH port forwarding or a unix socket for remote access)", listen)
+	}
+	return nil
+}
+
+func (c *checker) checkLinks(links *yaml.Node) {
+	params := mappingValue(links, "strip_params")
+	if params == nil || params.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, p := range params.Content {
+		_, pattern, ok := strings.Cut(p.Value, ":")
+		if !ok {
+			pattern = p.Value
+		}
+		if _, err := path.Match(pattern, ""); errors.Is(err, path.ErrBadPattern) {
+			c.errorf(p, fmt.Sprintf("links.strip_params[%d]", i), "invalid pattern %q", p.Value)
+		}
+	}
+}
+
+func (c *checker) checkKeys(keys *yaml.Node) {
+	if keys == nil || keys.Kind != yaml.MappingNode {
+		return
+	}
+	defaults := DefaultKeys()
+	for i := 0; i+1 < len(keys.Content); i += 2 {
+		view, actions := keys.Content[i], keys.Content[i+1]
+		known, ok := defaults[strings.ToLower(view.Value)]
+		if !ok {
+			msg := fmt.Sprintf("unknown view %q", view.Value)
+			if s := closest(view.Value, defaults); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			c.warnf(view, "keys", "%s", msg)
+			continue
+		}
+		if actions.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(actions.Content); j += 2 {
+			action, bound := actions.Content[j], actions.Content[j+1]
+			at := join("keys."+view.Value, action.Value)
+			if _, ok := known[strings.ToLower(action.Value)]; !ok {
+				if _, global := defaults["global"][strings.ToLower(action.Value)]; !global {
+					msg := fmt.Sprintf("unknown action %q", action.Value)
+					if s := closest(action.Value, known); s != "" {
+						msg += fmt.Sprintf(" (did you mean %q?)", s)
+					}
+					c.warnf(action, "keys."+view.Value, "%s", msg)
+					continue
+				}
+			}
+			if bound.Kind != yaml.SequenceNode {
+				continue
+			}
+			for k, spec := range bound.Content {
+				if _, err := KeySequence(spec.Value); err != nil {
+					c.errorf(spec, fmt.Sprintf("%s[%d]", at, k), "%v", err)
+				}
+			}
+		}
+	}
+}
+
+// checkTheme checks the color and style names and the colors of a theme
+func (c *checker) checkTheme(theme *yaml.Node, at string) {
+	if theme == nil || theme.Kind != yaml.MappingNode {
+		return
+	}
+	colorNames := make(map[string]bool)
+	for _, name := range ThemeColors {
+		colorNames[name] = true
+	}
+	styleNames := make(map[string]bool)
+	for _, name := range ThemeStyles {
+		styleNames[name] = true
+	}
+
+	if colors := mappingValue(theme, "colors"); colors != nil && colors.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(colors.Content); i += 2 {
+			name, value := colors.Content[i], colors.Content[i+1]
+			c.checkName(name, join(at, "colors"), "color", colorNames)
+			if !validColor(value.Value) {
+				c.errorf(value, join(join(at, "colors"), name.Value), "invalid color %q (use #rrggbb or an ANSI number 0-255)", value.Value)
+			}
+		}
+	}
+	if styles := mappingValue(theme, "styles"); styles != nil && styles.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(styles.Content); i += 2 {
+			name, style := styles.Content[i], styles.Content[i+1]
+			c.checkName(name, join(at, "styles"), "style", styleNames)
+			for _, key := range []string{"foreground", "background", "border_foreground"} {
+				if v := mappingValue(style, key); v != nil && !validColor(v.Value) {
+					c.errorf(v, join(join(join(at, "styles"), name.Value), key), "invalid color %q (use #rrggbb or an ANSI number 0-255)", v.Value)
+				}
+			}
+		}
+	}
+}
+
+// checkName warns about a name that isn't one of the known ones
+func (c *checker) checkName(name *yaml.Node, at, kind string, known map[string]bool) {
+	if known[strings.ToLower(name.Value)] {
+		return
+	}
+	msg := fmt.Sprintf("unknown %s %q", kind, name.Value)
+	if s := closest(name.Value, known); s != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", s)
+	}
+	c.warnf(name, at, "%s", msg)
+}
+
+// displayPath shortens a path under the home directory to ~/...
+func displayPath(p string) string {
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, p); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join("~", rel)
+		}
+	}
+	return p
+}
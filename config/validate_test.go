@@ -0,0 +1,98 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFeedsLegacyAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		errors   []string // substrings of the expected errors
+		warnings []string
+	}{
+		{
+			name:     "legacy all entry",
+			yaml:     "feeds:\n  - name: All\n    url: \"\"\n  - name: Blog\n    url: https://example.com/feed\n",
+			warnings: []string{`"All" entry without a url`},
+		},
+		{
+			name:     "legacy all entry without url key",
+			yaml:     "feeds:\n  - name: All\n  - name: Blog\n    url: https://example.com/feed\n",
+			warnings: []string{`"All" entry without a url`},
+		},
+		{
+			name:   "all with a url is reserved",
+			yaml:   "feeds:\n  - name: all\n    url: https://example.com/feed\n",
+			errors: []string{`"All" is reserved`},
+		},
+		{
+			name:   "other feed without url",
+			yaml:   "feeds:\n  - name: Blog\n    url: \"\"\n",
+			errors: []string{"feed has no url"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs, warnings []string
+			for _, p := range checkYAML([]byte(tt.yaml)) {
+				if p.Warning {
+					warnings = append(warnings, p.Message)
+				} else {
+					errs = append(errs, p.Message)
+				}
+			}
+			match(t, "errors", errs, tt.errors)
+			match(t, "warnings", warnings, tt.warnings)
+		})
+	}
+}
+
+func match(t *testing.T, kind string, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %q, want %d matching %q", kind, got, len(want), want)
+		return
+	}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("%s[%d] = %q, want it to contain %q", kind, i, got[i], want[i])
+		}
+	}
+}
+
+func TestDecodeDropsLegacyAll(t *testing.T) {
+	cfg, err := decode([]byte("feeds:\n  - name: All\n    url: \"\"\n  - name: Blog\n    url: https://example.com/feed\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Feeds) != 1 || cfg.Feeds[0].Name != "Blog" {
+		t.Errorf("feeds = %+v, want only Blog", cfg.Feeds)
+	}
+}
+
+func TestCheckFeed(t *testing.T) {
+	tests := []struct {
+		feed Feed
+		err  string // substring of the expected error, "" for none
+	}{
+		{Feed{Name: "Blog", URL: "https://example.com/feed"}, ""},
+		{Feed{Name: "Mail", URL: "maildir:~/Mail/news"}, ""},
+		{Feed{Name: " ", URL: "https://example.com/feed"}, "feed has no name"},
+		{Feed{Name: "all", URL: "https://example.com/feed"}, `"All" is reserved`},
+		{Feed{Name: "Blog"}, "feed has no url"},
+		{Feed{Name: "Blog", URL: "example.com/feed"}, "has no scheme"},
+		{Feed{Name: "Blog", URL: "ftp://example.com/feed"}, "unsupported URL scheme"},
+		{Feed{Name: "Blog", URL: "https://example.com/feed", Encoding: "nope"}, "unknown character encoding"},
+	}
+	for _, tt := range tests {
+		err := CheckFeed(tt.feed)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("CheckFeed(%+v) = %v, want nil", tt.feed, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("CheckFeed(%+v) = %v, want %q", tt.feed, err, tt.err)
+		}
+	}
+}
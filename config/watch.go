@@ -0,0 +1,108 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay collapses the burst of events an editor produces when saving
+const reloadDelay = 200 * time.Millisecond
+
+// Reload is the result of reloading a changed config file. Err is set (and
+// Config nil) when the new file doesn't load, e.g. while it is half edited.
+type Reload struct {
+	Config *Config
+	Err    error
+}
+
+// Watcher reloads a profile's config file whenever it changes on disk
+type Watcher struct {
+	paths   Paths
+	fsw     *fsnotify.Watcher
+	reloads chan Reload
+	done    chan struct{}
+	close   sync.Once
+}
+
+// Watch starts watching the config file of a profile. The directory is
+// watched rather than the file, because many editors save by writing a new
+// file and renaming it over the old one.
+func Watch(paths Paths) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fsw.Add(filepath.Dir(paths.ConfigFile)); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	w := &Watcher{
+		paths:   paths,
+		fsw:     fsw,
+		reloads: make(chan Reload),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Reloads delivers the reloaded config after every change. It is closed
+// once the watcher is closed.
+func (w *Watcher) Reloads() <-chan Reload {
+	return w.reloads
+}
+
+// Close stops watching. It is safe to call more than once.
+func (w *Watcher) Close() error {
+	var err error
+	w.close.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+func (w *Watcher) run() {
+	defer close(w.reloads)
+	target := filepath.Clean(w.paths.ConfigFile)
+	var timer <-chan time.Time
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != target || event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer = time.After(reloadDelay)
+
+		case _, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+
+		case <-timer:
+			timer = nil
+			if _, err := os.Stat(w.paths.ConfigFile); err != nil {
+				// Deleted, or caught between an editor's remove and rename;
+				// LoadConfig would replace it with the default config
+				continue
+			}
+			cfg, err := LoadConfig(w.paths)
+			select {
+			case w.reloads <- Reload{Config: cfg, Err: err}:
+			case <-w.done:
+				return
+			}
+
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchReloadsAndCloses(t *testing.T) {
+	dir := t.TempDir()
+	paths := Paths{ConfigFile: filepath.Join(dir, "config.yaml"), CacheDir: dir, StateDir: dir}
+	if err := os.WriteFile(paths.ConfigFile, []byte("feeds: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := Watch(paths)
+	if err != nil {
+		t.Skipf("fsnotify unavailable: %v", err)
+	}
+
+	edited := "feeds:\n  - name: Blog\n    url: https://example.com/feed\n"
+	if err := os.WriteFile(paths.ConfigFile, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reload := <-w.Reloads():
+		if reload.Err != nil {
+			t.Fatal(reload.Err)
+		}
+		if len(reload.Config.Feeds) != 1 || reload.Config.Feeds[0].Name != "Blog" {
+			t.Errorf("feeds = %+v, want Blog", reload.Config.Feeds)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after editing the config")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	select {
+	case _, ok := <-w.Reloads():
+		if ok {
+			t.Error("reload delivered after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reloads not closed after Close")
+	}
+}
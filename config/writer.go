@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JohanLi233/gorss/internal/fsutil"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Update loads the config file at path, lets fn change it and writes it back.
+// Only the values fn changed are touched in the file: comments, key order,
+// quoting and keys gorss doesn't know about are preserved. The result is
+// validated before it replaces the file, and the file is replaced atomically.
+//
+// All code that writes config.yaml goes through Update.
+func Update(path string, fn func(*Config) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return &ValidationError{File: path, Problems: checkYAML(data)}
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	cfg, err := decode(data)
+	if err != nil {
+		return err
+	}
+	if err := fn(cfg); err != nil {
+		return err
+	}
+
+	mergeNode(doc.Content[0], reflect.ValueOf(*cfg))
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if problems := checkYAML(buf.Bytes()); HasErrors(problems) {
+		return &ValidationError{File: path, Problems: problems}
+	}
+	return writeFile(path, buf.Bytes())
+}
+
+// DuplicateFeedError is returned by AddFeed for a feed whose name or URL is
+// already subscribed
+type DuplicateFeedError struct {
+	Existing Feed // the feed already in the config
+	SameURL  bool // the URL is taken, not the name
+}
+
+func (e *DuplicateFeedError) Error() string {
+	if e.SameURL {
+		return fmt.Sprintf("%s is already subscribed as %q", e.Existing.URL, e.Existing.Name)
+	}
+	return fmt.Sprintf("a feed named %q already exists", e.Existing.Name)
+}
+
+// AddFeed subscribes to a feed in the config file at path. Names are unique
+// ignoring case, and a URL can only be subscribed once. The feed is checked
+// with CheckFeed first.
+func AddFeed(path string, feed Feed) error {
+	if err := CheckFeed(feed); err != nil {
+		return err
+	}
+	return Update(path, func(cfg *Config) error {
+		for _, f := range cfg.Feeds {
+			if strings.EqualFold(f.Name, feed.Name) {
+				return &DuplicateFeedError{Existing: f}
+			}
+			if f.URL == feed.URL {
+				return &DuplicateFeedError{Existing: f, SameURL: true}
+			}
+		}
+		cfg.Feeds = append(cfg.Feeds, feed)
+		return nil
+	})
+}
+
+// RemoveFeed unsubscribes from the feed with the given name (ignoring case)
+// in the config file at path and returns it
+func RemoveFeed(path, name string) (Feed, error) {
+	var removed Feed
+	found := false
+	err := Update(path, func(cfg *Config) error {
+		var kept []Feed
+		for _, f := range cfg.Feeds {
+			if !found && strings.EqualFold(f.Name, name) {
+				removed, found = f, true
+				continue
+			}
+			kept = append(kept, f)
+		}
+		if !found {
+			return fmt.Errorf("no feed named %q", name)
+		}
+		cfg.Feeds = kept
+		return nil
+	})
+	return removed, err
+}
+
+// decode reads a config document the same way LoadConfig does
+func decode(data []byte) (*Config, error) {
+	v, err := newViper(data)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	// Drop the "All" placeholder older versions saved; Check warns about it
+	feeds := cfg.Feeds[:0]
+	for _, f := range cfg.Feeds {
+		if !strings.EqualFold(strings.TrimSpace(f.Name), "All") || strings.TrimSpace(f.URL) != "" {
+			feeds = append(feeds, f)
+		}
+	}
+	cfg.Feeds = feeds
+	return &cfg, nil
+}
+
+// newViper returns a private viper instance holding a YAML document
+func newViper(data []byte) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return v, nil
+}
+
+// writeFile replaces path with data, keeping the permissions of the
+// existing file
+func writeFile(path string, data []byte) error {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return fsutil.WriteFile(path, data, perm)
+}
+
+// mergeNode writes v into the YAML node n in place. Mapping keys that have no
+// field in v are left alone, and fields with zero values are only written if
+// the key is already present, so the file doesn't fill up with defaults.
+func mergeNode(n *yaml.Node, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			*n = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: n.HeadComment, LineComment: n.LineComment}
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			key := strings.Split(f.Tag.Get("mapstructure"), ",")[0]
+			if key == "-" || !f.IsExported() {
+				continue
+			}
+			if key == "" {
+				key = strings.ToLower(f.Name)
+			}
+			value := mappingValue(n, key)
+			if value == nil {
+				if v.Field(i).IsZero() {
+					continue
+				}
+				value = &yaml.Node{}
+				n.Content = append(n.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+			}
+			mergeNode(value, v.Field(i))
+		}
+
+	case reflect.Slice:
+		mergeSequence(n, v)
+
+	case reflect.Ptr:
+		// Optional values: nil means unset, which leaves the node alone
+		if !v.IsNil() {
+			mergeNode(n, v.Elem())
+		}
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			*n = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		// Viper lowercases map keys, so the file's keys are matched ignoring
+		// case; matched keys keep their spelling and comments. Drop keys that
+		// were removed, then update the rest and add the new ones.
+		present := make(map[string]bool, v.Len())
+		for _, k := range v.MapKeys() {
+			present[strings.ToLower(k.String())] = true
+		}
+		kept := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if key := strings.ToLower(n.Content[i].Value); present[key] {
+				kept = append(kept, n.Content[i], n.Content[i+1])
+				delete(present, key) // a second spelling of the same key is a duplicate
+			}
+		}
+		n.Content = kept
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			value := mappingValue(n, k.String())
+			if value == nil {
+				value = &yaml.Node{}
+				n.Content = append(n.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k.String()}, value)
+			}
+			mergeNode(value, v.MapIndex(k))
+		}
+
+	default:
+		setScalar(n, v)
+	}
+}
+
+// mergeSequence writes a slice into a sequence node. Items are matched to the
+// existing nodes by their "name" field where they have one (so editing or
+// removing one feed keeps the comments of the others), then by position.
+// Nodes of removed items are dropped along with their comments.
+func mergeSequence(n *yaml.Node, v reflect.Value) {
+	if n.Kind != yaml.SequenceNode {
+		*n = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", HeadComment: n.HeadComment, LineComment: n.LineComment}
+	}
+	old := n.Content
+	used := make([]bool, len(old))
+	items := make([]*yaml.Node, v.Len())
+
+	// First pass: match by name, or by equal value for scalars
+	for i := 0; i < v.Len(); i++ {
+		id, ok := identity(v.Index(i))
+		if !ok {
+			continue
+		}
+		for j, node := range old {
+			if !used[j] && nodeIdentity(node, v.Index(i)) == id {
+				items[i], used[j] = node, true
+				break
+			}
+		}
+	}
+	// Second pass: an unmatched item takes the unmatched node at its own
+	// position, which is what renaming a feed in place looks like
+	for i := range items {
+		if items[i] != nil {
+			continue
+		}
+		if i < len(old) && !used[i] {
+			items[i], used[i] = old[i], true
+		} else {
+			items[i] = &yaml.Node{}
+		}
+	}
+
+	for i, item := range items {
+		mergeNode(item, v.Index(i))
+	}
+	n.Content = items
+}
+
+// identity returns the key used to match a slice element to an existing node
+func identity(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if f := v.FieldByName("Name"); f.IsValid() && f.Kind() == reflect.String {
+			return f.String(), true
+		}
+		return "", false
+	case reflect.String, reflect.Int, reflect.Bool:
+		return fmt.Sprint(v.Interface()), true
+	}
+	return "", false
+}
+
+// nodeIdentity is identity for an existing node of the same shape as v
+func nodeIdentity(n *yaml.Node, v reflect.Value) string {
+	if v.Kind() == reflect.Struct {
+		if name := mappingValue(n, "name"); name != nil {
+			return name.Value
+		}
+		return "\x00" // never matches
+	}
+	return n.Value
+}
+
+// setScalar writes a scalar value, keeping the node's comments and, where it
+// still parses back to the same value, its quoting style
+func setScalar(n *yaml.Node, v reflect.Value) {
+	var value, tag string
+	switch v.Kind() {
+	case reflect.Bool:
+		value, tag = strconv.FormatBool(v.Bool()), "!!bool"
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		value, tag = strconv.FormatInt(v.Int(), 10), "!!int"
+	case reflect.Float64, reflect.Float32:
+		value, tag = strconv.FormatFloat(v.Float(), 'g', -1, 64), "!!float"
+	default:
+		value, tag = fmt.Sprint(v.Interface()), "!!str"
+	}
+
+	if n.Kind == yaml.ScalarNode && n.Value == value && n.ShortTag() == tag {
+		return
+	}
+	style := n.Style
+	if n.Kind != yaml.ScalarNode || tag != "!!str" {
+		style = 0
+	}
+	*n = yaml.Node{
+		Kind:        yaml.ScalarNode,
+		Tag:         tag,
+		Value:       value,
+		Style:       style,
+		HeadComment: n.HeadComment,
+		LineComment: n.LineComment,
+		FootComment: n.FootComment,
+	}
+}
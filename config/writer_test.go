@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateKeepsMixedCaseMapKeys(t *testing.T) {
+	const original = `# my feeds
+feeds:
+  - name: Blog # the first one
+    url: https://example.com/feed
+theme: MyTheme
+themes:
+  MyTheme:  # custom
+    base: dark
+    colors:
+      Accent: "#112233" # warm
+  Other:
+    base: light
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AddFeed(path, Feed{Name: "News", URL: "https://news.example/rss"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+
+	for _, want := range []string{
+		"# my feeds",
+		"name: Blog # the first one",
+		"MyTheme: # custom",
+		`Accent: "#112233" # warm`,
+		"Other:",
+		"name: News",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q after AddFeed:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"mytheme:", "accent:", "other:"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("map key rewritten as %q:\n%s", unwanted, got)
+		}
+	}
+
+	// Changing and removing map entries still matches keys ignoring case
+	err = Update(path, func(cfg *Config) error {
+		theme := cfg.Themes["mytheme"]
+		theme.Base = "light"
+		cfg.Themes["mytheme"] = theme
+		delete(cfg.Themes, "other")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	got = string(data)
+	if !strings.Contains(got, "MyTheme: # custom") || !strings.Contains(got, "base: light") {
+		t.Errorf("theme not updated in place:\n%s", got)
+	}
+	if strings.Contains(got, "Other") {
+		t.Errorf("removed theme still present:\n%s", got)
+	}
+	if strings.Count(got, "base:") != 1 {
+		t.Errorf("want exactly one theme left:\n%s", got)
+	}
+}
+
+func TestUpdateAddsNewMapKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("themes:\n  Mine: # keep\n    base: dark\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := Update(path, func(cfg *Config) error {
+		cfg.Themes["second"] = ThemeConfig{Base: "light"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	got := string(data)
+	if !strings.Contains(got, "Mine: # keep") || !strings.Contains(got, "second:") || strings.Count(got, "base:") != 2 {
+		t.Errorf("unexpected result:\n%s", got)
+	}
+}
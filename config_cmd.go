@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/JohanLi233/gorss/config"
+)
+
+// runConfigCommand implements `gorss config <subcommand>`
+func runConfigCommand(paths config.Paths, args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return usageError("usage: gorss config check")
+	}
+
+	problems, err := config.Check(paths.ConfigFile)
+	if os.IsNotExist(err) {
+		fmt.Printf("%s does not exist; gorss creates a default config there on first start\n", paths.ConfigFile)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Printf("%s: OK\n", paths.ConfigFile)
+		return nil
+	}
+	// Compiler-style output so editors can jump to the line
+	for _, p := range problems {
+		location := paths.ConfigFile
+		if p.Line > 0 {
+			location += fmt.Sprintf(":%d", p.Line)
+			p.Line = 0
+		}
+		fmt.Printf("%s: %s\n", location, p)
+	}
+	if config.HasErrors(problems) {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// reportConfigError prints a config loading error in a form that tells the
+// user what to fix, and exits
+func reportConfigError(err error) {
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+		fmt.Fprintf(os.Stderr, "gorss can't start because of problems in %s:\n", verr.File)
+		for _, p := range verr.Problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", p)
+		}
+		fmt.Fprintf(os.Stderr, "\nFix them and run `gorss config check` to verify.\n")
+	} else {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+	}
+	os.Exit(1)
+}
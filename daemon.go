@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JohanLi233/gorss/api"
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+	"github.com/JohanLi233/gorss/greader"
+	"github.com/JohanLi233/gorss/websub"
+)
+
+// daemon is the fetch scheduler of `gorss serve`. Its feed list starts as the
+// configured one and changes when feeds are added or removed through the API.
+// With a sync backend it syncs with the server instead, and the feed list is
+// the server's subscriptions.
+type daemon struct {
+	paths  config.Paths
+	fm     *feed.FeedManager
+	logger *log.Logger
+	sub    *websub.Subscriber // nil without WebSub
+	syncer *greader.Syncer    // nil unless the feeds come from a sync server
+
+	mu          sync.Mutex
+	feeds       []config.Feed
+	status      map[string]api.FeedStatus
+	started     time.Time
+	lastRefresh time.Time
+	nextRefresh time.Time
+}
+
+func newDaemon(paths config.Paths, feeds []config.Feed, fm *feed.FeedManager, logger *log.Logger) *daemon {
+	return &daemon{
+		paths:   paths,
+		fm:      fm,
+		logger:  logger,
+		feeds:   append([]config.Feed(nil), feeds...),
+		status:  make(map[string]api.FeedStatus),
+		started: time.Now(),
+	}
+}
+
+// Feeds returns the feeds being scheduled
+func (d *daemon) Feeds() []config.Feed {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]config.Feed(nil), d.feeds...)
+}
+
+// Status reports when feeds were fetched and which failed
+func (d *daemon) Status() api.Status {
+	d.mu.Lock()
+	status := api.Status{
+		Started:     d.started,
+		LastRefresh: d.lastRefresh,
+		NextRefresh: d.nextRefresh,
+		Feeds:       make(map[string]api.FeedStatus, len(d.feeds)),
+	}
+	for _, f := range d.feeds {
+		status.Feeds[f.Name] = d.status[f.Name]
+	}
+	d.mu.Unlock()
+
+	for name, fs := range status.Feeds {
+		fs.Push = d.pushed(name)
+		status.Feeds[name] = fs
+	}
+	return status
+}
+
+// pushed reports whether a feed is updated by its WebSub hub
+func (d *daemon) pushed(name string) bool {
+	if d.sub == nil {
+		return false
+	}
+	hub, ok := d.fm.Hub(name)
+	return ok && d.sub.Active(hub.Self)
+}
+
+// Refresh fetches the feeds one after another and records the results
+func (d *daemon) Refresh(feeds []config.Feed) []api.RefreshResult {
+	if d.syncer != nil {
+		return d.sync(feeds)
+	}
+	results := make([]api.RefreshResult, 0, len(feeds))
+	for _, f := range feeds {
+		if f.URL == "" {
+			continue
+		}
+		added, err := d.fm.RefreshFeed(f)
+		result := api.RefreshResult{Feed: f.Name, New: added}
+		now := time.Now()
+		fs := api.FeedStatus{LastFetch: &now}
+		if err != nil {
+			d.logger.Printf("refresh: %v", err)
+			result.Error = err.Error()
+			fs.LastError = err.Error()
+		}
+		d.mu.Lock()
+		d.status[f.Name] = fs
+		d.mu.Unlock()
+		results = append(results, result)
+	}
+	for _, w := range d.fm.TakeWarnings() {
+		d.logger.Printf("%s", w)
+	}
+	return results
+}
+
+// sync syncs with the server, which fetches all feeds at once, and reports
+// the new articles of the given feeds, or of all if none are given
+func (d *daemon) sync(feeds []config.Feed) []api.RefreshResult {
+	known := make(map[string]bool)
+	for _, a := range d.fm.GetArticles() {
+		known[a.ID] = true
+	}
+	result, err := d.syncer.Sync()
+	for _, w := range result.Warnings {
+		d.logger.Printf("sync: %s", w)
+	}
+	now := time.Now()
+	fs := api.FeedStatus{LastFetch: &now}
+	if err != nil {
+		if n, perr := d.syncer.Pending(); perr == nil && n > 0 {
+			err = fmt.Errorf("%w (%d local changes queued)", err, n)
+		}
+		d.logger.Printf("%v", err)
+		fs.LastError = err.Error()
+	} else {
+		d.logger.Printf("sync: %d new articles, %d changes sent, %d from the server", result.New, result.Pushed, result.Pulled)
+	}
+	added := make(map[string]int)
+	for _, a := range d.fm.GetArticles() {
+		if !known[a.ID] {
+			added[a.Source]++
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err == nil {
+		d.feeds = result.Feeds
+		d.status = make(map[string]api.FeedStatus)
+	}
+	for _, f := range d.feeds {
+		d.status[f.Name] = fs
+	}
+	if len(feeds) == 0 {
+		feeds = d.feeds
+	}
+	results := make([]api.RefreshResult, 0, len(feeds))
+	for _, f := range feeds {
+		results = append(results, api.RefreshResult{Feed: f.Name, New: added[f.Name], Error: fs.LastError})
+	}
+	return results
+}
+
+// poll refreshes the feeds that don't get pushed updates
+func (d *daemon) poll(every time.Duration) {
+	var poll []config.Feed
+	for _, f := range d.Feeds() {
+		if !d.pushed(f.Name) {
+			poll = append(poll, f)
+		}
+	}
+	d.Refresh(poll)
+
+	d.mu.Lock()
+	d.lastRefresh = time.Now()
+	d.nextRefresh = d.lastRefresh.Add(every)
+	d.mu.Unlock()
+}
+
+// AddFeed subscribes to a feed in the config file and fetches it right away
+func (d *daemon) AddFeed(f config.Feed) error {
+	if d.syncer != nil {
+		return errRemoteFeeds
+	}
+	if err := config.AddFeed(d.paths.ConfigFile, f); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.feeds = append(d.feeds, f)
+	d.mu.Unlock()
+	d.logger.Printf("added feed %s", f.Name)
+
+	go func() {
+		d.Refresh([]config.Feed{f})
+		if d.sub != nil {
+			subscribeHubs(d.sub, []config.Feed{f}, d.fm, d.logger)
+		}
+	}()
+	return nil
+}
+
+// RemoveFeed unsubscribes from a feed in the config file and stops fetching it
+func (d *daemon) RemoveFeed(name string) (config.Feed, error) {
+	if d.syncer != nil {
+		return config.Feed{}, errRemoteFeeds
+	}
+	removed, err := config.RemoveFeed(d.paths.ConfigFile, name)
+	if err != nil {
+		return removed, err
+	}
+	d.mu.Lock()
+	for i, f := range d.feeds {
+		if strings.EqualFold(f.Name, name) {
+			d.feeds = append(d.feeds[:i:i], d.feeds[i+1:]...)
+			delete(d.status, f.Name)
+			break
+		}
+	}
+	d.mu.Unlock()
+	d.logger.Printf("removed feed %s", removed.Name)
+
+	if hub, ok := d.fm.Hub(removed.Name); ok && d.sub != nil && d.sub.Active(hub.Self) {
+		if err := d.sub.Unsubscribe(hub.Self); err != nil {
+			d.logger.Printf("websub: %v", err)
+		}
+	}
+	return removed, nil
+}
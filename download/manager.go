@@ -0,0 +1,307 @@
+// Package download implements a small download queue for article enclosures
+// with a concurrency limit, progress reporting and resumable transfers.
+package download
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the state of a download job
+type Status int
+
+const (
+	Queued Status = iota
+	Running
+	Done
+	Failed
+)
+
+// String returns a human readable status
+func (s Status) String() string {
+	switch s {
+	case Queued:
+		return "queued"
+	case Running:
+		return "downloading"
+	case Done:
+		return "done"
+	case Failed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// Job is a snapshot of one download
+type Job struct {
+	URL      string
+	Path     string // final location on disk
+	Title    string // what the user sees, usually the article title
+	Status   Status
+	Received int64
+	Total    int64 // 0 when the server did not send a length
+	Err      error
+}
+
+// Progress returns the completed fraction in [0, 1], or -1 if unknown
+func (j Job) Progress() float64 {
+	if j.Status == Done {
+		return 1
+	}
+	if j.Total <= 0 {
+		return -1
+	}
+	return float64(j.Received) / float64(j.Total)
+}
+
+// Manager runs queued downloads with at most Concurrency transfers at a time.
+// Partial downloads are kept as "<name>.part" and resumed with a Range request.
+type Manager struct {
+	sem     chan struct{}
+	client  *http.Client
+	updates chan struct{}
+
+	mu   sync.Mutex
+	dir  string
+	jobs []*Job
+}
+
+// NewManager creates a download manager saving into dir
+func NewManager(dir string, concurrency int) *Manager {
+	if concurrency < 1 {
+		concurrency = 2
+	}
+	return &Manager{
+		dir:     dir,
+		sem:     make(chan struct{}, concurrency),
+		client:  &http.Client{}, // no overall timeout: episodes can take a while
+		updates: make(chan struct{}, 1),
+	}
+}
+
+// Dir returns the download directory
+func (m *Manager) Dir() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dir
+}
+
+// SetDir changes the directory new downloads are saved to, e.g. after the
+// config is reloaded; queued and running downloads keep theirs
+func (m *Manager) SetDir(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dir = dir
+}
+
+// Updates returns a channel that receives a value whenever any job changes.
+// Notifications are coalesced, so call Jobs to get the current state.
+func (m *Manager) Updates() <-chan struct{} { return m.updates }
+
+// notify signals listeners without blocking
+func (m *Manager) notify() {
+	select {
+	case m.updates <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue adds a download unless the same URL is already queued, running or done.
+// It returns the path the file will be saved to.
+func (m *Manager) Enqueue(rawURL, title string) string {
+	m.mu.Lock()
+	for _, j := range m.jobs {
+		if j.URL == rawURL && j.Status != Failed {
+			m.mu.Unlock()
+			return j.Path
+		}
+	}
+
+	job := &Job{URL: rawURL, Path: filepath.Join(m.dir, fileName(rawURL)), Title: title}
+	replaced := false
+	for i, j := range m.jobs {
+		// A retry of a failed job replaces it in place
+		if j.URL == rawURL {
+			m.jobs[i] = job
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		m.jobs = append(m.jobs, job)
+	}
+	m.mu.Unlock()
+	m.notify()
+
+	go m.run(job)
+	return job.Path
+}
+
+// Jobs returns a snapshot of all jobs in queue order
+func (m *Manager) Jobs() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]Job, len(m.jobs))
+	for i, j := range m.jobs {
+		result[i] = *j
+	}
+	return result
+}
+
+// LocalPath returns the downloaded file for a URL, if it exists on disk
+func (m *Manager) LocalPath(rawURL string) (string, bool) {
+	p := filepath.Join(m.Dir(), fileName(rawURL))
+	if _, err := os.Stat(p); err == nil {
+		return p, true
+	}
+	return "", false
+}
+
+// run waits for a free slot and performs the transfer
+func (m *Manager) run(job *Job) {
+	m.sem <- struct{}{}
+	defer func() { <-m.sem }()
+
+	m.update(job, func(j *Job) { j.Status = Running })
+	err := m.transfer(job)
+	m.update(job, func(j *Job) {
+		if err != nil {
+			j.Status = Failed
+			j.Err = err
+		} else {
+			j.Status = Done
+		}
+	})
+}
+
+// update mutates a job under the lock and notifies listeners
+func (m *Manager) update(job *Job, fn func(*Job)) {
+	m.mu.Lock()
+	fn(job)
+	m.mu.Unlock()
+	m.notify()
+}
+
+// transfer downloads job.URL into job.Path, resuming a previous partial file
+func (m *Manager) transfer(job *Job) error {
+	if _, err := os.Stat(job.Path); err == nil {
+		// Already downloaded in an earlier session
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(job.Path), 0755); err != nil {
+		return err
+	}
+
+	partPath := job.Path + ".part"
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest("GET", job.URL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// Server ignored the range request; start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete
+		return os.Rename(partPath, job.Path)
+	default:
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	total := int64(0)
+	if resp.ContentLength > 0 {
+		total = offset + resp.ContentLength
+	}
+	m.update(job, func(j *Job) {
+		j.Received = offset
+		j.Total = total
+	})
+
+	f, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	lastNotify := time.Now()
+	received := offset
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := f.Write(buf[:n]); err != nil {
+				f.Close()
+				return err
+			}
+			received += int64(n)
+			// Throttle progress notifications to keep the UI responsive
+			if time.Since(lastNotify) > 200*time.Millisecond {
+				lastNotify = time.Now()
+				m.update(job, func(j *Job) { j.Received = received })
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			f.Close()
+			return readErr
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	m.update(job, func(j *Job) { j.Received = received })
+	return os.Rename(partPath, job.Path)
+}
+
+// fileName derives a safe local file name from a URL, keeping the extension
+func fileName(rawURL string) string {
+	name := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		name = path.Base(u.Path)
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+
+	// Many podcast hosts serve every episode as ".../media.mp3"; add a short hash to keep them apart
+	sum := sha1.Sum([]byte(rawURL))
+	prefix := hex.EncodeToString(sum[:4])
+	if name == "" || name == "." || name == "/" {
+		return prefix
+	}
+	return prefix + "-" + name
+}
@@ -0,0 +1,367 @@
+package export
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JohanLi233/gorss/feed"
+)
+
+const (
+	maxImageSize    = 5 << 20 // larger images are left out of the book
+	maxImages       = 500
+	imageFetchers   = 4
+	epubContentType = "application/epub+zip"
+)
+
+// imageTypes are the image formats EPUB readers must support, with the
+// extension used in the book
+var imageTypes = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+}
+
+// EPUBOptions controls how a book is built
+type EPUBOptions struct {
+	Title  string    // book title, defaults to "gorss <date>"
+	Images bool      // download the images of articles and embed them
+	Now    time.Time // publication date, defaults to the current time
+
+	// Fetch downloads an image; nil uses feed.FetchResource
+	Fetch func(url string) ([]byte, string, error)
+}
+
+// bookImage is an image embedded in the book
+type bookImage struct {
+	index       int
+	path        string // relative to the package document, e.g. images/img3.png
+	contentType string
+	data        []byte
+}
+
+// chapter is one article in the book, grouped under its feed in the contents
+type chapter struct {
+	article Article
+	path    string // e.g. text/a3.xhtml
+}
+
+// WriteEPUB bundles articles into an EPUB 3 book (with an EPUB 2 table of
+// contents for older readers). The contents group the articles by feed, in
+// the order the feeds first appear in articles.
+func WriteEPUB(w io.Writer, articles []Article, opts EPUBOptions) error {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Title == "" {
+		opts.Title = "gorss " + opts.Now.Format("2006-01-02")
+	}
+	if opts.Fetch == nil {
+		opts.Fetch = func(u string) ([]byte, string, error) { return feed.FetchResource(u, maxImageSize) }
+	}
+
+	// Group by feed
+	var feeds []string
+	byFeed := make(map[string][]chapter)
+	n := 0
+	for _, a := range articles {
+		if _, ok := byFeed[a.FeedName]; !ok {
+			feeds = append(feeds, a.FeedName)
+		}
+		n++
+		byFeed[a.FeedName] = append(byFeed[a.FeedName], chapter{article: a, path: fmt.Sprintf("text/a%d.xhtml", n)})
+	}
+	var chapters []chapter
+	for _, name := range feeds {
+		chapters = append(chapters, byFeed[name]...)
+	}
+
+	var images map[string]bookImage
+	if opts.Images {
+		images = fetchImages(chapters, opts.Fetch)
+	}
+
+	zw := zip.NewWriter(w)
+	// The mimetype file comes first and is stored uncompressed so readers can sniff it
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mw, epubContentType); err != nil {
+		return err
+	}
+
+	files := []struct{ name, data string }{
+		{"META-INF/container.xml", containerXML},
+		{"OEBPS/style.css", bookCSS},
+		{"OEBPS/content.opf", packageDocument(opts, feeds, chapters, images)},
+		{"OEBPS/nav.xhtml", navDocument(opts.Title, feeds, byFeed)},
+		{"OEBPS/toc.ncx", ncxDocument(opts.Title, feeds, byFeed)},
+	}
+	for _, ch := range chapters {
+		files = append(files, struct{ name, data string }{"OEBPS/" + ch.path, chapterDocument(ch.article, images)})
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.data); err != nil {
+			return err
+		}
+	}
+
+	for _, img := range sortedImages(images) {
+		fw, err := zw.Create("OEBPS/" + img.path)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(img.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// fetchImages downloads the images of all chapters, keyed by URL. Images
+// that fail to download or aren't in a format readers support are left out.
+func fetchImages(chapters []chapter, fetch func(string) ([]byte, string, error)) map[string]bookImage {
+	seen := make(map[string]bool)
+	var urls []string
+	for _, ch := range chapters {
+		content, base := chapterSource(ch.article)
+		for _, u := range imageURLs(content, base) {
+			if !seen[u] && len(urls) < maxImages {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+
+	images := make(map[string]bookImage)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for i := 0; i < imageFetchers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				data, header, err := fetch(urls[i])
+				if err != nil {
+					continue
+				}
+				contentType := http.DetectContentType(data)
+				if strings.HasPrefix(header, "image/svg+xml") {
+					contentType = "image/svg+xml"
+				}
+				ext, ok := imageTypes[contentType]
+				if !ok {
+					continue
+				}
+				mu.Lock()
+				images[urls[i]] = bookImage{index: i, path: fmt.Sprintf("images/img%d%s", i+1, ext), contentType: contentType, data: data}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return images
+}
+
+// sortedImages returns the images in the order they were found
+func sortedImages(images map[string]bookImage) []bookImage {
+	out := make([]bookImage, 0, len(images))
+	for _, img := range images {
+		out = append(out, img)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].index < out[j].index })
+	return out
+}
+
+// chapterSource returns the HTML content of an article and the URL relative
+// links in it are resolved against
+func chapterSource(a Article) (string, *url.URL) {
+	content := a.Content
+	if content == "" {
+		content = a.Description
+	}
+	if a.ContentType == feed.GemtextContentType {
+		content = gemtextToHTML(content)
+	}
+	base, err := url.Parse(a.Link)
+	if err != nil {
+		base = nil
+	}
+	return content, base
+}
+
+func chapterDocument(a Article, images map[string]bookImage) string {
+	content, base := chapterSource(a)
+	body := cleanXHTML(content, base, func(src string) string {
+		if img, ok := images[src]; ok {
+			return "../" + img.path
+		}
+		return ""
+	})
+
+	meta := []string{a.Published.Format("2006-01-02 15:04"), xmlEscape(a.FeedName)}
+	if len(a.Authors) > 0 {
+		meta = append(meta, xmlEscape(strings.Join(a.Authors, ", ")))
+	}
+	if link, ok := resolveURL(nil, a.Link); ok {
+		meta = append(meta, `<a href="`+xmlEscape(link)+`">original</a>`)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(xhtmlHeader(a.Title, "../style.css"))
+	sb.WriteString("<h1>" + xmlEscape(oneLine(a.Title)) + "</h1>\n")
+	sb.WriteString(`<p class="meta">` + strings.Join(meta, " · ") + "</p>\n")
+	sb.WriteString(body)
+	sb.WriteString("\n</body>\n</html>\n")
+	return sb.String()
+}
+
+func xhtmlHeader(title, css string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+<title>` + xmlEscape(oneLine(title)) + `</title>
+<link rel="stylesheet" type="text/css" href="` + css + `"/>
+</head>
+<body>
+`
+}
+
+func packageDocument(opts EPUBOptions, feeds []string, chapters []chapter, images map[string]bookImage) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	fmt.Fprintf(&sb, "<dc:identifier id=\"bookid\">urn:uuid:%s</dc:identifier>\n", newUUID())
+	fmt.Fprintf(&sb, "<dc:title>%s</dc:title>\n", xmlEscape(opts.Title))
+	sb.WriteString("<dc:language>und</dc:language>\n")
+	sb.WriteString("<dc:creator>gorss</dc:creator>\n")
+	fmt.Fprintf(&sb, "<dc:date>%s</dc:date>\n", opts.Now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "<dc:description>%d articles from %s</dc:description>\n", len(chapters), xmlEscape(strings.Join(feeds, ", ")))
+	fmt.Fprintf(&sb, "<meta property=\"dcterms:modified\">%s</meta>\n", opts.Now.UTC().Format("2006-01-02T15:04:05Z"))
+	sb.WriteString(`</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+<item id="css" href="style.css" media-type="text/css"/>
+`)
+	for i, ch := range chapters {
+		fmt.Fprintf(&sb, "<item id=\"c%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, ch.path)
+	}
+	for _, img := range sortedImages(images) {
+		id := strings.TrimSuffix(strings.TrimPrefix(img.path, "images/"), imageTypes[img.contentType])
+		fmt.Fprintf(&sb, "<item id=\"%s\" href=\"%s\" media-type=\"%s\"/>\n", id, img.path, img.contentType)
+	}
+	sb.WriteString("</manifest>\n<spine toc=\"ncx\">\n<itemref idref=\"nav\"/>\n")
+	for i := range chapters {
+		fmt.Fprintf(&sb, "<itemref idref=\"c%d\"/>\n", i+1)
+	}
+	sb.WriteString("</spine>\n</package>\n")
+	return sb.String()
+}
+
+// navDocument is the EPUB 3 table of contents: feeds, each with its articles
+func navDocument(title string, feeds []string, byFeed map[string][]chapter) string {
+	var sb strings.Builder
+	sb.WriteString(xhtmlHeader(title, "style.css"))
+	sb.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n<h1>" + xmlEscape(title) + "</h1>\n<ol>\n")
+	for _, name := range feeds {
+		chapters := byFeed[name]
+		fmt.Fprintf(&sb, "<li><a href=\"%s\">%s</a>\n<ol>\n", chapters[0].path, xmlEscape(feedLabel(name)))
+		for _, ch := range chapters {
+			fmt.Fprintf(&sb, "<li><a href=\"%s\">%s</a></li>\n", ch.path, xmlEscape(titleLabel(ch.article)))
+		}
+		sb.WriteString("</ol>\n</li>\n")
+	}
+	sb.WriteString("</ol>\n</nav>\n</body>\n</html>\n")
+	return sb.String()
+}
+
+// ncxDocument is the same table of contents for EPUB 2 readers
+func ncxDocument(title string, feeds []string, byFeed map[string][]chapter) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+<head><meta name="dtb:depth" content="2"/></head>
+`)
+	fmt.Fprintf(&sb, "<docTitle><text>%s</text></docTitle>\n<navMap>\n", xmlEscape(title))
+	order := 0
+	for i, name := range feeds {
+		chapters := byFeed[name]
+		order++
+		fmt.Fprintf(&sb, "<navPoint id=\"f%d\" playOrder=\"%d\"><navLabel><text>%s</text></navLabel><content src=\"%s\"/>\n",
+			i+1, order, xmlEscape(feedLabel(name)), chapters[0].path)
+		for j, ch := range chapters {
+			if j > 0 {
+				// The feed entry already points at the first article
+				order++
+			}
+			fmt.Fprintf(&sb, "<navPoint id=\"f%da%d\" playOrder=\"%d\"><navLabel><text>%s</text></navLabel><content src=\"%s\"/></navPoint>\n",
+				i+1, j+1, order, xmlEscape(titleLabel(ch.article)), ch.path)
+		}
+		sb.WriteString("</navPoint>\n")
+	}
+	sb.WriteString("</navMap>\n</ncx>\n")
+	return sb.String()
+}
+
+func feedLabel(name string) string {
+	if name == "" {
+		return "Untitled feed"
+	}
+	return oneLine(name)
+}
+
+func titleLabel(a Article) string {
+	if t := oneLine(a.Title); t != "" {
+		return t
+	}
+	return a.Published.Format("2006-01-02 15:04")
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+const bookCSS = `body { font-family: serif; line-height: 1.4; }
+h1 { font-size: 1.5em; }
+.meta { color: #666; font-size: 0.85em; }
+img { max-width: 100%; }
+pre { white-space: pre-wrap; font-size: 0.85em; }
+blockquote { margin-left: 1em; font-style: italic; }
+`
@@ -0,0 +1,229 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JohanLi233/gorss/feed"
+)
+
+var pngData = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// wellFormed reports the first XML error in doc, if any
+func wellFormed(doc string) error {
+	d := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func readEPUB(t *testing.T, data []byte) (*zip.Reader, map[string]string) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(b)
+	}
+	return zr, files
+}
+
+type opfPackage struct {
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+type ncxPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	PlayOrder int        `xml:"playOrder,attr"`
+	Points    []ncxPoint `xml:"navPoint"`
+}
+
+func TestWriteEPUB(t *testing.T) {
+	articles := []Article{
+		{Article: feed.Article{FeedName: "Go Blog", Title: "First", Link: "https://go.dev/blog/first",
+			Content: `<p>Logo <img src="/logo.png" alt="logo"> and <img src="https://cdn.example.com/broken.png" alt="broken"></p>`}},
+		{Article: feed.Article{FeedName: "Other", Title: "Elsewhere", Content: "<p>other</p>"}},
+		{Article: feed.Article{FeedName: "Go Blog", Title: "", Published: published, Link: "https://go.dev/blog/second", Description: "<p>only a description</p>"}},
+	}
+	fetch := func(u string) ([]byte, string, error) {
+		if u == "https://go.dev/logo.png" {
+			return pngData, "image/png", nil
+		}
+		return nil, "", errors.New("not found")
+	}
+	var buf bytes.Buffer
+	if err := WriteEPUB(&buf, articles, EPUBOptions{Title: "Digest", Images: true, Fetch: fetch}); err != nil {
+		t.Fatal(err)
+	}
+	zr, files := readEPUB(t, buf.Bytes())
+
+	first := zr.File[0]
+	if first.Name != "mimetype" || first.Method != zip.Store || files["mimetype"] != "application/epub+zip" {
+		t.Errorf("first entry %q (method %d) = %q, want an uncompressed mimetype", first.Name, first.Method, files["mimetype"])
+	}
+	if !strings.Contains(files["META-INF/container.xml"], `full-path="OEBPS/content.opf"`) {
+		t.Errorf("container.xml doesn't point at the package:\n%s", files["META-INF/container.xml"])
+	}
+	for name, data := range files {
+		if strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".opf") || strings.HasSuffix(name, ".xhtml") || strings.HasSuffix(name, ".ncx") {
+			if err := wellFormed(data); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+
+	var pkg opfPackage
+	if err := xml.Unmarshal([]byte(files["OEBPS/content.opf"]), &pkg); err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]bool)
+	for _, item := range pkg.Manifest {
+		ids[item.ID] = true
+		if _, ok := files[path.Join("OEBPS", item.Href)]; !ok {
+			t.Errorf("manifest item %s (%s) not in the book", item.ID, item.Href)
+		}
+	}
+	if len(pkg.Manifest) != 3+len(articles)+1 {
+		t.Errorf("%d manifest items, want nav, ncx, css, %d chapters and one image", len(pkg.Manifest), len(articles))
+	}
+	var spine []string
+	for _, ref := range pkg.Spine {
+		if !ids[ref.IDRef] {
+			t.Errorf("spine item %s not in the manifest", ref.IDRef)
+		}
+		spine = append(spine, ref.IDRef)
+	}
+	if want := []string{"nav", "c1", "c2", "c3"}; !reflect.DeepEqual(spine, want) {
+		t.Errorf("spine %q, want %q", spine, want)
+	}
+
+	// Chapters are grouped by feed: the second Go Blog article comes before Other
+	var ncx struct {
+		Points []ncxPoint `xml:"navMap>navPoint"`
+	}
+	if err := xml.Unmarshal([]byte(files["OEBPS/toc.ncx"]), &ncx); err != nil {
+		t.Fatal(err)
+	}
+	var toc []string
+	orders := make(map[int]string)
+	for _, f := range ncx.Points {
+		toc = append(toc, f.Label)
+		orders[f.PlayOrder] = f.Content.Src
+		for _, a := range f.Points {
+			toc = append(toc, "  "+a.Label+" "+a.Content.Src)
+			if src, ok := orders[a.PlayOrder]; ok && src != a.Content.Src {
+				t.Errorf("play order %d used for %s and %s", a.PlayOrder, src, a.Content.Src)
+			}
+			orders[a.PlayOrder] = a.Content.Src
+		}
+	}
+	want := []string{"Go Blog", "  First text/a1.xhtml", "  2024-05-01 08:30 text/a3.xhtml", "Other", "  Elsewhere text/a2.xhtml"}
+	if !reflect.DeepEqual(toc, want) {
+		t.Errorf("NCX contents\n got %q\nwant %q", toc, want)
+	}
+	nav := files["OEBPS/nav.xhtml"]
+	if i, j := strings.Index(nav, "text/a3.xhtml"), strings.Index(nav, ">Other<"); i < 0 || j < 0 || i > j {
+		t.Errorf("nav doesn't group by feed:\n%s", nav)
+	}
+
+	chapter := files["OEBPS/text/a1.xhtml"]
+	if !strings.Contains(chapter, `<img src="../images/img1.png" alt="logo"/>`) || !strings.Contains(chapter, "[broken]") {
+		t.Errorf("first chapter images:\n%s", chapter)
+	}
+	if files["OEBPS/images/img1.png"] != string(pngData) {
+		t.Error("image not stored")
+	}
+	if !strings.Contains(files["OEBPS/text/a3.xhtml"], "only a description") {
+		t.Error("description not used without content")
+	}
+}
+
+func TestWriteEPUBWithoutImages(t *testing.T) {
+	articles := []Article{{Article: feed.Article{FeedName: "Go Blog", Title: "First", Content: `<img src="https://go.dev/logo.png" alt="logo">`}}}
+	fetch := func(string) ([]byte, string, error) {
+		t.Error("image fetched")
+		return nil, "", errors.New("unexpected")
+	}
+	var buf bytes.Buffer
+	if err := WriteEPUB(&buf, articles, EPUBOptions{Fetch: fetch}); err != nil {
+		t.Fatal(err)
+	}
+	_, files := readEPUB(t, buf.Bytes())
+	if !strings.Contains(files["OEBPS/text/a1.xhtml"], "[logo]") {
+		t.Errorf("image not replaced by its alt text:\n%s", files["OEBPS/text/a1.xhtml"])
+	}
+	if !strings.Contains(files["OEBPS/content.opf"], "<dc:title>gorss ") {
+		t.Error("no default title")
+	}
+}
+
+func TestCleanXHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+	images := func(src string) string {
+		if src == "https://example.com/blog/cat.jpg" {
+			return "../images/img1.jpg"
+		}
+		return ""
+	}
+	tests := []struct {
+		name, in, want string
+	}{
+		{"script", `<p>a<script>alert("x")</script>b</p>`, "<p>ab</p>"},
+		{"iframe", `<iframe src="https://evil.example/"><p>fallback</p></iframe><p>kept</p>`, "<p>kept</p>"},
+		{"style and form", `<style>p{}</style><form><input name="q"></form>text`, "text"},
+		{"unknown tags unwrapped", `<section><article>inside</article></section>`, "inside"},
+		{"attributes dropped", `<p class="x" onclick="bad()" style="color:red">hi</p>`, "<p>hi</p>"},
+		{"unclosed tags", `<p>one<p>two<br>`, "<p>one</p><p>two<br/></p>"},
+		{"entities", `a &amp; b &lt;c&gt; &nbsp;d`, "a &amp; b &lt;c&gt; \u00a0d"},
+		{"relative link", `<a href="../about" title="t">about</a>`, `<a href="https://example.com/about">about</a>`},
+		{"script link", `<a href="javascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"kept image", `<img src="cat.jpg" width="10">`, `<img src="../images/img1.jpg" alt=""/>`},
+		{"missing image", `<img src="dog.jpg" alt="a <dog>">`, "[a &lt;dog&gt;]"},
+		{"table spans", `<table><tr><td colspan="2" bgcolor="red">x</td></tr></table>`, `<table><tbody><tr><td colspan="2">x</td></tr></tbody></table>`},
+		{"control characters", "a\x00b\x1bc", "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cleanXHTML(tt.in, base, images)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if err := wellFormed("<body>" + got + "</body>"); err != nil {
+				t.Errorf("%q: %v", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+// Package export writes articles in formats other tools can read: JSON and
+// NDJSON, CSV for spreadsheets, Markdown notes with YAML front matter (one
+// file per article, e.g. for an Obsidian vault), a single static HTML page
+// and EPUB books for e-readers.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+// Format is an export format, one of config.ExportFormats
+type Format string
+
+const (
+	Markdown Format = "markdown"
+	JSON     Format = "json"
+	NDJSON   Format = "ndjson"
+	CSV      Format = "csv"
+	HTML     Format = "html"
+	EPUB     Format = "epub"
+)
+
+// ParseFormat looks up a format by name; "md" and "jsonl" are accepted as aliases
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "md":
+		return Markdown, nil
+	case "jsonl":
+		return NDJSON, nil
+	}
+	for _, f := range config.ExportFormats {
+		if name == f {
+			return Format(f), nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q, expected one of %s", name, strings.Join(config.ExportFormats, ", "))
+}
+
+// Ext returns the file extension used for the format
+func (f Format) Ext() string {
+	if f == Markdown {
+		return ".md"
+	}
+	return "." + string(f)
+}
+
+// Article is an article prepared for export
+type Article struct {
+	feed.Article
+	Text    string // the content as plain text
+	Starred bool
+	Read    bool
+}
+
+// Prepare converts articles for export. text renders the content of an
+// article as plain text; state may be nil.
+func Prepare(articles []feed.Article, state *feed.State, text func(feed.Article) string) []Article {
+	out := make([]Article, 0, len(articles))
+	for _, a := range articles {
+		e := Article{Article: a, Text: text(a)}
+		if state != nil {
+			e.Starred = state.IsStarred(a.ID)
+			e.Read = state.IsRead(a.ID)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// Write writes articles to w in one of the single-file formats
+func Write(w io.Writer, format Format, articles []Article) error {
+	switch format {
+	case JSON:
+		records := make([]record, 0, len(articles))
+		for _, a := range articles {
+			records = append(records, newRecord(a))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case NDJSON:
+		enc := json.NewEncoder(w)
+		for _, a := range articles {
+			if err := enc.Encode(newRecord(a)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CSV:
+		return writeCSV(w, articles)
+	case HTML:
+		return writeHTML(w, articles)
+	case EPUB:
+		return WriteEPUB(w, articles, EPUBOptions{Images: true})
+	}
+	return fmt.Errorf("%s writes one file per article; export it to a directory", format)
+}
+
+// ToDir exports articles into dir and returns what was written: the file
+// for single-file formats, named after the export time, or dir itself for
+// Markdown
+func ToDir(dir string, format Format, articles []Article, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if format == Markdown {
+		if _, err := WriteMarkdown(dir, articles); err != nil {
+			return "", err
+		}
+		return dir, nil
+	}
+
+	path := filepath.Join(dir, "gorss-export-"+now.Format("20060102-150405")+format.Ext())
+	return path, ToFile(path, format, articles)
+}
+
+// ToFile exports articles in one of the single-file formats to path
+func ToFile(path string, format Format, articles []Article) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, format, articles); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// record is an article in the JSON formats
+type record struct {
+	ID          string      `json:"id"`
+	Feed        string      `json:"feed"`
+	Title       string      `json:"title"`
+	Link        string      `json:"link,omitempty"`
+	Published   time.Time   `json:"published"`
+	Updated     *time.Time  `json:"updated,omitempty"`
+	Authors     []string    `json:"authors,omitempty"`
+	Categories  []string    `json:"categories,omitempty"`
+	Enclosures  []enclosure `json:"enclosures,omitempty"`
+	CommentsURL string      `json:"comments_url,omitempty"`
+	Starred     bool        `json:"starred"`
+	Read        bool        `json:"read"`
+	ContentType string      `json:"content_type,omitempty"`
+	Content     string      `json:"content,omitempty"`
+	Text        string      `json:"text"`
+}
+
+type enclosure struct {
+	URL    string `json:"url"`
+	Type   string `json:"type,omitempty"`
+	Length int64  `json:"length,omitempty"`
+}
+
+func newRecord(a Article) record {
+	r := record{
+		ID:          a.ID,
+		Feed:        a.FeedName,
+		Title:       a.Title,
+		Link:        a.Link,
+		Published:   a.Published,
+		Authors:     a.Authors,
+		Categories:  a.Categories,
+		CommentsURL: a.CommentsURL,
+		Starred:     a.Starred,
+		Read:        a.Read,
+		ContentType: a.ContentType,
+		Content:     a.Content,
+		Text:        a.Text,
+	}
+	if !a.Updated.IsZero() {
+		updated := a.Updated
+		r.Updated = &updated
+	}
+	for _, e := range a.Enclosures {
+		r.Enclosures = append(r.Enclosures, enclosure{URL: e.URL, Type: e.Type, Length: e.Length})
+	}
+	return r
+}
+
+func writeCSV(w io.Writer, articles []Article) error {
+	cw := csv.NewWriter(w)
+	header := []string{"id", "feed", "title", "link", "published", "authors", "categories", "starred", "read", "text"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, a := range articles {
+		row := []string{
+			a.ID,
+			a.FeedName,
+			a.Title,
+			a.Link,
+			a.Published.Format(time.RFC3339),
+			strings.Join(a.Authors, "; "),
+			strings.Join(a.Categories, "; "),
+			strconv.FormatBool(a.Starred),
+			strconv.FormatBool(a.Read),
+			a.Text,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,239 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JohanLi233/gorss/feed"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+var published = time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+
+func testArticles() []Article {
+	return []Article{
+		{
+			Article: feed.Article{
+				ID: "00000000a1", FeedName: "Go Blog", Title: "Go 1.23, \"iterators\"\nand more",
+				Link: "https://go.dev/blog/go1.23", Published: published, Updated: published.Add(time.Hour),
+				Authors: []string{"Gopher", "Rob"}, Categories: []string{"release notes", "go"},
+				Enclosures: []feed.Enclosure{{URL: "https://go.dev/talk.mp3", Type: "audio/mpeg", Length: 1234}},
+				Content:    "<p>Hello, <b>world</b></p>",
+			},
+			Text: "Hello, world\n\nSecond paragraph, with a comma.", Starred: true, Read: true,
+		},
+		{
+			Article: feed.Article{ID: "00000000b2", FeedName: "日本語", Title: "日本語のタイトル", Published: published},
+			Text:    "本文",
+		},
+		{
+			// Same day and title as the first: the note name needs the ID
+			Article: feed.Article{ID: "00000000c3", FeedName: "Go Blog", Title: "Go 1.23, \"iterators\"\nand more", Published: published},
+		},
+		{
+			// Nothing to make a slug of
+			Article: feed.Article{ID: "00000000d4", FeedName: "Misc", Title: "!!!", Published: published},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"markdown": Markdown, " MD ": Markdown, "jsonl": NDJSON, "Json": JSON, "csv": CSV, "html": HTML, "epub": EPUB} {
+		if got, err := ParseFormat(name); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("ParseFormat(pdf) succeeded")
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	articles := testArticles()
+	var buf bytes.Buffer
+	if err := Write(&buf, JSON, articles); err != nil {
+		t.Fatal(err)
+	}
+	var records []record
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, records, articles)
+}
+
+func TestNDJSONRoundTrip(t *testing.T) {
+	articles := testArticles()
+	var buf bytes.Buffer
+	if err := Write(&buf, NDJSON, articles); err != nil {
+		t.Fatal(err)
+	}
+	var records []record
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("line %d: %v", len(records)+1, err)
+		}
+		records = append(records, r)
+	}
+	checkRecords(t, records, articles)
+}
+
+func checkRecords(t *testing.T, records []record, articles []Article) {
+	t.Helper()
+	if len(records) != len(articles) {
+		t.Fatalf("%d records, want %d", len(records), len(articles))
+	}
+	for i, a := range articles {
+		want := newRecord(a)
+		got := records[i]
+		if !got.Published.Equal(want.Published) {
+			t.Errorf("record %d published %v, want %v", i, got.Published, want.Published)
+		}
+		got.Published = want.Published
+		if (got.Updated == nil) != (want.Updated == nil) || got.Updated != nil && !got.Updated.Equal(*want.Updated) {
+			t.Errorf("record %d updated %v, want %v", i, got.Updated, want.Updated)
+		}
+		got.Updated = want.Updated
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("record %d:\n got %+v\nwant %+v", i, got, want)
+		}
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	articles := testArticles()
+	var buf bytes.Buffer
+	if err := Write(&buf, CSV, articles); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(articles)+1 {
+		t.Fatalf("%d rows, want a header and %d", len(rows), len(articles))
+	}
+	if strings.Join(rows[0], ",") != "id,feed,title,link,published,authors,categories,starred,read,text" {
+		t.Errorf("header %v", rows[0])
+	}
+	first := rows[1]
+	want := []string{"00000000a1", "Go Blog", articles[0].Title, articles[0].Link, "2024-05-01T08:30:00Z",
+		"Gopher; Rob", "release notes; go", "true", "true", articles[0].Text}
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("first row\n got %q\nwant %q", first, want)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	articles := testArticles()
+	articles[0].Title = `<script>alert(1)</script>`
+	var buf bytes.Buffer
+	if err := Write(&buf, HTML, articles); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := html.Parse(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "<script>") {
+		t.Error("title not escaped")
+	}
+	for _, want := range []string{"日本語のタイトル", "Second paragraph, with a comma.", "https://go.dev/blog/go1.23"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+}
+
+func TestWriteRefusesMarkdown(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, Markdown, testArticles()); err == nil {
+		t.Error("Markdown written to a single file")
+	}
+}
+
+func TestMarkdownNotes(t *testing.T) {
+	dir := t.TempDir()
+	articles := testArticles()
+	paths, err := WriteMarkdown(dir, articles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range paths {
+		names = append(names, filepath.Base(p))
+	}
+	want := []string{
+		"2024-05-01-go-1-23-iterators-and-more.md",
+		"2024-05-01-日本語のタイトル.md",
+		"2024-05-01-go-1-23-iterators-and-more-00000000c3.md",
+		"2024-05-01-00000000d4.md",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("notes\n got %q\nwant %q", names, want)
+	}
+
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, body, ok := strings.Cut(strings.TrimPrefix(string(data), "---\n"), "\n---\n")
+	if !strings.HasPrefix(string(data), "---\n") || !ok {
+		t.Fatalf("no front matter in %q", data)
+	}
+	var fm frontMatter
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		t.Fatal(err)
+	}
+	if fm.Title != `Go 1.23, "iterators" and more` || fm.ID != "00000000a1" || fm.Feed != "Go Blog" || fm.URL != articles[0].Link {
+		t.Errorf("front matter %+v", fm)
+	}
+	if !fm.Published.Equal(published) || fm.Updated == nil || !fm.Updated.Equal(published.Add(time.Hour)) {
+		t.Errorf("dates %v %v", fm.Published, fm.Updated)
+	}
+	if !reflect.DeepEqual(fm.Tags, []string{"release-notes", "go"}) || !reflect.DeepEqual(fm.Authors, []string{"Gopher", "Rob"}) || !fm.Starred {
+		t.Errorf("tags %q, authors %q, starred %v", fm.Tags, fm.Authors, fm.Starred)
+	}
+	if !reflect.DeepEqual(fm.Enclosures, []string{"https://go.dev/talk.mp3"}) {
+		t.Errorf("enclosures %q", fm.Enclosures)
+	}
+	for _, want := range []string{"# Go 1.23, \"iterators\" and more\n", "Second paragraph", "[Original article](<https://go.dev/blog/go1.23>)"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("note body lacks %q:\n%s", want, body)
+		}
+	}
+
+	// Exporting again replaces the notes instead of adding more
+	if _, err := WriteMarkdown(dir, articles); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != len(articles) {
+		t.Errorf("%d files after exporting twice, want %d", len(entries), len(articles))
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":            "hello-world",
+		"  leading and trailing  ": "leading-and-trailing",
+		"Ünïcödé Straße":           "ünïcödé-straße",
+		"中文 标题":                    "中文-标题",
+		"!!!":                      "",
+		strings.Repeat("a", 100):   strings.Repeat("a", 60),
+	}
+	for in, want := range tests {
+		if got := slug(in); got != want {
+			t.Errorf("slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
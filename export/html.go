@@ -0,0 +1,59 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"strings"
+)
+
+// The HTML export is one self-contained page. Articles are shown as their
+// plain text, never as the feed's HTML, so the page can't run feed scripts.
+var pageTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"paragraphs": paragraphs,
+	"join":       strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gorss export</title>
+<style>
+body { max-width: 42em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; }
+article { border-top: 1px solid #ccc; margin-top: 2em; }
+.meta { color: #777; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>gorss export</h1>
+<p class="meta">{{len .}} articles</p>
+<ol>
+{{- range .}}
+<li><a href="#a-{{.ID}}">{{.Title}}</a></li>
+{{- end}}
+</ol>
+{{- range .}}
+<article id="a-{{.ID}}">
+<h2>{{if .Link}}<a href="{{.Link}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</h2>
+<p class="meta">{{.Published.Format "2006-01-02 15:04"}} · {{.FeedName}}{{if .Authors}} · {{join .Authors ", "}}{{end}}{{if .Starred}} · ★{{end}}</p>
+{{- range paragraphs .Text}}
+<p>{{.}}</p>
+{{- end}}
+</article>
+{{- end}}
+</body>
+</html>
+`))
+
+func writeHTML(w io.Writer, articles []Article) error {
+	return pageTemplate.Execute(w, articles)
+}
+
+// paragraphs splits text at blank lines
+func paragraphs(text string) []string {
+	var out []string
+	for _, p := range strings.Split(text, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
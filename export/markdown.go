@@ -0,0 +1,132 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter is the YAML header of a Markdown note
+type frontMatter struct {
+	Title      string     `yaml:"title"`
+	ID         string     `yaml:"id"`
+	Feed       string     `yaml:"feed"`
+	URL        string     `yaml:"url,omitempty"`
+	Published  time.Time  `yaml:"published"`
+	Updated    *time.Time `yaml:"updated,omitempty"`
+	Authors    []string   `yaml:"authors,omitempty"`
+	Tags       []string   `yaml:"tags,omitempty"`
+	Enclosures []string   `yaml:"enclosures,omitempty"`
+	Starred    bool       `yaml:"starred,omitempty"`
+}
+
+// WriteMarkdown writes one note per article into dir and returns their
+// paths. Notes are named after the date and title, so exporting an article
+// again replaces its note.
+func WriteMarkdown(dir string, articles []Article) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	var paths []string
+	for _, a := range articles {
+		name := a.Published.Format("2006-01-02")
+		s := slug(a.Title)
+		if s != "" {
+			name += "-" + s
+		}
+		if s == "" || used[name] {
+			name += "-" + a.ID
+		}
+		used[name] = true
+
+		data, err := markdownNote(a)
+		if err != nil {
+			return paths, err
+		}
+		path := filepath.Join(dir, name+Markdown.Ext())
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// markdownNote renders an article as a note with YAML front matter
+func markdownNote(a Article) ([]byte, error) {
+	fm := frontMatter{
+		Title:     oneLine(a.Title),
+		ID:        a.ID,
+		Feed:      a.FeedName,
+		URL:       a.Link,
+		Published: a.Published,
+		Authors:   a.Authors,
+		Starred:   a.Starred,
+	}
+	if !a.Updated.IsZero() {
+		updated := a.Updated
+		fm.Updated = &updated
+	}
+	for _, c := range a.Categories {
+		// Tags can't contain spaces
+		if tag := strings.Join(strings.Fields(c), "-"); tag != "" {
+			fm.Tags = append(fm.Tags, tag)
+		}
+	}
+	for _, e := range a.Enclosures {
+		fm.Enclosures = append(fm.Enclosures, e.URL)
+	}
+	header, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
+	buf.WriteString("---\n\n")
+	buf.WriteString("# " + oneLine(a.Title) + "\n\n")
+	if text := strings.TrimSpace(a.Text); text != "" {
+		buf.WriteString(text + "\n\n")
+	}
+	if a.Link != "" {
+		buf.WriteString("[Original article](<" + a.Link + ">)\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// slug turns a title into a file name part: lower case letters and digits
+// (of any script) joined by dashes, at most 60 of them
+func slug(title string) string {
+	var sb strings.Builder
+	dash := false
+	n := 0
+	for _, r := range strings.ToLower(title) {
+		if n >= 60 {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+				n++
+			}
+			sb.WriteRune(r)
+			n++
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return sb.String()
+}
+
+// oneLine joins the lines of s with spaces
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
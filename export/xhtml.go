@@ -0,0 +1,257 @@
+package export
+
+import (
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/JohanLi233/gorss/feed"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// EPUB readers parse content documents as XML and many refuse anything
+// outside a small set of elements, so feed HTML is rebuilt from an allow list
+// instead of being copied.
+
+// keptTags are written as they are (with only the attributes in keptAttrs)
+var keptTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Hr: true, atom.Div: true, atom.Span: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Code: true, atom.Q: true, atom.Cite: true,
+	atom.Em: true, atom.Strong: true, atom.I: true, atom.B: true, atom.U: true, atom.S: true,
+	atom.Sub: true, atom.Sup: true, atom.Small: true, atom.Mark: true, atom.Abbr: true,
+	atom.Del: true, atom.Ins: true, atom.A: true, atom.Img: true,
+	atom.Figure: true, atom.Figcaption: true,
+	atom.Table: true, atom.Caption: true, atom.Thead: true, atom.Tbody: true, atom.Tfoot: true,
+	atom.Tr: true, atom.Th: true, atom.Td: true,
+}
+
+// droppedTags are removed together with their content; other unknown
+// elements are replaced by their content
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Applet: true,
+	atom.Form: true, atom.Input: true, atom.Button: true, atom.Select: true, atom.Textarea: true,
+	atom.Video: true, atom.Audio: true, atom.Canvas: true, atom.Svg: true, atom.Math: true,
+	atom.Head: true, atom.Title: true, atom.Meta: true, atom.Link: true,
+}
+
+var keptAttrs = map[atom.Atom][]string{
+	atom.A:   {"href"},
+	atom.Img: {"src", "alt"},
+	atom.Td:  {"colspan", "rowspan"},
+	atom.Th:  {"colspan", "rowspan"},
+}
+
+var voidTags = map[atom.Atom]bool{atom.Br: true, atom.Hr: true, atom.Img: true}
+
+// cleanXHTML turns feed HTML into XHTML built from the allowed elements.
+// image maps the (absolute) URL of each image to the path to use in the
+// book, or "" to leave the image out; its alt text is kept instead.
+func cleanXHTML(content string, base *url.URL, image func(src string) string) string {
+	nodes, err := xhtml.ParseFragment(strings.NewReader(content), &xhtml.Node{Type: xhtml.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return "<p>" + xmlEscape(content) + "</p>"
+	}
+	var sb strings.Builder
+	for _, n := range nodes {
+		writeXHTML(&sb, n, base, image)
+	}
+	return sb.String()
+}
+
+func writeXHTML(sb *strings.Builder, n *xhtml.Node, base *url.URL, image func(string) string) {
+	switch n.Type {
+	case xhtml.TextNode:
+		sb.WriteString(xmlEscape(n.Data))
+		return
+	case xhtml.ElementNode:
+	default:
+		// Comments, doctypes
+		return
+	}
+
+	if droppedTags[n.DataAtom] {
+		return
+	}
+	if !keptTags[n.DataAtom] {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeXHTML(sb, c, base, image)
+		}
+		return
+	}
+
+	attrs := make(map[string]string)
+	for _, a := range n.Attr {
+		for _, keep := range keptAttrs[n.DataAtom] {
+			if a.Namespace == "" && a.Key == keep {
+				attrs[keep] = a.Val
+			}
+		}
+	}
+	switch n.DataAtom {
+	case atom.A:
+		href, ok := resolveURL(base, attrs["href"])
+		if !ok {
+			delete(attrs, "href")
+		} else {
+			attrs["href"] = href
+		}
+	case atom.Img:
+		src, ok := resolveURL(base, attrs["src"])
+		path := ""
+		if ok {
+			path = image(src)
+		}
+		if path == "" {
+			if alt := strings.TrimSpace(attrs["alt"]); alt != "" {
+				sb.WriteString("[" + xmlEscape(alt) + "]")
+			}
+			return
+		}
+		attrs["src"] = path
+		if _, ok := attrs["alt"]; !ok {
+			// Required in XHTML
+			attrs["alt"] = ""
+		}
+	}
+
+	sb.WriteString("<" + n.Data)
+	for _, key := range keptAttrs[n.DataAtom] {
+		if v, ok := attrs[key]; ok {
+			sb.WriteString(" " + key + `="` + xmlEscape(v) + `"`)
+		}
+	}
+	if voidTags[n.DataAtom] {
+		sb.WriteString("/>")
+		return
+	}
+	sb.WriteString(">")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeXHTML(sb, c, base, image)
+	}
+	sb.WriteString("</" + n.Data + ">")
+}
+
+// CleanHTML returns the content of an article (or its description) as HTML
+// built from the same allowed elements as EPUB chapters, with links and
+// images pointing to their absolute URLs. Published feeds use it so that
+// subscribers never get the scripts and styles of the original feed.
+func CleanHTML(a feed.Article) string {
+	content, base := chapterSource(Article{Article: a})
+	return cleanXHTML(content, base, func(src string) string {
+		if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+			return src
+		}
+		return ""
+	})
+}
+
+// resolveURL makes ref absolute and accepts only web and mail links
+func resolveURL(base *url.URL, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", false
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	switch u.Scheme {
+	case "http", "https", "mailto":
+		return u.String(), true
+	}
+	return "", false
+}
+
+// imageURLs lists the absolute URLs of the images in feed HTML
+func imageURLs(content string, base *url.URL) []string {
+	var urls []string
+	cleanXHTML(content, base, func(src string) string {
+		if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+			urls = append(urls, src)
+		}
+		return ""
+	})
+	return urls
+}
+
+// gemtextToHTML converts a text/gemini document to HTML, so it can go
+// through the same cleaning as feed HTML
+func gemtextToHTML(text string) string {
+	var sb strings.Builder
+	inList, inPre := false, false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "```") {
+			if inPre {
+				sb.WriteString("</pre>\n")
+			} else {
+				sb.WriteString("<pre>")
+			}
+			inPre = !inPre
+			continue
+		}
+		if inPre {
+			sb.WriteString(xmlEscape(line) + "\n")
+			continue
+		}
+		isItem := strings.HasPrefix(line, "* ")
+		if inList && !isItem {
+			sb.WriteString("</ul>\n")
+			inList = false
+		}
+		switch {
+		case isItem:
+			if !inList {
+				sb.WriteString("<ul>\n")
+				inList = true
+			}
+			sb.WriteString("<li>" + xmlEscape(strings.TrimSpace(line[2:])) + "</li>\n")
+		case strings.HasPrefix(line, "###"):
+			sb.WriteString("<h4>" + xmlEscape(strings.TrimSpace(line[3:])) + "</h4>\n")
+		case strings.HasPrefix(line, "##"):
+			sb.WriteString("<h3>" + xmlEscape(strings.TrimSpace(line[2:])) + "</h3>\n")
+		case strings.HasPrefix(line, "#"):
+			sb.WriteString("<h2>" + xmlEscape(strings.TrimSpace(line[1:])) + "</h2>\n")
+		case strings.HasPrefix(line, "=>"):
+			fields := strings.Fields(line[2:])
+			if len(fields) == 0 {
+				continue
+			}
+			label := fields[0]
+			if len(fields) > 1 {
+				label = strings.Join(fields[1:], " ")
+			}
+			sb.WriteString(`<p><a href="` + xmlEscape(fields[0]) + `">` + xmlEscape(label) + "</a></p>\n")
+		case strings.HasPrefix(line, ">"):
+			sb.WriteString("<blockquote>" + xmlEscape(strings.TrimSpace(line[1:])) + "</blockquote>\n")
+		case strings.TrimSpace(line) != "":
+			sb.WriteString("<p>" + xmlEscape(line) + "</p>\n")
+		}
+	}
+	if inList {
+		sb.WriteString("</ul>\n")
+	}
+	if inPre {
+		sb.WriteString("</pre>\n")
+	}
+	return sb.String()
+}
+
+// xmlEscape escapes text for XML, dropping the characters XML 1.0 doesn't allow
+func xmlEscape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' ||
+			(r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0xFFFD) || r >= 0x10000 {
+			return r
+		}
+		return -1
+	}, s)
+	return html.EscapeString(s)
+}
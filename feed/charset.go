@@ -0,0 +1,275 @@
+package feed
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// xmlDeclEncoding matches the encoding attribute of an XML declaration
+var xmlDeclEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?\bencoding\s*=\s*["'])([A-Za-z0-9._:-]+)(["'])`)
+
+// sniffCandidate is a legacy encoding tried when a feed declares nothing
+// usable and is not valid UTF-8, with the language it is normally used for
+type sniffCandidate struct {
+	label string
+	lang  string // "zh-hans", "zh-hant", "ja", "ko" or "" for Latin text
+}
+
+// sniffCandidates are tried in order of preference for ties
+var sniffCandidates = []sniffCandidate{
+	{"gb18030", "zh-hans"},
+	{"big5", "zh-hant"},
+	{"shift_jis", "ja"},
+	{"euc-jp", "ja"},
+	{"euc-kr", "ko"},
+	{"windows-1252", ""},
+}
+
+// frequentChars holds some of the most frequent characters of each language.
+// The EUC encodings share their byte ranges, so Korean or Japanese decoded as
+// GBK is still "valid"; what gives it away is that it isn't made of the
+// characters that language actually uses most.
+var frequentChars = map[string]string{
+	"zh-hans": "的一是不了在人有我他这个们中来上大为和国地到以说时要就出会可也你对生能而子那得于着下自之年过发后作里用道行所然家种事成方多经么去法学如都同现当没动面起看定天分还进好小部其些主样理心她本前开但因只从想实",
+	"zh-hant": "的一是不了在人有我他這個們中來上大為和國地到以說時要就出會可也你對生能而子那得於著下自之年過發後作裡用道行所然家種事成方多經麼去法學如都同現當沒動面起看定天分還進好小部其些主樣理心她本前開但因只從想實",
+	"ja":      "のにはをたがでてとしれさあいうかるすなこもりまっく日本人年大一国中出事会者時的上",
+	"ko":      "이다는의에고하을가지한로를서기사도으리대자정아어수일나시인니해전게국들라제보만주부상위장소것적과내문요있습입",
+}
+
+// decodeFeedBody transcodes a feed document to UTF-8. The encoding is taken
+// from, in order: the per-feed override, a byte order mark, the XML
+// declaration, the Content-Type header and finally byte sniffing. Declarations
+// of UTF-8 are only trusted if the body really is UTF-8, since misconfigured
+// servers commonly claim UTF-8 for GBK or Shift_JIS pages. Sniffing needs
+// enough non-ASCII text to tell the candidates apart; when it can't, the body
+// is read as UTF-8 (declared, or the default for XML) with invalid bytes
+// replaced, and the feed's encoding setting has to name the charset.
+//
+// The XML declaration of the result is rewritten to say UTF-8 so the parser
+// doesn't decode it a second time.
+func decodeFeedBody(body []byte, header http.Header, override string) ([]byte, error) {
+	if override != "" {
+		enc, _ := charset.Lookup(override)
+		if enc == nil {
+			return nil, fmt.Errorf("unknown encoding %q", override)
+		}
+		return transcode(body, enc)
+	}
+
+	switch {
+	case bytes.HasPrefix(body, []byte("\xef\xbb\xbf")):
+		return markUTF8(body[3:]), nil
+	case bytes.HasPrefix(body, []byte("\xfe\xff")), bytes.HasPrefix(body, []byte("\xff\xfe")):
+		return transcode(body, xunicode.UTF16(xunicode.BigEndian, xunicode.ExpectBOM))
+	}
+
+	for _, label := range []string{declaredXMLEncoding(body), headerCharset(header)} {
+		if label == "" {
+			continue
+		}
+		enc, name := charset.Lookup(label)
+		if enc == nil {
+			continue
+		}
+		if name == "utf-8" {
+			if utf8.Valid(body) {
+				return markUTF8(body), nil
+			}
+			continue
+		}
+		return transcode(body, enc)
+	}
+
+	if utf8.Valid(body) {
+		return markUTF8(body), nil
+	}
+	if enc, ok := sniffEncoding(body); ok {
+		return transcode(body, enc)
+	}
+	// Any other declared encoding was used above
+	return transcode(body, xunicode.UTF8)
+}
+
+// declaredXMLEncoding returns the encoding named in the XML declaration, if any
+func declaredXMLEncoding(body []byte) string {
+	head := body
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	if m := xmlDeclEncoding.FindSubmatch(head); m != nil {
+		return string(m[2])
+	}
+	return ""
+}
+
+// headerCharset returns the charset parameter of the Content-Type header
+func headerCharset(header http.Header) string {
+	if header == nil {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+// transcode decodes body from enc to UTF-8
+func transcode(body []byte, enc encoding.Encoding) ([]byte, error) {
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode feed: %w", err)
+	}
+	return markUTF8(bytes.TrimPrefix(decoded, []byte("\xef\xbb\xbf"))), nil
+}
+
+// markUTF8 rewrites the XML declaration's encoding to UTF-8
+func markUTF8(body []byte) []byte {
+	return xmlDeclEncoding.ReplaceAll(body, []byte("${1}UTF-8${3}"))
+}
+
+// minSniffChars is the number of non-ASCII characters sniffing needs. A few
+// bytes decode plausibly in several encodings: "短" in GBK is "똬" in EUC-KR.
+const minSniffChars = 8
+
+// sniffEncoding guesses the legacy encoding of body by decoding it with each
+// candidate and keeping the one that produces the most plausible text. It
+// reports false when the text is too short or no candidate stands out.
+func sniffEncoding(body []byte) (encoding.Encoding, bool) {
+	sample := body
+	if len(sample) > 64<<10 {
+		sample = sample[:64<<10]
+	}
+
+	var best encoding.Encoding
+	bestScore, secondScore, chars := 0, 0, 0
+	for _, c := range sniffCandidates {
+		enc, _ := charset.Lookup(c.label)
+		if enc == nil {
+			continue
+		}
+		decoded, err := enc.NewDecoder().Bytes(sample)
+		if err != nil {
+			continue
+		}
+		score := plausibility(string(decoded), c.lang)
+		switch {
+		case best == nil || score > bestScore:
+			if best != nil {
+				secondScore = bestScore
+			}
+			best, bestScore, chars = enc, score, nonASCII(decoded)
+		case score > secondScore:
+			secondScore = score
+		}
+	}
+	if best == nil || chars < minSniffChars || bestScore <= 0 || bestScore == secondScore {
+		return nil, false
+	}
+	return best, true
+}
+
+// nonASCII counts the characters of UTF-8 text that aren't ASCII
+func nonASCII(text []byte) int {
+	n := 0
+	for _, r := range string(text) {
+		if r >= utf8.RuneSelf {
+			n++
+		}
+	}
+	return n
+}
+
+// plausibility scores text decoded with an encoding for lang: characters that
+// language uses a lot count for it, replacement characters and rarely used
+// code points against it
+func plausibility(text, lang string) int {
+	frequent := frequentChars[lang]
+	common := commonChar(lang)
+	score := 0
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf:
+			// ASCII decodes the same in every candidate
+		case r == utf8.RuneError:
+			score -= 100
+		case frequent != "" && strings.ContainsRune(frequent, r):
+			score += 5
+		case r >= 0x3041 && r <= 0x30ff: // kana
+			if lang == "ja" {
+				score += 3
+			} else {
+				score -= 3
+			}
+		case r >= 0xac00 && r <= 0xd7a3: // hangul syllables
+			if lang == "ko" && common(r) {
+				score += 2
+			} else {
+				score -= 2
+			}
+		case r >= 0x4e00 && r <= 0x9fff: // CJK unified ideographs
+			if lang != "ko" && common(r) {
+				score++
+			} else {
+				score -= 2
+			}
+		case r >= 0x3000 && r <= 0x303f, r >= 0xff01 && r <= 0xff5e: // CJK and fullwidth punctuation
+			score++
+		case unicode.IsLetter(r) && r < 0x250:
+			// Latin-1 and Latin Extended letters, plausible for windows-1252
+			score++
+		default:
+			score -= 5
+		}
+	}
+	return score
+}
+
+// commonChar returns a test for the frequently used level of the character
+// set behind lang: GB2312 level 1, Big5 common characters, JIS X 0208 level 1
+// or the hangul of KS X 1001 (as opposed to the rarely used UHC additions)
+func commonChar(lang string) func(rune) bool {
+	var enc encoding.Encoding
+	var inRange func(code int) bool
+	switch lang {
+	case "zh-hans":
+		enc = simplifiedchinese.GBK
+		inRange = func(code int) bool { return code >= 0xb0a1 && code <= 0xd7f9 }
+	case "zh-hant":
+		enc = traditionalchinese.Big5
+		inRange = func(code int) bool { return code >= 0xa440 && code <= 0xc67e }
+	case "ja":
+		enc = japanese.ShiftJIS
+		inRange = func(code int) bool { return code >= 0x889f && code <= 0x9872 }
+	case "ko":
+		enc = korean.EUCKR
+		inRange = func(code int) bool { return code >= 0xb0a1 && code <= 0xc8fe && code&0xff >= 0xa1 }
+	default:
+		return func(rune) bool { return false }
+	}
+
+	encoder := enc.NewEncoder()
+	buf := make([]byte, 8)
+	return func(r rune) bool {
+		encoder.Reset()
+		n, _, err := encoder.Transform(buf, []byte(string(r)), true)
+		if err != nil || n != 2 {
+			return false
+		}
+		return inRange(int(buf[0])<<8 | int(buf[1]))
+	}
+}
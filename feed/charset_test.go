@@ -0,0 +1,110 @@
+package feed
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("encoding %q: %v", s, err)
+	}
+	return b
+}
+
+func rssDoc(decl string, title []byte) []byte {
+	doc := decl + "<rss version=\"2.0\"><channel><title>"
+	return append(append([]byte(doc), title...), "</title></channel></rss>"...)
+}
+
+func TestDecodeFeedBodySniffs(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  encoding.Encoding
+		text string
+	}{
+		{"gbk", simplifiedchinese.GBK, "我们的新闻：今天天气很好，大家都去公园了。"},
+		{"gb18030", simplifiedchinese.GB18030, "这是一个关于中文编码的测试，看看能不能正确识别。"},
+		{"big5", traditionalchinese.Big5, "我們的新聞：今天天氣很好，大家都去公園了。"},
+		{"shift_jis", japanese.ShiftJIS, "今日は天気がいいので、公園に行きました。"},
+		{"euc-jp", japanese.EUCJP, "日本語のニュースを読むのはとても楽しいです。"},
+		{"euc-kr", korean.EUCKR, "오늘은 날씨가 좋아서 공원에 갔습니다."},
+		{"windows-1252", charmap.Windows1252, "Crème brûlée à la française, déjà vu, señor Müller – naïveté"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, decl := range []string{"", `<?xml version="1.0"?>`, `<?xml version="1.0" encoding="utf-8"?>`} {
+				got, err := decodeFeedBody(rssDoc(decl, encode(t, tt.enc, tt.text)), nil, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(got), tt.text) {
+					t.Errorf("decl %q: got %q", decl, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeFeedBodyDeclared(t *testing.T) {
+	short := []byte{0xb6, 0xcc} // "短" in GBK, "똬" in EUC-KR
+	if got := string(encode(t, simplifiedchinese.GBK, "短")); got != string(short) {
+		t.Fatalf("GBK 短 = % x", got)
+	}
+	tests := []struct {
+		name     string
+		body     []byte
+		header   string
+		override string
+		want     string // in the result
+		reject   string // not in the result
+	}{
+		{"xml declaration", rssDoc(`<?xml version="1.0" encoding="GBK"?>`, short), "", "", "短", ""},
+		{"content-type", rssDoc("", short), "application/rss+xml; charset=gb2312", "", "短", ""},
+		{"declaration before header", rssDoc(`<?xml version="1.0" encoding="gbk"?>`, short), "text/xml; charset=euc-kr", "", "短", ""},
+		{"override before declaration", rssDoc(`<?xml version="1.0" encoding="euc-kr"?>`, short), "", "gbk", "短", ""},
+		{"declaration rewritten", rssDoc(`<?xml version="1.0" encoding="Shift_JIS"?>`, encode(t, japanese.ShiftJIS, "日本")), "", "", `encoding="UTF-8"`, "Shift_JIS"},
+		{"short without declaration", rssDoc("", short), "", "", "�", "똬"},
+		{"short under a false utf-8 declaration", rssDoc(`<?xml version="1.0" encoding="utf-8"?>`, short), "", "", "�", "똬"},
+		{"short latin without declaration", rssDoc("", []byte("caf\xe9")), "", "", "caf�", ""},
+		{"short with override", rssDoc("", short), "", "GB18030", "短", ""},
+		{"unknown declaration ignored", rssDoc(`<?xml version="1.0" encoding="x-nope"?>`, []byte("plain")), "", "", "plain", ""},
+		{"utf-8", rssDoc("", []byte("短い")), "text/xml; charset=utf-8", "", "短い", ""},
+		{"undeclared utf-8", rssDoc("", []byte("短い")), "", "", "短い", ""},
+		{"utf-8 bom", append([]byte("\xef\xbb\xbf"), rssDoc("", []byte("短"))...), "", "", "<rss", "\ufeff"},
+		{"utf-16 bom", encode(t, xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM), string(rssDoc("", []byte("短")))), "", "", "<title>短</title>", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Content-Type", tt.header)
+			}
+			got, err := decodeFeedBody(tt.body, header, tt.override)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(got), tt.want) {
+				t.Errorf("got %q, want it to contain %q", got, tt.want)
+			}
+			if tt.reject != "" && strings.Contains(string(got), tt.reject) {
+				t.Errorf("got %q, want no %q", got, tt.reject)
+			}
+		})
+	}
+
+	if _, err := decodeFeedBody(short, nil, "x-nope"); err == nil {
+		t.Error("unknown override accepted")
+	}
+}
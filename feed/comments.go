@@ -0,0 +1,341 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Comment is one comment in a discussion thread
+type Comment struct {
+	Author    string
+	Content   string // HTML
+	Published time.Time
+	Children  []Comment
+}
+
+// CommentThread is the cached discussion of an article
+type CommentThread struct {
+	Source   string // URL the comments were fetched from
+	Fetched  time.Time
+	Comments []Comment
+}
+
+// Count returns the total number of comments in the thread, including replies
+func (t CommentThread) Count() int {
+	var count func([]Comment) int
+	count = func(cs []Comment) int {
+		n := len(cs)
+		for _, c := range cs {
+			n += count(c.Children)
+		}
+		return n
+	}
+	return count(t.Comments)
+}
+
+var (
+	hnItemURL      = regexp.MustCompile(`^https?://news\.ycombinator\.com/item\?id=(\d+)`)
+	lobstersURL    = regexp.MustCompile(`^(https?://lobste\.rs/s/[a-z0-9]+)`)
+	redditComments = regexp.MustCompile(`^https?://(?:www\.|old\.)?reddit\.com/r/[^/]+/comments/[^/?#]+`)
+)
+
+// CachedComments returns the cached comment thread of an article, if any
+func (fm *FeedManager) CachedComments(article Article) (CommentThread, bool) {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	thread, ok := fm.comments[articleKey(article)]
+	return thread, ok
+}
+
+// FetchComments downloads the discussion of an article and caches it.
+// Hacker News, Lobsters and Reddit threads keep their nesting; other sites
+// fall back to the item's comments feed, which is flat.
+func (fm *FeedManager) FetchComments(article Article) (CommentThread, error) {
+	var thread CommentThread
+	var err error
+
+	candidates := []string{article.CommentsURL, article.Link}
+	fetched := false
+	for _, u := range candidates {
+		if u == "" {
+			continue
+		}
+		switch {
+		case hnItemURL.MatchString(u):
+			thread.Source = u
+			thread.Comments, err = fetchHNComments(hnItemURL.FindStringSubmatch(u)[1])
+			fetched = true
+		case lobstersURL.MatchString(u):
+			thread.Source = u
+			thread.Comments, err = fetchLobstersComments(lobstersURL.FindStringSubmatch(u)[1])
+			fetched = true
+		case redditComments.MatchString(u):
+			thread.Source = u
+			thread.Comments, err = fetchRedditComments(redditComments.FindString(u))
+			fetched = true
+		}
+		if fetched {
+			break
+		}
+	}
+
+	if !fetched {
+		if article.CommentsFeed == "" {
+			return thread, fmt.Errorf("no comments available for this article")
+		}
+		thread.Source = article.CommentsFeed
+		thread.Comments, err = fm.fetchCommentFeed(article.CommentsFeed)
+	}
+	if err != nil {
+		return thread, err
+	}
+	thread.Fetched = time.Now()
+
+	fm.mu.Lock()
+	fm.comments[articleKey(article)] = thread
+	fm.mu.Unlock()
+
+	if err := fm.saveComments(); err != nil {
+		return thread, err
+	}
+	return thread, nil
+}
+
+// articleKey identifies an article in side caches
+func articleKey(a Article) string {
+	if a.Link != "" {
+		return a.Link
+	}
+	return a.GUID
+}
+
+// getJSON fetches a URL and decodes its JSON body into v
+func getJSON(u string, v interface{}) error {
+	body, _, err := fetchHTTP(u)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// fetchHNComments reads a Hacker News thread from the Algolia items API,
+// which returns the whole tree in one request
+func fetchHNComments(id string) ([]Comment, error) {
+	type hnItem struct {
+		Author    string   `json:"author"`
+		Text      string   `json:"text"`
+		CreatedAt int64    `json:"created_at_i"`
+		Children  []hnItem `json:"children"`
+	}
+	var root hnItem
+	if err := getJSON("https://hn.algolia.com/api/v1/items/"+id, &root); err != nil {
+		return nil, err
+	}
+
+	var convert func([]hnItem) []Comment
+	convert = func(items []hnItem) []Comment {
+		var comments []Comment
+		for _, it := range items {
+			if it.Author == "" && it.Text == "" {
+				// Deleted comment; keep its replies
+				comments = append(comments, convert(it.Children)...)
+				continue
+			}
+			comments = append(comments, Comment{
+				Author:    it.Author,
+				Content:   it.Text,
+				Published: time.Unix(it.CreatedAt, 0),
+				Children:  convert(it.Children),
+			})
+		}
+		return comments
+	}
+	return convert(root.Children), nil
+}
+
+// fetchLobstersComments reads a Lobsters story, whose comments come as a flat
+// list with depths that we turn back into a tree
+func fetchLobstersComments(storyURL string) ([]Comment, error) {
+	var story struct {
+		Comments []struct {
+			Comment        string          `json:"comment"`
+			Depth          int             `json:"depth"`
+			CreatedAt      time.Time       `json:"created_at"`
+			CommentingUser json.RawMessage `json:"commenting_user"`
+		} `json:"comments"`
+	}
+	if err := getJSON(storyURL+".json", &story); err != nil {
+		return nil, err
+	}
+
+	var roots []Comment
+	// stack[i] points at the most recent comment at depth i
+	var stack []*[]Comment
+	for _, c := range story.Comments {
+		comment := Comment{
+			Author:    lobstersUser(c.CommentingUser),
+			Content:   c.Comment,
+			Published: c.CreatedAt,
+		}
+		depth := c.Depth
+		if depth < 0 {
+			depth = 0
+		}
+		if depth > len(stack) {
+			depth = len(stack)
+		}
+		stack = stack[:depth]
+
+		parent := &roots
+		if depth > 0 {
+			parent = stack[depth-1]
+		}
+		*parent = append(*parent, comment)
+		added := &(*parent)[len(*parent)-1]
+		stack = append(stack, &added.Children)
+	}
+	return roots, nil
+}
+
+// lobstersUser handles both the old (object) and new (string) commenting_user formats
+func lobstersUser(raw json.RawMessage) string {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return name
+	}
+	var user struct {
+		Username string `json:"username"`
+	}
+	_ = json.Unmarshal(raw, &user)
+	return user.Username
+}
+
+// fetchRedditComments reads a Reddit thread through its public .json endpoint
+func fetchRedditComments(threadURL string) ([]Comment, error) {
+	type listing struct {
+		Data struct {
+			Children []struct {
+				Kind string          `json:"kind"`
+				Data json.RawMessage `json:"data"`
+			} `json:"children"`
+		} `json:"data"`
+	}
+	type redditComment struct {
+		Author   string          `json:"author"`
+		BodyHTML string          `json:"body_html"`
+		Created  float64         `json:"created_utc"`
+		Replies  json.RawMessage `json:"replies"`
+	}
+
+	var pages []listing
+	if err := getJSON(strings.TrimSuffix(threadURL, "/")+"/.json?raw_json=1", &pages); err != nil {
+		return nil, err
+	}
+	if len(pages) < 2 {
+		return nil, fmt.Errorf("unexpected reddit response")
+	}
+
+	var convert func(listing) []Comment
+	convert = func(l listing) []Comment {
+		var comments []Comment
+		for _, child := range l.Data.Children {
+			if child.Kind != "t1" {
+				// "more" stubs would need extra requests; skip them
+				continue
+			}
+			var rc redditComment
+			if err := json.Unmarshal(child.Data, &rc); err != nil {
+				continue
+			}
+			comment := Comment{
+				Author:    rc.Author,
+				Content:   rc.BodyHTML, // raw_json=1 sends it unescaped
+				Published: time.Unix(int64(rc.Created), 0),
+			}
+			// replies is "" when there are none, otherwise another listing
+			var replies listing
+			if len(rc.Replies) > 0 && rc.Replies[0] == '{' && json.Unmarshal(rc.Replies, &replies) == nil {
+				comment.Children = convert(replies)
+			}
+			comments = append(comments, comment)
+		}
+		return comments
+	}
+	return convert(pages[1]), nil
+}
+
+// fetchCommentFeed reads a per-item comments feed (wfw:commentRss); it has no nesting
+func (fm *FeedManager) fetchCommentFeed(feedURL string) ([]Comment, error) {
+	if _, err := url.Parse(feedURL); err != nil {
+		return nil, err
+	}
+	body, _, err := fetchHTTP(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := fm.parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []Comment
+	for _, item := range parsed.Items {
+		content := item.Content
+		if content == "" {
+			content = item.Description
+		}
+		author := strings.Join(itemAuthors(item), ", ")
+		if author == "" {
+			author = item.Title
+		}
+		published := time.Time{}
+		if item.PublishedParsed != nil {
+			published = *item.PublishedParsed
+		}
+		comments = append(comments, Comment{Author: author, Content: content, Published: published})
+	}
+	return comments, nil
+}
+
+// loadComments loads cached comment threads from the comments cache file
+func (fm *FeedManager) loadComments() error {
+	comments := make(map[string]CommentThread)
+	warning, err := fm.lock.readJSON(fm.commentsPath, &comments)
+	fm.warn(warning)
+	if err != nil {
+		return err
+	}
+
+	fm.mu.Lock()
+	fm.comments = comments
+	fm.mu.Unlock()
+	return nil
+}
+
+// saveComments saves comment threads to the comments cache file, keeping
+// threads another gorss instance fetched more recently
+func (fm *FeedManager) saveComments() error {
+	return fm.lock.withLock(true, func() error {
+		onDisk := make(map[string]CommentThread)
+		_, _ = readJSONFile(fm.commentsPath, &onDisk)
+
+		fm.mu.Lock()
+		for key, theirs := range onDisk {
+			if ours, ok := fm.comments[key]; !ok || theirs.Fetched.After(ours.Fetched) {
+				fm.comments[key] = theirs
+			}
+		}
+		data, err := json.MarshalIndent(fm.comments, "", "  ")
+		fm.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		return writeFileAtomic(fm.commentsPath, data, 0644)
+	})
+}
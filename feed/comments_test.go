@@ -0,0 +1,43 @@
+package feed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchRedditCommentsDecodesOnce(t *testing.T) {
+	// With raw_json=1 Reddit sends body_html unescaped; text that looks like
+	// a tag stays escaped inside it
+	const thread = `[{"data":{"children":[]}},{"data":{"children":[
+		{"kind":"t1","data":{"author":"a","body_html":"<div class=\"md\"><p>wrap it in &lt;div&gt; &amp; done</p></div>","created_utc":1700000000,"replies":{"data":{"children":[
+			{"kind":"t1","data":{"author":"b","body_html":"<p>thanks</p>","created_utc":1700000100,"replies":""}}
+		]}}}},
+		{"kind":"more","data":{}}
+	]}}]`
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(thread))
+	}))
+	defer srv.Close()
+
+	comments, err := fetchRedditComments(srv.URL + "/r/golang/comments/abc/title/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "raw_json=1" {
+		t.Errorf("query = %q, want raw_json=1", query)
+	}
+	if len(comments) != 1 {
+		t.Fatalf("got %d comments, want 1", len(comments))
+	}
+	want := `<div class="md"><p>wrap it in &lt;div&gt; &amp; done</p></div>`
+	if comments[0].Content != want {
+		t.Errorf("content = %q, want %q", comments[0].Content, want)
+	}
+	if len(comments[0].Children) != 1 || comments[0].Children[0].Author != "b" {
+		t.Errorf("replies = %+v", comments[0].Children)
+	}
+}
@@ -1,10 +1,14 @@
 package feed
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,70 +18,144 @@ import (
 
 // Article represents a single RSS article
 type Article struct {
+	ID          string // Short stable identifier, see articleID
 	Title       string
 	Description string
 	Content     string
 	Link        string
 	Published   time.Time
 	FeedName    string
+	GUID        string // Item GUID, or Message-ID for mail sources
+	Source      string // Name of the configured feed this article came from
+	ContentType string // MIME type of Content; empty means HTML
+	Enclosures  []Enclosure
+
+	// Extra item metadata; absent from older caches, where it decodes as empty
+	Authors      []string
+	Categories   []string
+	Updated      time.Time // zero if the feed gives no update date
+	Image        string    // item image or thumbnail URL
+	CommentsURL  string    // discussion page (RSS <comments>)
+	CommentsFeed string    // comments feed (wfw:commentRss)
+	CommentCount int       // slash:comments
+}
+
+// SourceName returns the configured feed the article came from
+func (a Article) SourceName() string {
+	if a.Source != "" {
+		return a.Source
+	}
+	return a.FeedName
+}
+
+// Enclosure is a media file attached to an article, e.g. a podcast episode
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64 // Size in bytes as advertised by the feed, 0 if unknown
 }
 
 // FeedSummary contains the LLM-generated summary for a feed
 type FeedSummary struct {
-	FeedName    string
-	Summary     string
-	Generated   time.Time
+	FeedName     string
+	Summary      string
+	Generated    time.Time
 	ArticleCount int
 }
 
 // FeedManager handles fetching and storing feed data
 type FeedManager struct {
-	Feeds     []config.Feed
-	Articles  []Article
-	Summaries map[string]FeedSummary // Key is feed name
-	parser    *gofeed.Parser
-	mu        sync.RWMutex
-	cachePath string
-	summaryPath string
+	feeds        []config.Feed // guarded by mu; see Feeds and SetFeeds
+	Articles     []Article
+	Summaries    map[string]FeedSummary // Key is feed name
+	parser       *gofeed.Parser
+	mu           sync.RWMutex
+	cachePath    string
+	summaryPath  string
+	geminiHosts  *knownHosts        // TOFU certificate store for gemini:// feeds
+	hubs         map[string]HubLink // WebSub hubs discovered while fetching, keyed by feed name
+	fullText     map[string]string  // Extracted full article content, keyed by article link
+	fullTextPath string
+	comments     map[string]CommentThread // Fetched discussions, keyed by article link
+	commentsPath string
+	lock         cacheLock    // advisory lock shared with other gorss processes
+	links        linkRewriter // resolves links and strips tracking parameters
+
+	warnMu   sync.Mutex
+	warnings []string
 }
 
-// NewFeedManager creates a new feed manager and loads cached articles if available
-func NewFeedManager(feeds []config.Feed) *FeedManager {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		homeDir = "."
-	}
-	cachePath := filepath.Join(homeDir, ".cache", "gorss", "feed_cache.json")
-	summaryPath := filepath.Join(homeDir, ".cache", "gorss", "summaries.json")
+// NewFeedManager creates a new feed manager that keeps its caches in cacheDir
+// (see config.Paths) and loads cached articles if available
+func NewFeedManager(feeds []config.Feed, cacheDir string) *FeedManager {
+	cachePath := filepath.Join(cacheDir, "feed_cache.json")
+	summaryPath := filepath.Join(cacheDir, "summaries.json")
+	knownHostsPath := filepath.Join(cacheDir, "gemini_known_hosts")
+	fullTextPath := filepath.Join(cacheDir, "fulltext.json")
+	commentsPath := filepath.Join(cacheDir, "comments.json")
 
 	fm := &FeedManager{
-		Feeds:       feeds,
-		Summaries:   make(map[string]FeedSummary),
-		parser:      gofeed.NewParser(),
-		cachePath:   cachePath,
-		summaryPath: summaryPath,
+		feeds:        feeds,
+		Summaries:    make(map[string]FeedSummary),
+		parser:       newParser(),
+		cachePath:    cachePath,
+		summaryPath:  summaryPath,
+		geminiHosts:  newKnownHosts(knownHostsPath),
+		hubs:         make(map[string]HubLink),
+		fullText:     make(map[string]string),
+		fullTextPath: fullTextPath,
+		comments:     make(map[string]CommentThread),
+		commentsPath: commentsPath,
+		lock:         cacheLock{path: filepath.Join(cacheDir, ".lock")},
+		links:        linkRewriter{rules: parseStripRules(DefaultStripParams)},
 	}
 
 	// Load feed cache
 	if err := fm.loadCache(); err != nil {
-		fmt.Printf("Warning: failed to load feed cache: %v\n", err)
+		fm.warn(fmt.Sprintf("failed to load feed cache: %v", err))
 	}
 
 	// Load summaries cache
 	if err := fm.loadSummaries(); err != nil {
-		fmt.Printf("Warning: failed to load summaries cache: %v\n", err)
+		fm.warn(fmt.Sprintf("failed to load summaries cache: %v", err))
+	}
+
+	// Load extracted full-text cache
+	if err := fm.loadFullText(); err != nil {
+		fm.warn(fmt.Sprintf("failed to load full-text cache: %v", err))
+	}
+
+	// Load comment threads cache
+	if err := fm.loadComments(); err != nil {
+		fm.warn(fmt.Sprintf("failed to load comments cache: %v", err))
 	}
 
 	return fm
 }
 
+// Feeds returns a copy of the feeds to fetch
+func (fm *FeedManager) Feeds() []config.Feed {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	return append([]config.Feed(nil), fm.feeds...)
+}
+
+// SetFeeds replaces the feeds to fetch, e.g. after the config is reloaded; a
+// refresh already running keeps the ones it started with
+func (fm *FeedManager) SetFeeds(feeds []config.Feed) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	fm.feeds = append([]config.Feed(nil), feeds...)
+}
+
 // RefreshFeeds fetches the latest articles from all configured feeds
 func (fm *FeedManager) RefreshFeeds() error {
+	feeds := fm.Feeds()
 	var wg sync.WaitGroup
-	articleCh := make(chan []Article, len(fm.Feeds))
-	errorCh := make(chan error, len(fm.Feeds))
+	articleCh := make(chan []Article, len(feeds))
+	errorCh := make(chan error, len(feeds))
 
-	for _, feed := range fm.Feeds {
+	for _, feed := range feeds {
 		// Skip the "All" feed since it's just a category, not a real feed
 		if feed.URL == "" {
 			continue
@@ -121,7 +199,7 @@ func (fm *FeedManager) RefreshFeeds() error {
 	fm.mu.Unlock()
 
 	if err := fm.saveCache(); err != nil {
-		fmt.Printf("Warning: failed to save feed cache: %v\n", err)
+		fm.warn(fmt.Sprintf("failed to save feed cache: %v", err))
 	}
 
 	return nil
@@ -138,13 +216,172 @@ func (fm *FeedManager) GetArticles() []Article {
 	return result
 }
 
-// fetchFeed fetches a single feed and converts it to articles
+// Article looks up an article by its ID
+func (fm *FeedManager) Article(id string) (Article, bool) {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	for _, a := range fm.Articles {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Article{}, false
+}
+
+// fetchFeed fetches a single feed and converts it to articles, replacing
+// truncated content with extracted full text where available
 func (fm *FeedManager) fetchFeed(feed config.Feed) ([]Article, error) {
-	parsed, err := fm.parser.ParseURL(feed.URL)
+	articles, err := fm.fetchSource(feed)
+	if err != nil {
+		return nil, err
+	}
+	articles = fm.rewriteLinks(feed.URL, articles)
+	return assignIDs(fm.applyFullText(feed, articles)), nil
+}
+
+// fetchSource fetches the articles of a feed from wherever it lives
+func (fm *FeedManager) fetchSource(feed config.Feed) ([]Article, error) {
+	if isMaildirURL(feed.URL) {
+		return fetchMaildir(feed)
+	}
+	if isGeminiURL(feed.URL) {
+		return fm.fetchGemini(feed)
+	}
+
+	body, header, err := fetchHTTP(feed.URL)
+	if err != nil {
+		return nil, err
+	}
+	body, err = decodeFeedBody(body, header, feed.Encoding)
 	if err != nil {
 		return nil, err
 	}
 
+	parsed, err := fm.parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if hub, self := discoverHub(body, header); hub != "" {
+		if self == "" {
+			self = feed.URL
+		}
+		fm.mu.Lock()
+		fm.hubs[feed.Name] = HubLink{Hub: hub, Self: self}
+		fm.mu.Unlock()
+	}
+
+	return articlesFromFeed(parsed, feed), nil
+}
+
+// Hub returns the WebSub hub advertised by a feed during the last fetch
+func (fm *FeedManager) Hub(feedName string) (HubLink, bool) {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	hub, ok := fm.hubs[feedName]
+	return hub, ok
+}
+
+// RefreshSome fetches only the given feeds and merges their articles into the
+// existing set instead of replacing it, so other feeds keep their articles
+func (fm *FeedManager) RefreshSome(feeds []config.Feed) error {
+	var firstErr error
+	for _, feed := range feeds {
+		if feed.URL == "" {
+			continue
+		}
+		if _, err := fm.RefreshFeed(feed); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// RefreshFeed fetches one feed and merges its articles into the existing set.
+// It returns the number of new articles.
+func (fm *FeedManager) RefreshFeed(feed config.Feed) (int, error) {
+	articles, err := fm.fetchFeed(feed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch %s: %w", feed.Name, err)
+	}
+	return fm.MergeArticles(feed.Name, articles), nil
+}
+
+// MergePushed parses a feed document delivered by a WebSub hub and merges its
+// entries into the articles of the given feed. It returns the number of new articles.
+func (fm *FeedManager) MergePushed(feed config.Feed, body []byte) (int, error) {
+	parsed, err := fm.parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	articles := fm.rewriteLinks(feed.URL, articlesFromFeed(parsed, feed))
+	articles = assignIDs(fm.applyFullText(feed, articles))
+	return fm.MergeArticles(feed.Name, articles), nil
+}
+
+// MergeArticles adds articles from one source to the article set, replacing
+// any existing article with the same GUID (or link), and saves the cache.
+// It returns the number of articles that were not already present.
+func (fm *FeedManager) MergeArticles(source string, articles []Article) int {
+	key := func(a Article) string {
+		if a.GUID != "" {
+			return a.GUID
+		}
+		return a.Link
+	}
+
+	fm.mu.Lock()
+	index := make(map[string]int)
+	for i, a := range fm.Articles {
+		if a.SourceName() == source {
+			index[key(a)] = i
+		}
+	}
+	added := 0
+	for _, a := range assignIDs(articles) {
+		if i, ok := index[key(a)]; ok {
+			fm.Articles[i] = a
+			continue
+		}
+		index[key(a)] = len(fm.Articles)
+		fm.Articles = append(fm.Articles, a)
+		added++
+	}
+	fm.mu.Unlock()
+
+	if err := fm.saveCache(); err != nil {
+		fm.warn(fmt.Sprintf("failed to save feed cache: %v", err))
+	}
+	return added
+}
+
+// ReplaceArticles replaces the whole article set, as a full refresh does, and
+// saves the cache. It returns the number of articles that were not present before.
+func (fm *FeedManager) ReplaceArticles(articles []Article) int {
+	articles = assignIDs(articles)
+
+	fm.mu.Lock()
+	known := make(map[string]bool, len(fm.Articles))
+	for _, a := range fm.Articles {
+		known[a.ID] = true
+	}
+	added := 0
+	for _, a := range articles {
+		if !known[a.ID] {
+			added++
+		}
+	}
+	fm.Articles = articles
+	fm.mu.Unlock()
+
+	if err := fm.saveCache(); err != nil {
+		fm.warn(fmt.Sprintf("failed to save feed cache: %v", err))
+	}
+	return added
+}
+
+// articlesFromFeed converts the items of a parsed feed to articles
+func articlesFromFeed(parsed *gofeed.Feed, feed config.Feed) []Article {
 	var articles []Article
 	for _, item := range parsed.Items {
 		pubDate := time.Now()
@@ -157,6 +394,21 @@ func (fm *FeedManager) fetchFeed(feed config.Feed) ([]Article, error) {
 			content = item.Description
 		}
 
+		var enclosures []Enclosure
+		for _, e := range item.Enclosures {
+			if e.URL == "" {
+				continue
+			}
+			length, _ := strconv.ParseInt(strings.TrimSpace(e.Length), 10, 64)
+			enclosures = append(enclosures, Enclosure{URL: e.URL, Type: e.Type, Length: length})
+		}
+
+		var updated time.Time
+		if item.UpdatedParsed != nil {
+			updated = *item.UpdatedParsed
+		}
+		commentsURL, commentsFeed, commentCount := itemComments(item)
+
 		articles = append(articles, Article{
 			Title:       item.Title,
 			Description: item.Description,
@@ -164,47 +416,77 @@ func (fm *FeedManager) fetchFeed(feed config.Feed) ([]Article, error) {
 			Link:        item.Link,
 			Published:   pubDate,
 			FeedName:    feed.Name,
+			GUID:        item.GUID,
+			Source:      feed.Name,
+			Enclosures:  enclosures,
+
+			Authors:      itemAuthors(item),
+			Categories:   item.Categories,
+			Updated:      updated,
+			Image:        itemImage(item),
+			CommentsURL:  commentsURL,
+			CommentsFeed: commentsFeed,
+			CommentCount: commentCount,
 		})
 	}
 
-	return articles, nil
+	return articles
 }
 
-// loadCache loads cached articles from the cache file
-func (fm *FeedManager) loadCache() error {
-	data, err := os.ReadFile(fm.cachePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
+// articleID derives a short identifier from the feed an article belongs to
+// and its GUID (or link, or title), so it stays the same across fetches
+func articleID(a Article) string {
+	source := a.Source
+	if source == "" {
+		source = a.FeedName
+	}
+	key := a.GUID
+	if key == "" {
+		key = a.Link
+	}
+	if key == "" {
+		key = a.Title
+	}
+	sum := sha1.Sum([]byte(source + "\x00" + key))
+	return hex.EncodeToString(sum[:])[:10]
+}
+
+// assignIDs sets the ID of articles that don't have one yet
+func assignIDs(articles []Article) []Article {
+	for i := range articles {
+		if articles[i].ID == "" {
+			articles[i].ID = articleID(articles[i])
 		}
-		return err
 	}
+	return articles
+}
+
+// loadCache loads cached articles from the cache file
+func (fm *FeedManager) loadCache() error {
 	var articles []Article
-	if err := json.Unmarshal(data, &articles); err != nil {
+	warning, err := fm.lock.readJSON(fm.cachePath, &articles)
+	fm.warn(warning)
+	if err != nil {
 		return err
 	}
 	fm.mu.Lock()
-	fm.Articles = articles
+	// Older caches have no IDs
+	fm.Articles = assignIDs(articles)
 	fm.mu.Unlock()
 	return nil
 }
 
 // saveCache saves current articles to the cache file
 func (fm *FeedManager) saveCache() error {
-	dir := filepath.Dir(fm.cachePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
 	fm.mu.RLock()
 	data, err := json.MarshalIndent(fm.Articles, "", "  ")
 	fm.mu.RUnlock()
 	if err != nil {
 		return err
 	}
-	if err := os.WriteFile(fm.cachePath, data, 0644); err != nil {
-		return err
-	}
-	return nil
+	return fm.lock.withLock(true, func() error {
+		return writeFileAtomic(fm.cachePath, data, 0644)
+	})
 }
 
 // GetSummary returns the summary for a specific feed
@@ -218,31 +500,26 @@ func (fm *FeedManager) GetSummary(feedName string) (FeedSummary, bool) {
 // SetSummary sets or updates the summary for a feed
 func (fm *FeedManager) SetSummary(feedName string, summary string, articleCount int) {
 	fm.mu.Lock()
-	defer fm.mu.Unlock()
-
 	fm.Summaries[feedName] = FeedSummary{
 		FeedName:     feedName,
 		Summary:      summary,
 		Generated:    time.Now(),
 		ArticleCount: articleCount,
 	}
+	fm.mu.Unlock()
 
 	// Save summaries to disk
-	_ = fm.saveSummaries()
+	if err := fm.saveSummaries(); err != nil {
+		fm.warn(fmt.Sprintf("failed to save summaries: %v", err))
+	}
 }
 
 // loadSummaries loads cached summaries from the summary file
 func (fm *FeedManager) loadSummaries() error {
-	data, err := os.ReadFile(fm.summaryPath)
+	summaries := make(map[string]FeedSummary)
+	warning, err := fm.lock.readJSON(fm.summaryPath, &summaries)
+	fm.warn(warning)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
-
-	var summaries map[string]FeedSummary
-	if err := json.Unmarshal(data, &summaries); err != nil {
 		return err
 	}
 
@@ -252,24 +529,45 @@ func (fm *FeedManager) loadSummaries() error {
 	return nil
 }
 
-// saveSummaries saves current summaries to the summary file
+// saveSummaries saves current summaries to the summary file, keeping newer
+// summaries that another gorss instance wrote in the meantime
 func (fm *FeedManager) saveSummaries() error {
-	dir := filepath.Dir(fm.summaryPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-
-	fm.mu.RLock()
-	data, err := json.MarshalIndent(fm.Summaries, "", "  ")
-	fm.mu.RUnlock()
+	return fm.lock.withLock(true, func() error {
+		onDisk := make(map[string]FeedSummary)
+		_, _ = readJSONFile(fm.summaryPath, &onDisk)
+
+		fm.mu.Lock()
+		for name, theirs := range onDisk {
+			if ours, ok := fm.Summaries[name]; !ok || theirs.Generated.After(ours.Generated) {
+				fm.Summaries[name] = theirs
+			}
+		}
+		data, err := json.MarshalIndent(fm.Summaries, "", "  ")
+		fm.mu.Unlock()
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		return err
-	}
+		return writeFileAtomic(fm.summaryPath, data, 0644)
+	})
+}
 
-	if err := os.WriteFile(fm.summaryPath, data, 0644); err != nil {
-		return err
+// warn records a non-fatal problem for the UI to show; empty messages are ignored
+func (fm *FeedManager) warn(message string) {
+	if message == "" {
+		return
 	}
+	fm.warnMu.Lock()
+	fm.warnings = append(fm.warnings, message)
+	fm.warnMu.Unlock()
+}
 
-	return nil
+// TakeWarnings returns and clears the warnings collected since the last call.
+// Cache problems are reported here instead of being printed over the TUI.
+func (fm *FeedManager) TakeWarnings() []string {
+	fm.warnMu.Lock()
+	defer fm.warnMu.Unlock()
+	warnings := fm.warnings
+	fm.warnings = nil
+	return warnings
 }
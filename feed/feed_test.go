@@ -0,0 +1,39 @@
+package feed
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JohanLi233/gorss/config"
+)
+
+// A config reload replaces the feeds while a refresh may be running; run
+// with -race
+func TestSetFeedsDuringRefresh(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<rss version="2.0"><channel><title>t</title><item><title>%s</title><guid>%s</guid></item></channel></rss>`, r.URL.Path, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	feeds := func(n int) []config.Feed {
+		var out []config.Feed
+		for i := 0; i < n; i++ {
+			out = append(out, config.Feed{Name: fmt.Sprint("feed", i), URL: fmt.Sprintf("%s/%d", srv.URL, i)})
+		}
+		return out
+	}
+	fm := NewFeedManager(feeds(3), t.TempDir())
+	done := make(chan error)
+	go func() { done <- fm.RefreshFeeds() }()
+	for i := 0; i < 20; i++ {
+		fm.SetFeeds(feeds(i % 5))
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := len(fm.Feeds()); got != 4 {
+		t.Errorf("%d feeds after the last SetFeeds, want 4", got)
+	}
+}
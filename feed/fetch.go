@@ -0,0 +1,70 @@
+package feed
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	userAgent       = "gorss/1.0 (+https://github.com/JohanLi233/gorss)"
+	fetchTimeout    = 30 * time.Second
+	maxFeedBodySize = 32 << 20 // refuse to buffer absurdly large feeds
+)
+
+// httpClient is shared by all HTTP feed fetches
+var httpClient = &http.Client{Timeout: fetchTimeout}
+
+// fetchHTTP downloads a feed document and returns the raw body together with
+// the response headers, which carry hub links and charset information
+func fetchHTTP(url string) ([]byte, http.Header, error) {
+	return fetchLimited(url, maxFeedBodySize, false)
+}
+
+// FetchResource downloads something an article refers to, such as an image,
+// and returns it with its Content-Type. Bodies larger than maxSize are an error.
+func FetchResource(url string, maxSize int64) ([]byte, string, error) {
+	body, header, err := fetchLimited(url, maxSize, true)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, header.Get("Content-Type"), nil
+}
+
+// fetchLimited GETs url and reads at most limit bytes of the body. With
+// strict set a longer body is an error; otherwise it is cut off.
+func fetchLimited(url string, limit int64, strict bool) ([]byte, http.Header, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, gofeed.HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if int64(len(body)) > limit {
+		if strict {
+			return nil, nil, fmt.Errorf("response larger than %d bytes", limit)
+		}
+		body = body[:limit]
+	}
+	return body, resp.Header, nil
+}
@@ -0,0 +1,164 @@
+package feed
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter selects articles using a small query language. Terms are separated
+// by spaces and must all match (case-insensitive substring match):
+//
+//	author:alice      an author name
+//	tag:go            a category/tag (category: is an alias)
+//	feed:hn           the feed name
+//	is:starred        starred articles (is:unread for unread ones)
+//	kubernetes        title, description or content
+//
+// Values containing spaces can be quoted: author:"Jane Doe". The is: terms
+// depend on the reading state and are only checked by MatchState.
+type Filter struct {
+	Text       []string
+	Authors    []string
+	Categories []string
+	Feeds      []string
+	Starred    bool
+	Unread     bool
+}
+
+// ParseFilter parses a filter query
+func ParseFilter(query string) Filter {
+	var f Filter
+	for _, term := range splitQuery(query) {
+		key, value, ok := strings.Cut(term, ":")
+		if !ok || value == "" {
+			f.Text = append(f.Text, strings.ToLower(term))
+			continue
+		}
+		value = strings.ToLower(value)
+		switch strings.ToLower(key) {
+		case "author", "by":
+			f.Authors = append(f.Authors, value)
+		case "tag", "category", "cat":
+			f.Categories = append(f.Categories, value)
+		case "feed", "source":
+			f.Feeds = append(f.Feeds, value)
+		case "is":
+			switch value {
+			case "starred":
+				f.Starred = true
+			case "unread":
+				f.Unread = true
+			default:
+				f.Text = append(f.Text, strings.ToLower(term))
+			}
+		default:
+			// Not a known key (e.g. a URL); treat the whole term as text
+			f.Text = append(f.Text, strings.ToLower(term))
+		}
+	}
+	return f
+}
+
+// splitQuery splits on whitespace, keeping double-quoted sections together
+func splitQuery(query string) []string {
+	var terms []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if current.Len() > 0 {
+				terms = append(terms, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		terms = append(terms, current.String())
+	}
+	return terms
+}
+
+// Empty reports whether the filter matches everything
+func (f Filter) Empty() bool {
+	return len(f.Text) == 0 && len(f.Authors) == 0 && len(f.Categories) == 0 && len(f.Feeds) == 0 &&
+		!f.Starred && !f.Unread
+}
+
+// Match reports whether an article satisfies every term of the filter
+func (f Filter) Match(a Article) bool {
+	for _, want := range f.Authors {
+		if !containsAny(a.Authors, want) {
+			return false
+		}
+	}
+	for _, want := range f.Categories {
+		if !containsAny(a.Categories, want) {
+			return false
+		}
+	}
+	for _, want := range f.Feeds {
+		if !containsAny([]string{a.FeedName, a.Source}, want) {
+			return false
+		}
+	}
+	if len(f.Text) > 0 {
+		haystack := strings.ToLower(a.Title + "\n" + a.Description + "\n" + a.Content)
+		for _, want := range f.Text {
+			if !strings.Contains(haystack, want) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MatchState is Match including the is: terms, checked against the reading state
+func (f Filter) MatchState(a Article, s *State) bool {
+	if f.Starred && !s.IsStarred(a.ID) {
+		return false
+	}
+	if f.Unread && s.IsRead(a.ID) {
+		return false
+	}
+	return f.Match(a)
+}
+
+// containsAny reports whether any value contains the lower-cased needle
+func containsAny(values []string, needle string) bool {
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSince turns a "since" value into a point in time. Besides Go
+// durations it accepts days ("7d") and weeks ("2w"), and dates.
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if n := len(s); n > 1 && (s[n-1] == 'd' || s[n-1] == 'w') {
+		if count, err := strconv.Atoi(s[:n-1]); err == nil && count >= 0 {
+			days := count
+			if s[n-1] == 'w' {
+				days *= 7
+			}
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("%q is neither a duration like 24h or 7d nor a date like 2006-01-02", s)
+	}
+	return now.Add(-d), nil
+}
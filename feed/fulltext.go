@@ -0,0 +1,141 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/JohanLi233/gorss/config"
+)
+
+// fullTextWorkers limits concurrent page downloads during a refresh
+const fullTextWorkers = 4
+
+// applyFullText swaps in cached full content for articles that have it and,
+// for feeds with full_text enabled, extracts content for articles that don't
+func (fm *FeedManager) applyFullText(feed config.Feed, articles []Article) []Article {
+	var missing []int
+	fm.mu.RLock()
+	for i, a := range articles {
+		if content, ok := fm.fullText[a.Link]; ok {
+			articles[i].Content = content
+			articles[i].ContentType = ""
+		} else if feed.FullText && a.Link != "" {
+			missing = append(missing, i)
+		}
+	}
+	fm.mu.RUnlock()
+
+	if len(missing) == 0 {
+		return articles
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fullTextWorkers)
+	for _, i := range missing {
+		wg.Add(1)
+		go func(a *Article) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			content, err := FetchReadable(a.Link)
+			if err != nil {
+				// Keep the feed's own content; the user can retry from the detail view
+				return
+			}
+			content = fm.rewriteContent(a.Link, content)
+			a.Content = content
+			a.ContentType = ""
+			fm.mu.Lock()
+			fm.fullText[a.Link] = content
+			fm.mu.Unlock()
+		}(&articles[i])
+	}
+	wg.Wait()
+
+	if err := fm.saveFullText(); err != nil {
+		fm.warn(fmt.Sprintf("failed to save full-text cache: %v", err))
+	}
+	return articles
+}
+
+// FetchFullText extracts the full content of an article from its link, caches
+// it and updates the stored article. The updated article is returned.
+func (fm *FeedManager) FetchFullText(article Article) (Article, error) {
+	if article.Link == "" {
+		return article, fmt.Errorf("article has no link")
+	}
+
+	content, err := FetchReadable(article.Link)
+	if err != nil {
+		return article, err
+	}
+	content = fm.rewriteContent(article.Link, content)
+	article.Content = content
+	article.ContentType = ""
+
+	fm.mu.Lock()
+	fm.fullText[article.Link] = content
+	for i := range fm.Articles {
+		if fm.Articles[i].Link == article.Link {
+			fm.Articles[i].Content = content
+			fm.Articles[i].ContentType = ""
+		}
+	}
+	fm.mu.Unlock()
+
+	if err := fm.saveFullText(); err != nil {
+		return article, err
+	}
+	if err := fm.saveCache(); err != nil {
+		return article, err
+	}
+	return article, nil
+}
+
+// HasFullText reports whether full content has been extracted for a link
+func (fm *FeedManager) HasFullText(link string) bool {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	_, ok := fm.fullText[link]
+	return ok
+}
+
+// loadFullText loads extracted article content from the full-text cache file
+func (fm *FeedManager) loadFullText() error {
+	fullText := make(map[string]string)
+	warning, err := fm.lock.readJSON(fm.fullTextPath, &fullText)
+	fm.warn(warning)
+	if err != nil {
+		return err
+	}
+
+	fm.mu.Lock()
+	fm.fullText = fullText
+	fm.mu.Unlock()
+	return nil
+}
+
+// saveFullText saves extracted article content to the full-text cache file,
+// merging in content extracted by other gorss instances
+func (fm *FeedManager) saveFullText() error {
+	return fm.lock.withLock(true, func() error {
+		onDisk := make(map[string]string)
+		_, _ = readJSONFile(fm.fullTextPath, &onDisk)
+
+		fm.mu.Lock()
+		for link, content := range onDisk {
+			if _, ok := fm.fullText[link]; !ok {
+				fm.fullText[link] = content
+			}
+		}
+		data, err := json.MarshalIndent(fm.fullText, "", "  ")
+		fm.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		return writeFileAtomic(fm.fullTextPath, data, 0644)
+	})
+}
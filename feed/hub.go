@@ -0,0 +1,96 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// HubLink describes the WebSub hub advertised by a feed
+type HubLink struct {
+	Hub  string // URL of the hub to subscribe at
+	Self string // canonical topic URL of the feed, falls back to the fetched URL
+}
+
+// discoverHub looks for rel="hub" and rel="self" links in the HTTP Link
+// header and in the feed document itself (atom:link in both Atom and RSS)
+func discoverHub(body []byte, header http.Header) (hub, self string) {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, rels := parseLinkHeader(link)
+			for _, rel := range rels {
+				switch rel {
+				case "hub":
+					if hub == "" {
+						hub = target
+					}
+				case "self":
+					if self == "" {
+						self = target
+					}
+				}
+			}
+		}
+	}
+	if hub != "" && self != "" {
+		return hub, self
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) { return input, nil }
+	depth := 0
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			// Hub links live on the feed/channel, never deeper than <rss><channel><atom:link>
+			if t.Name.Local != "link" || depth > 3 {
+				// Stop once the first item/entry starts; hub links come before them
+				if t.Name.Local == "item" || t.Name.Local == "entry" {
+					return hub, self
+				}
+				continue
+			}
+			var rel, href string
+			for _, attr := range t.Attr {
+				switch attr.Name.Local {
+				case "rel":
+					rel = attr.Value
+				case "href":
+					href = attr.Value
+				}
+			}
+			if rel == "hub" && hub == "" {
+				hub = href
+			} else if rel == "self" && self == "" {
+				self = href
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return hub, self
+}
+
+// parseLinkHeader parses one entry of an RFC 8288 Link header,
+// e.g. `<https://hub.example/>; rel="hub"`
+func parseLinkHeader(link string) (string, []string) {
+	parts := strings.Split(link, ";")
+	target := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+	var rels []string
+	for _, param := range parts[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.ToLower(strings.TrimSpace(key)) != "rel" {
+			continue
+		}
+		rels = append(rels, strings.Fields(strings.Trim(value, `"`))...)
+	}
+	return target, rels
+}
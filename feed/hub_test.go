@@ -0,0 +1,53 @@
+package feed
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDiscoverHub(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		link      string
+		hub, self string
+	}{
+		{
+			name: "link header",
+			link: `<https://hub.example/>; rel="hub", <https://example.com/feed>; rel="self"`,
+			hub:  "https://hub.example/", self: "https://example.com/feed",
+		},
+		{
+			name: "atom",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom"><link rel="hub" href="https://hub.example/"/><link rel="self" href="https://example.com/atom"/></feed>`,
+			hub:  "https://hub.example/", self: "https://example.com/atom",
+		},
+		{
+			name: "rss with atom:link",
+			body: `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><atom:link rel="hub" href="https://hub.example/"/><atom:link rel="self" href="https://example.com/rss"/></channel></rss>`,
+			hub:  "https://hub.example/", self: "https://example.com/rss",
+		},
+		{
+			name: "header wins over the document",
+			link: `<https://header.example/>; rel="hub"`,
+			body: `<feed><link rel="hub" href="https://doc.example/"/><link rel="self" href="https://example.com/atom"/></feed>`,
+			hub:  "https://header.example/", self: "https://example.com/atom",
+		},
+		{
+			name: "links in entries are ignored",
+			body: `<feed><entry><link rel="hub" href="https://hub.example/"/></entry></feed>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.link != "" {
+				header.Set("Link", tt.link)
+			}
+			hub, self := discoverHub([]byte(tt.body), header)
+			if hub != tt.hub || self != tt.self {
+				t.Errorf("discoverHub = %q, %q; want %q, %q", hub, self, tt.hub, tt.self)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package feed
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultStripParams are the tracking query parameters removed from links
+// when the configuration doesn't list its own
+var DefaultStripParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "igshid",
+	"mc_cid", "mc_eid", "_hsenc", "_hsmi", "mkt_tok",
+}
+
+// urlAttributes are the HTML attributes holding URLs that get resolved
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "poster": true, "cite": true, "action": true, "data": true,
+}
+
+// stripRule removes query parameters whose name matches a glob pattern,
+// optionally only on one domain (and its subdomains)
+type stripRule struct {
+	host    string
+	pattern string
+}
+
+// parseStripRules parses rules of the form "pattern" or "host:pattern",
+// e.g. "utm_*" or "youtube.com:si"
+func parseStripRules(rules []string) []stripRule {
+	var parsed []stripRule
+	for _, r := range rules {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		host, pattern, ok := strings.Cut(r, ":")
+		if !ok {
+			host, pattern = "", r
+		}
+		parsed = append(parsed, stripRule{host: strings.ToLower(host), pattern: pattern})
+	}
+	return parsed
+}
+
+// matches reports whether the rule removes param from a URL on host
+func (r stripRule) matches(host, param string) bool {
+	if r.host != "" && host != r.host && !strings.HasSuffix(host, "."+r.host) {
+		return false
+	}
+	ok, _ := path.Match(r.pattern, param)
+	return ok
+}
+
+// linkRewriter makes the links of fetched articles absolute and removes
+// tracking parameters from them
+type linkRewriter struct {
+	rules []stripRule
+}
+
+// SetStripParams sets the tracking-parameter rules applied to article links
+// on fetch. A nil list selects DefaultStripParams; an empty one disables stripping.
+func (fm *FeedManager) SetStripParams(rules []string) {
+	if rules == nil {
+		rules = DefaultStripParams
+	}
+	fm.mu.Lock()
+	fm.links = linkRewriter{rules: parseStripRules(rules)}
+	fm.mu.Unlock()
+}
+
+// rewriteLinks resolves article links against the feed URL, and links inside
+// HTML content against the article link, stripping tracking parameters
+func (fm *FeedManager) rewriteLinks(feedURL string, articles []Article) []Article {
+	fm.mu.RLock()
+	lr := fm.links
+	fm.mu.RUnlock()
+
+	base, err := url.Parse(feedURL)
+	if err != nil || base.Host == "" {
+		// maildir: and other local sources have nothing to resolve against
+		base = nil
+	}
+	for i := range articles {
+		a := &articles[i]
+		a.Link = lr.rewriteURL(a.Link, base)
+		a.CommentsURL = lr.rewriteURL(a.CommentsURL, base)
+		a.CommentsFeed = lr.rewriteURL(a.CommentsFeed, base)
+		a.Image = lr.rewriteURL(a.Image, base)
+		for j := range a.Enclosures {
+			a.Enclosures[j].URL = lr.rewriteURL(a.Enclosures[j].URL, base)
+		}
+
+		if a.ContentType != "" {
+			// Gemtext isn't HTML; its links are shown as written
+			continue
+		}
+		itemBase := base
+		if u, err := url.Parse(a.Link); err == nil && u.IsAbs() {
+			itemBase = u
+		}
+		a.Content = lr.rewriteHTML(a.Content, itemBase)
+		a.Description = lr.rewriteHTML(a.Description, itemBase)
+	}
+	return articles
+}
+
+// rewriteURL resolves ref against base (if any) and strips tracking parameters
+func (lr linkRewriter) rewriteURL(ref string, base *url.URL) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	if base != nil && !u.IsAbs() {
+		u = base.ResolveReference(u)
+	}
+	if u.RawQuery != "" && len(lr.rules) > 0 && (u.Scheme == "http" || u.Scheme == "https") {
+		u.RawQuery = lr.stripQuery(strings.ToLower(u.Hostname()), u.RawQuery)
+	}
+	return u.String()
+}
+
+// stripQuery removes matching parameters from a raw query, keeping the order
+// and encoding of the others
+func (lr linkRewriter) stripQuery(host, rawQuery string) string {
+	parts := strings.Split(rawQuery, "&")
+	kept := parts[:0]
+	for _, part := range parts {
+		name, _, _ := strings.Cut(part, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		strip := false
+		for _, r := range lr.rules {
+			if r.matches(host, name) {
+				strip = true
+				break
+			}
+		}
+		if !strip {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+// rewriteHTML rewrites the URL attributes and srcset lists of an HTML fragment.
+// Content without any link is returned unchanged, so it isn't reformatted.
+func (lr linkRewriter) rewriteHTML(content string, base *url.URL) string {
+	if !strings.Contains(content, "=") {
+		return content
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(content), &html.Node{
+		Type: html.ElementNode, Data: "body", DataAtom: atom.Body,
+	})
+	if err != nil {
+		return content
+	}
+
+	changed := false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for i, a := range n.Attr {
+				var rewritten string
+				switch {
+				case urlAttributes[a.Key]:
+					rewritten = lr.rewriteURL(a.Val, base)
+				case a.Key == "srcset":
+					rewritten = lr.rewriteSrcset(a.Val, base)
+				default:
+					continue
+				}
+				if rewritten != a.Val {
+					n.Attr[i].Val = rewritten
+					changed = true
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	if !changed {
+		return content
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		if err := html.Render(&b, n); err != nil {
+			return content
+		}
+	}
+	return b.String()
+}
+
+// rewriteSrcset rewrites each URL of a srcset attribute ("a.png 1x, b.png 2x")
+func (lr linkRewriter) rewriteSrcset(srcset string, base *url.URL) string {
+	candidates := strings.Split(srcset, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = lr.rewriteURL(fields[0], base)
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// rewriteContent rewrites the links of extracted article content against the article link
+func (fm *FeedManager) rewriteContent(link, content string) string {
+	fm.mu.RLock()
+	lr := fm.links
+	fm.mu.RUnlock()
+
+	base, err := url.Parse(link)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+	return lr.rewriteHTML(content, base)
+}
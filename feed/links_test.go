@@ -0,0 +1,139 @@
+package feed
+
+import (
+	"testing"
+)
+
+func TestRewriteLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []string // nil for DefaultStripParams
+		feedURL string
+		article Article
+		link    string
+		content string
+	}{
+		{
+			name:    "relative link",
+			feedURL: "https://example.com/blog/feed.xml",
+			article: Article{Link: "posts/1.html"},
+			link:    "https://example.com/blog/posts/1.html",
+		},
+		{
+			name:    "root-relative link",
+			feedURL: "https://example.com/blog/feed.xml",
+			article: Article{Link: "/posts/1.html?utm_source=rss"},
+			link:    "https://example.com/posts/1.html",
+		},
+		{
+			name:    "relative src against the article link",
+			feedURL: "https://example.com/feed.xml",
+			article: Article{Link: "https://cdn.example.org/2024/post/", Content: `<p><img src="img/a.png" alt="a"/></p>`},
+			link:    "https://cdn.example.org/2024/post/",
+			content: `<p><img src="https://cdn.example.org/2024/post/img/a.png" alt="a"/></p>`,
+		},
+		{
+			name:    "relative href without an article link",
+			feedURL: "https://example.com/feed.xml",
+			article: Article{Content: `<a href="/about">about</a>`},
+			content: `<a href="https://example.com/about">about</a>`,
+		},
+		{
+			name:    "srcset",
+			feedURL: "https://example.com/feed.xml",
+			article: Article{Link: "https://example.com/p/", Content: `<img srcset="a.png 1x,  /b.png?utm_medium=x 2x" src="a.png"/>`},
+			link:    "https://example.com/p/",
+			content: `<img srcset="https://example.com/p/a.png 1x, https://example.com/b.png 2x" src="https://example.com/p/a.png"/>`,
+		},
+		{
+			name:    "tracking parameters stripped, others and fragment kept",
+			feedURL: "https://example.com/feed.xml",
+			article: Article{Link: "https://example.com/p?id=7&utm_source=rss&utm_campaign=x&fbclid=abc&q=a%20b#comments"},
+			link:    "https://example.com/p?id=7&q=a%20b#comments",
+		},
+		{
+			name:    "escaped parameter name",
+			feedURL: "https://example.com/feed.xml",
+			article: Article{Link: "https://example.com/p?utm%5Fsource=rss&page=2"},
+			link:    "https://example.com/p?page=2",
+		},
+		{
+			name:    "only tracking parameters",
+			feedURL: "https://example.com/feed.xml",
+			article: Article{Link: "https://example.com/p?gclid=1#top"},
+			link:    "https://example.com/p#top",
+		},
+		{
+			name:    "custom rule on a domain",
+			rules:   []string{"youtube.com:si", "ref"},
+			feedURL: "https://example.com/feed.xml",
+			article: Article{
+				Link:    "https://www.youtube.com/watch?v=abc&si=track&utm_source=rss",
+				Content: `<a href="https://example.com/x?si=keep&ref=home&v=1">x</a>`,
+			},
+			link:    "https://www.youtube.com/watch?v=abc&utm_source=rss",
+			content: `<a href="https://example.com/x?si=keep&amp;v=1">x</a>`,
+		},
+		{
+			name:    "stripping disabled",
+			rules:   []string{},
+			feedURL: "https://example.com/feed.xml",
+			article: Article{Link: "https://example.com/p?utm_source=rss"},
+			link:    "https://example.com/p?utm_source=rss",
+		},
+		{
+			name:    "in-page anchors and other schemes untouched",
+			feedURL: "https://example.com/feed.xml",
+			article: Article{Link: "#top", Content: `<a href="mailto:a@example.com?utm_source=x">mail</a>`},
+			link:    "#top",
+			content: `<a href="mailto:a@example.com?utm_source=x">mail</a>`,
+		},
+		{
+			name:    "gemtext content left alone",
+			feedURL: "gemini://example.org/feed.gmi",
+			article: Article{Link: "post.gmi", ContentType: "text/gemini", Content: `=> /x?a=b link`},
+			link:    "gemini://example.org/post.gmi",
+			content: `=> /x?a=b link`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm := NewFeedManager(nil, t.TempDir())
+			fm.SetStripParams(tt.rules)
+			got := fm.rewriteLinks(tt.feedURL, []Article{tt.article})[0]
+			if got.Link != tt.link {
+				t.Errorf("link = %q, want %q", got.Link, tt.link)
+			}
+			if got.Content != tt.content {
+				t.Errorf("content = %q, want %q", got.Content, tt.content)
+			}
+		})
+	}
+}
+
+func TestParseStripRules(t *testing.T) {
+	rules := parseStripRules([]string{" utm_* ", "", "YouTube.com:si"})
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2: %+v", len(rules), rules)
+	}
+	tests := []struct {
+		host, param string
+		want        bool
+	}{
+		{"example.com", "utm_source", true},
+		{"example.com", "utm", false},
+		{"youtube.com", "si", true},
+		{"m.youtube.com", "si", true},
+		{"notyoutube.com", "si", false},
+		{"example.com", "si", false},
+	}
+	for _, tt := range tests {
+		got := false
+		for _, r := range rules {
+			got = got || r.matches(tt.host, tt.param)
+		}
+		if got != tt.want {
+			t.Errorf("%s %s: stripped = %v, want %v", tt.host, tt.param, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package feed
+
+import "os"
+
+// lockFile is a no-op where flock is unavailable; writes are still atomic,
+// only concurrent instances may overwrite each other's updates
+func lockFile(f *os.File, exclusive bool) error { return nil }
+
+// unlockFile is a no-op where flock is unavailable
+func unlockFile(f *os.File) error { return nil }
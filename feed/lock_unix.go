@@ -0,0 +1,22 @@
+//go:build unix
+
+package feed
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes a blocking flock on f
+func lockFile(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	return syscall.Flock(int(f.Fd()), how)
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
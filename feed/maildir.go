@@ -0,0 +1,210 @@
+package feed
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JohanLi233/gorss/config"
+	"golang.org/x/net/html/charset"
+)
+
+// maildirScheme is the URL prefix used to configure a Maildir folder as a feed,
+// e.g. "maildir:~/Mail/newsletters" or "maildir:///var/mail/news"
+const maildirScheme = "maildir:"
+
+// wordDecoder decodes RFC 2047 encoded header words such as "=?UTF-8?B?...?="
+var wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// isMaildirURL reports whether a feed URL points at a local Maildir folder
+func isMaildirURL(url string) bool {
+	return strings.HasPrefix(url, maildirScheme)
+}
+
+// maildirPath converts a maildir: URL into a filesystem path
+func maildirPath(url string) (string, error) {
+	path := strings.TrimPrefix(url, maildirScheme)
+	path = strings.TrimPrefix(path, "//")
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, path[1:])
+	}
+	if path == "" {
+		return "", fmt.Errorf("empty maildir path")
+	}
+	return path, nil
+}
+
+// fetchMaildir reads every message in a Maildir folder and converts it to articles.
+// Messages are deduplicated by Message-ID, so a message that shows up in both
+// new/ and cur/ (or was delivered twice) only produces one article.
+func fetchMaildir(feed config.Feed) ([]Article, error) {
+	root, err := maildirPath(feed.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only new/ and cur/ hold complete messages; tmp/ is for deliveries in progress
+	var files []string
+	for _, sub := range []string{"new", "cur"} {
+		entries, err := os.ReadDir(filepath.Join(root, sub))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read maildir %s: %w", root, err)
+		}
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				files = append(files, filepath.Join(root, sub, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+
+	seen := make(map[string]struct{})
+	var articles []Article
+	for _, path := range files {
+		article, err := readMaildirMessage(path)
+		if err != nil {
+			// Skip unreadable messages instead of failing the whole folder
+			continue
+		}
+		if _, dup := seen[article.GUID]; dup {
+			continue
+		}
+		seen[article.GUID] = struct{}{}
+		article.Source = feed.Name
+		articles = append(articles, article)
+	}
+
+	return articles, nil
+}
+
+// readMaildirMessage parses a single message file into an article
+func readMaildirMessage(path string) (Article, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Article{}, err
+	}
+	defer f.Close()
+
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		return Article{}, err
+	}
+
+	messageID := strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>")
+	if messageID == "" {
+		// Fall back to the unique part of the maildir filename (before the ":2," flags)
+		messageID = strings.SplitN(filepath.Base(path), ":", 2)[0]
+	}
+
+	subject := decodeHeader(msg.Header.Get("Subject"))
+	sender := decodeHeader(msg.Header.Get("From"))
+	if addr, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		sender = addr.Name
+		if sender == "" {
+			sender = addr.Address
+		}
+	}
+
+	pubDate, err := msg.Header.Date()
+	if err != nil {
+		pubDate = time.Now()
+	}
+
+	htmlBody, textBody := extractMailBodies(msg.Header, msg.Body)
+	content := htmlBody
+	if content == "" {
+		content = textBody
+	}
+
+	return Article{
+		Title:       subject,
+		Description: textBody,
+		Content:     content,
+		Link:        strings.Trim(msg.Header.Get("Archived-At"), "<> "),
+		Published:   pubDate,
+		FeedName:    sender,
+		GUID:        messageID,
+	}, nil
+}
+
+// decodeHeader decodes RFC 2047 encoded words, returning the raw value on failure
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// mimeHeader is the subset of header access needed to walk MIME parts
+type mimeHeader interface {
+	Get(key string) string
+}
+
+// extractMailBodies walks a (possibly multipart) message body and returns the
+// first text/html and text/plain parts it finds, decoded to UTF-8
+func extractMailBodies(header mimeHeader, body io.Reader) (htmlBody, textBody string) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err != nil {
+				break
+			}
+			h, t := extractMailBodies(part.Header, part)
+			if htmlBody == "" {
+				htmlBody = h
+			}
+			if textBody == "" {
+				textBody = t
+			}
+		}
+		return htmlBody, textBody
+	}
+
+	if mediaType != "text/html" && mediaType != "text/plain" {
+		return "", ""
+	}
+
+	var r io.Reader = body
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(body)
+	}
+
+	if cs := params["charset"]; cs != "" {
+		if cr, err := charset.NewReaderLabel(cs, r); err == nil {
+			r = cr
+		}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil && len(data) == 0 {
+		return "", ""
+	}
+
+	if mediaType == "text/html" {
+		return string(data), ""
+	}
+	return "", string(data)
+}
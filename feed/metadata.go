@@ -0,0 +1,147 @@
+package feed
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+// customComments is the gofeed Item.Custom key used to carry the RSS <comments> URL
+const customComments = "comments"
+
+// rssTranslator extends the default gofeed RSS translator to keep the
+// <comments> element, which the universal feed type otherwise drops
+type rssTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+// Translate converts an RSS feed into the universal feed type
+func (t *rssTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	source, ok := feed.(*rss.Feed)
+	if !ok || len(source.Items) != len(result.Items) {
+		return result, nil
+	}
+	for i, item := range source.Items {
+		if item.Comments == "" {
+			continue
+		}
+		if result.Items[i].Custom == nil {
+			result.Items[i].Custom = make(map[string]string)
+		}
+		result.Items[i].Custom[customComments] = item.Comments
+	}
+	return result, nil
+}
+
+// newParser returns a gofeed parser that preserves the metadata gorss uses
+func newParser() *gofeed.Parser {
+	parser := gofeed.NewParser()
+	parser.RSSTranslator = &rssTranslator{}
+	return parser
+}
+
+// itemAuthors returns the display names of an item's authors
+func itemAuthors(item *gofeed.Item) []string {
+	var authors []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			authors = append(authors, name)
+		}
+	}
+
+	people := item.Authors
+	if len(people) == 0 && item.Author != nil {
+		people = []*gofeed.Person{item.Author}
+	}
+	for _, p := range people {
+		if p == nil {
+			continue
+		}
+		if p.Name != "" {
+			add(p.Name)
+		} else {
+			add(p.Email)
+		}
+	}
+	if len(authors) == 0 && item.DublinCoreExt != nil {
+		for _, creator := range item.DublinCoreExt.Creator {
+			add(creator)
+		}
+	}
+	return authors
+}
+
+// itemImage returns the item image or thumbnail, looking at the common places feeds put it
+func itemImage(item *gofeed.Item) string {
+	if item.Image != nil && item.Image.URL != "" {
+		return item.Image.URL
+	}
+	if media, ok := item.Extensions["media"]; ok {
+		for _, name := range []string{"thumbnail", "content", "group"} {
+			if url := mediaImage(media[name]); url != "" {
+				return url
+			}
+		}
+	}
+	if item.ITunesExt != nil && item.ITunesExt.Image != "" {
+		return item.ITunesExt.Image
+	}
+	for _, e := range item.Enclosures {
+		if strings.HasPrefix(e.Type, "image/") {
+			return e.URL
+		}
+	}
+	return ""
+}
+
+// mediaImage finds an image URL in Media RSS elements, descending into media:group
+func mediaImage(elements []ext.Extension) string {
+	for _, e := range elements {
+		if e.Name == "group" {
+			for _, name := range []string{"thumbnail", "content"} {
+				if url := mediaImage(e.Children[name]); url != "" {
+					return url
+				}
+			}
+			continue
+		}
+		url := e.Attrs["url"]
+		if url == "" {
+			continue
+		}
+		if e.Name == "thumbnail" || e.Attrs["medium"] == "image" || strings.HasPrefix(e.Attrs["type"], "image/") {
+			return url
+		}
+	}
+	return ""
+}
+
+// itemComments returns the comments page URL, the comments feed URL and the
+// comment count (slash:comments) of an item
+func itemComments(item *gofeed.Item) (page, feedURL string, count int) {
+	page = item.Custom[customComments]
+	if wfw, ok := item.Extensions["wfw"]; ok {
+		for _, name := range []string{"commentRss", "commentrss"} {
+			if values := wfw[name]; len(values) > 0 {
+				feedURL = strings.TrimSpace(values[0].Value)
+				break
+			}
+		}
+	}
+	if slash, ok := item.Extensions["slash"]; ok {
+		if values := slash["comments"]; len(values) > 0 {
+			count, _ = strconv.Atoi(strings.TrimSpace(values[0].Value))
+		}
+	}
+	return page, feedURL, count
+}
@@ -0,0 +1,253 @@
+package feed
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// The scoring below follows the classic Arc90 readability heuristics: paragraphs
+// vote for their parent and grandparent containers, class/id names nudge the
+// score up or down, and link-heavy containers are penalised.
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumbs|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|ad-break|agegate|pagination|pager|popup|promo|subscribe|newsletter`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveNames      = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeNames      = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// strippedTags are removed from the document before scoring
+var strippedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Form: true, atom.Nav: true, atom.Aside: true, atom.Svg: true,
+	atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+	atom.Object: true, atom.Embed: true, atom.Link: true, atom.Meta: true,
+}
+
+// FetchReadable downloads a web page and extracts its main content as HTML
+func FetchReadable(pageURL string) (string, error) {
+	body, _, err := fetchHTTP(pageURL)
+	if err != nil {
+		return "", err
+	}
+	return ExtractReadable(body)
+}
+
+// ExtractReadable finds the main content of an HTML page and returns it as cleaned HTML
+func ExtractReadable(page []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+
+	prepareDocument(doc)
+
+	scores := make(map[*html.Node]float64)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Td || n.DataAtom == atom.Blockquote) {
+			scoreParagraph(n, scores)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var top *html.Node
+	topScore := 0.0
+	for n, score := range scores {
+		score *= 1 - linkDensity(n)
+		scores[n] = score
+		if top == nil || score > topScore {
+			top, topScore = n, score
+		}
+	}
+
+	if top == nil {
+		top = findElement(doc, atom.Body)
+		if top == nil {
+			return "", fmt.Errorf("no readable content found")
+		}
+	}
+
+	// Siblings that scored well, or look like real paragraphs, belong to the article too
+	var out bytes.Buffer
+	threshold := math.Max(10, topScore*0.2)
+	parent := top.Parent
+	if parent == nil {
+		return renderNode(top), nil
+	}
+	for sib := parent.FirstChild; sib != nil; sib = sib.NextSibling {
+		if sib.Type != html.ElementNode {
+			continue
+		}
+		include := sib == top || scores[sib] >= threshold
+		if !include && sib.DataAtom == atom.P {
+			text := textOf(sib)
+			density := linkDensity(sib)
+			include = (len(text) > 80 && density < 0.25) ||
+				(len(text) > 0 && density == 0 && strings.Contains(text, ". "))
+		}
+		if include {
+			out.WriteString(renderNode(sib))
+		}
+	}
+
+	result := strings.TrimSpace(out.String())
+	if result == "" {
+		return "", fmt.Errorf("no readable content found")
+	}
+	return result, nil
+}
+
+// prepareDocument removes non-content elements and unlikely candidates in place
+func prepareDocument(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		} else if c.Type == html.ElementNode {
+			names := attr(c, "class") + " " + attr(c, "id")
+			unlikely := unlikelyCandidates.MatchString(names) && !maybeCandidate.MatchString(names) &&
+				c.DataAtom != atom.Body && c.DataAtom != atom.Article && c.DataAtom != atom.Html
+			if strippedTags[c.DataAtom] || unlikely || c.DataAtom == atom.Footer || (c.DataAtom == atom.Header && c.Parent != nil && c.Parent.DataAtom == atom.Body) {
+				n.RemoveChild(c)
+			} else {
+				prepareDocument(c)
+			}
+		}
+		c = next
+	}
+}
+
+// scoreParagraph adds a paragraph's content score to its parent and grandparent
+func scoreParagraph(p *html.Node, scores map[*html.Node]float64) {
+	text := textOf(p)
+	if len(text) < 25 {
+		return
+	}
+
+	score := 1.0
+	score += float64(strings.Count(text, ",") + strings.Count(text, "，"))
+	score += math.Min(float64(len(text))/100, 3)
+
+	parent := p.Parent
+	if parent == nil || parent.Type != html.ElementNode {
+		return
+	}
+	if _, ok := scores[parent]; !ok {
+		scores[parent] = initialScore(parent)
+	}
+	scores[parent] += score
+
+	grandparent := parent.Parent
+	if grandparent != nil && grandparent.Type == html.ElementNode {
+		if _, ok := scores[grandparent]; !ok {
+			scores[grandparent] = initialScore(grandparent)
+		}
+		scores[grandparent] += score / 2
+	}
+}
+
+// initialScore scores a container by its tag and its class/id names
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			score -= 25
+		}
+		if positiveNames.MatchString(name) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity is the share of a node's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(textOf(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linked += len(textOf(c))
+			return
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return float64(linked) / float64(total)
+}
+
+// textOf returns the whitespace-normalised text of a node
+func textOf(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// attr returns the value of an attribute, or "" if it is not set
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// findElement returns the first element with the given tag
+func findElement(n *html.Node, tag atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// renderNode serialises a node back to HTML
+func renderNode(n *html.Node) string {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil {
+		return ""
+	}
+	return buf.String()
+}
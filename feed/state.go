@@ -0,0 +1,160 @@
+package feed
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State records which articles have been read and which are starred, keyed
+// by article ID. Unlike the caches it can't be rebuilt by fetching, so it
+// lives in the state directory (see config.Paths). The TUI and the CLI share it.
+type State struct {
+	path string
+	lock cacheLock
+
+	mu      sync.Mutex
+	read    marks
+	starred marks
+}
+
+// marks is a set of article IDs with the time each was added, plus the IDs
+// changed since the last save
+type marks struct {
+	at    map[string]time.Time
+	dirty map[string]bool
+}
+
+func newMarks(at map[string]time.Time) marks {
+	if at == nil {
+		at = make(map[string]time.Time)
+	}
+	return marks{at: at, dirty: make(map[string]bool)}
+}
+
+func (m marks) has(id string) bool {
+	_, ok := m.at[id]
+	return ok
+}
+
+func (m marks) set(id string, on bool) {
+	if m.has(id) == on {
+		return
+	}
+	if on {
+		m.at[id] = time.Now()
+	} else {
+		delete(m.at, id)
+	}
+	m.dirty[id] = true
+}
+
+// merge combines the marks saved on disk with ours: IDs we changed keep our
+// value, all others take the one on disk
+func (m marks) merge(onDisk map[string]time.Time) marks {
+	merged := make(map[string]time.Time)
+	for id, t := range onDisk {
+		if !m.dirty[id] {
+			merged[id] = t
+		}
+	}
+	for id := range m.dirty {
+		if t, ok := m.at[id]; ok {
+			merged[id] = t
+		}
+	}
+	return newMarks(merged)
+}
+
+// reload is merge for a State that hasn't been saved: the IDs we changed stay
+// marked as changed
+func (m marks) reload(onDisk map[string]time.Time) marks {
+	merged := m.merge(onDisk)
+	merged.dirty = m.dirty
+	return merged
+}
+
+// stateFile is the on-disk form of State
+type stateFile struct {
+	Read    map[string]time.Time `json:"read"`
+	Starred map[string]time.Time `json:"starred,omitempty"`
+}
+
+// LoadState reads the reading state from stateDir; a missing file is an
+// empty state. The warning describes any recovery from a corrupt file.
+func LoadState(stateDir string) (*State, string, error) {
+	s := &State{
+		path:    filepath.Join(stateDir, "state.json"),
+		lock:    cacheLock{path: filepath.Join(stateDir, ".lock")},
+		read:    newMarks(nil),
+		starred: newMarks(nil),
+	}
+	var f stateFile
+	warning, err := s.lock.readJSON(s.path, &f)
+	s.read = newMarks(f.Read)
+	s.starred = newMarks(f.Starred)
+	return s, warning, err
+}
+
+// IsRead reports whether an article has been read
+func (s *State) IsRead(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read.has(id)
+}
+
+// SetRead marks an article read or unread. Call Save to write the change.
+func (s *State) SetRead(id string, read bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.read.set(id, read)
+}
+
+// IsStarred reports whether an article is starred
+func (s *State) IsStarred(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.starred.has(id)
+}
+
+// SetStarred stars or unstars an article. Call Save to write the change.
+func (s *State) SetStarred(id string, starred bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.starred.set(id, starred)
+}
+
+// Reload picks up the changes another gorss process saved, keeping the ones
+// made here that are not saved yet
+func (s *State) Reload() error {
+	var onDisk stateFile
+	if _, err := s.lock.readJSON(s.path, &onDisk); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.read = s.read.reload(onDisk.Read)
+	s.starred = s.starred.reload(onDisk.Starred)
+	return nil
+}
+
+// Save writes the state, keeping changes another gorss process saved in the
+// meantime for articles this one didn't touch
+func (s *State) Save() error {
+	return s.lock.withLock(true, func() error {
+		var onDisk stateFile
+		_, _ = readJSONFile(s.path, &onDisk)
+
+		s.mu.Lock()
+		s.read = s.read.merge(onDisk.Read)
+		s.starred = s.starred.merge(onDisk.Starred)
+		data, err := json.MarshalIndent(stateFile{Read: s.read.at, Starred: s.starred.at}, "", "  ")
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		return writeFileAtomic(s.path, data, 0644)
+	})
+}
@@ -0,0 +1,133 @@
+package feed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JohanLi233/gorss/internal/fsutil"
+)
+
+// backupSuffix is appended to a cache file name for its last known-good copy
+const backupSuffix = ".bak"
+
+// writeFileAtomic replaces path with data (see fsutil.WriteFile). The
+// previous contents, if they were valid JSON, are kept as path+".bak".
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if old, err := os.ReadFile(path); err == nil && json.Valid(old) {
+		if err := fsutil.WriteFile(path+backupSuffix, old, perm); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	return fsutil.WriteFile(path, data, perm)
+}
+
+// readJSONFile decodes a JSON cache file into v. If the file is corrupt it
+// falls back to the backup copy and moves the corrupt file aside, so the next
+// save starts clean. A missing file is not an error and leaves v untouched.
+// The returned warning describes any recovery that happened. Since recovering
+// moves files, callers hold the exclusive lock; readers use cacheLock.readJSON.
+func readJSONFile(path string, v interface{}) (warning string, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	decodeErr := json.Unmarshal(data, v)
+	if decodeErr == nil {
+		return "", nil
+	}
+
+	// Keep the broken file for inspection instead of overwriting it on the next save
+	_ = os.Rename(path, path+".corrupt")
+
+	backup, err := os.ReadFile(path + backupSuffix)
+	if err == nil && json.Unmarshal(backup, v) == nil {
+		return fmt.Sprintf("%s was corrupt (%v); restored from backup", filepath.Base(path), decodeErr), nil
+	}
+	return fmt.Sprintf("%s was corrupt (%v) and no usable backup exists; starting empty", filepath.Base(path), decodeErr), nil
+}
+
+// cacheLock is an advisory lock on the cache directory, shared by every
+// gorss process using it, so concurrent instances don't interleave writes
+type cacheLock struct {
+	path string
+}
+
+// withLock runs fn while holding the cache directory lock. Exclusive locks
+// are for writers; readers take a shared lock.
+func (l cacheLock) withLock(exclusive bool, fn func() error) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lockFile(f, exclusive); err != nil {
+		return fmt.Errorf("failed to lock cache directory: %w", err)
+	}
+	defer unlockFile(f)
+
+	return fn()
+}
+
+// readJSON reads a JSON cache file into v under the shared lock, like
+// readJSONFile. A corrupt file is only moved aside under the exclusive lock,
+// after reading it again: another process may have replaced it meanwhile.
+func (l cacheLock) readJSON(path string, v interface{}) (warning string, err error) {
+	corrupt := false
+	err = l.withLock(false, func() error {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		corrupt = !json.Valid(data) || json.Unmarshal(data, v) != nil
+		return nil
+	})
+	if err != nil || !corrupt {
+		return "", err
+	}
+	err = l.withLock(true, func() error {
+		var err error
+		warning, err = readJSONFile(path, v)
+		return err
+	})
+	return warning, err
+}
+
+// dirLock returns the lock of the directory path is in, which the caches
+// and reading state kept there use as well
+func dirLock(path string) cacheLock {
+	return cacheLock{path: filepath.Join(filepath.Dir(path), ".lock")}
+}
+
+// SaveJSON writes v as indented JSON to path, atomically and keeping a
+// backup, like the caches; for other packages' files next to them. It holds
+// the directory's exclusive lock while writing.
+func SaveJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return dirLock(path).withLock(true, func() error {
+		return writeFileAtomic(path, data, 0644)
+	})
+}
+
+// LoadJSON reads a file written by SaveJSON into v under the directory's
+// lock, recovering from the backup if it is corrupt. A missing file leaves v
+// untouched.
+func LoadJSON(path string, v interface{}) (warning string, err error) {
+	return dirLock(path).readJSON(path, v)
+}
@@ -0,0 +1,103 @@
+package feed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadJSONRecoversCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	lock := cacheLock{path: filepath.Join(dir, ".lock")}
+	path := filepath.Join(dir, "cache.json")
+
+	if err := writeFileAtomic(path, []byte(`{"a":"1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte(`{"a":"2"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	warning, err := lock.readJSON(path, &got)
+	if err != nil || warning != "" || got["a"] != "2" {
+		t.Fatalf("valid file: got %v, %q, %v", got, warning, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"a":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got = make(map[string]string)
+	warning, err = lock.readJSON(path, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["a"] != "1" || !strings.Contains(warning, "restored from backup") {
+		t.Errorf("corrupt file: got %v, %q; want the backup", got, warning)
+	}
+	if data, err := os.ReadFile(path + ".corrupt"); err != nil || string(data) != `{"a":` {
+		t.Errorf("corrupt file not kept aside: %q, %v", data, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("corrupt file still in place: %v", err)
+	}
+}
+
+func TestReadJSONMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	lock := cacheLock{path: filepath.Join(dir, ".lock")}
+	got := map[string]string{"kept": "yes"}
+	warning, err := lock.readJSON(filepath.Join(dir, "missing.json"), &got)
+	if err != nil || warning != "" || got["kept"] != "yes" {
+		t.Errorf("missing file: got %v, %q, %v", got, warning, err)
+	}
+}
+
+func TestLoadJSONTakesTheDirectoryLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "greader_sync.json")
+	if err := SaveJSON(path, map[string]string{"a": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveJSON(path, map[string]string{"a": "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// While another process holds the lock to replace the file, it is
+	// corrupt on disk; LoadJSON must wait instead of moving it aside
+	lock := cacheLock{path: filepath.Join(dir, ".lock")}
+	loaded := make(chan map[string]string, 1)
+	var got map[string]string
+	err := lock.withLock(true, func() error {
+		if err := os.WriteFile(path, []byte(`{"a":`), 0644); err != nil {
+			return err
+		}
+		go func() {
+			got := make(map[string]string)
+			if _, err := LoadJSON(path, &got); err != nil {
+				t.Error(err)
+			}
+			loaded <- got
+		}()
+		select {
+		case got = <-loaded:
+			t.Error("LoadJSON didn't wait for the lock")
+		case <-time.After(100 * time.Millisecond):
+		}
+		return os.WriteFile(path, []byte(`{"a":"3"}`), 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		got = <-loaded
+	}
+	if got["a"] != "3" {
+		t.Errorf("loaded %v, want the file written under the lock", got)
+	}
+	if _, err := os.Stat(path + ".corrupt"); !os.IsNotExist(err) {
+		t.Errorf("file moved aside: %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package greader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client talks to a Google Reader compatible server such as FreshRSS,
+// Miniflux or another `gorss serve`
+type Client struct {
+	base     string // API root, without the trailing slash
+	username string
+	password string
+	http     *http.Client
+
+	auth  string // ClientLogin token
+	token string // edit token
+}
+
+// NewClient creates a client for the API at base, e.g.
+// https://rss.example.com/api/greader.php
+func NewClient(base, username, password string) *Client {
+	return &Client{
+		base:     strings.TrimRight(base, "/"),
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// StatusError is an error response from the server
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned %d %s", e.Code, http.StatusText(e.Code))
+	}
+	return fmt.Sprintf("server returned %d: %s", e.Code, e.Message)
+}
+
+// Rejected reports whether err means the server refused a request, as opposed
+// to being unreachable or failing; repeating a rejected request won't help
+func Rejected(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code >= 400 && se.Code < 500
+}
+
+// login gets a token with the username and password
+func (c *Client) login() error {
+	form := url.Values{"Email": {c.username}, "Passwd": {c.password}}
+	resp, err := c.http.PostForm(c.base+"/accounts/ClientLogin", form)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login failed: %w", statusError(resp))
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if auth, ok := strings.CutPrefix(scanner.Text(), "Auth="); ok {
+			c.auth = strings.TrimSpace(auth)
+			return nil
+		}
+	}
+	return fmt.Errorf("login failed: no Auth token in the response")
+}
+
+// do sends a request to the API, logging in first and again once if the
+// token has expired. The response body must be closed.
+func (c *Client) do(method, path string, params url.Values) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if c.auth == "" {
+			if err := c.login(); err != nil {
+				return nil, err
+			}
+		}
+		u := c.base + apiPrefix + path
+		var body io.Reader
+		if method == http.MethodGet {
+			u += "?" + params.Encode()
+		} else {
+			body = strings.NewReader(params.Encode())
+		}
+		req, err := http.NewRequest(method, u, body)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.Header.Set("Authorization", "GoogleLogin auth="+c.auth)
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			c.auth, c.token = "", ""
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return nil, statusError(resp)
+		}
+		return resp, nil
+	}
+}
+
+func statusError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &StatusError{Code: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+}
+
+// get calls a JSON method
+func (c *Client) get(path string, params url.Values, v interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("output", "json")
+	return c.call(http.MethodGet, path, params, v)
+}
+
+// post calls a method that changes something, or that takes more
+// parameters than fit in a URL, with the edit token. v may be nil.
+func (c *Client) post(path string, form url.Values, v interface{}) error {
+	if c.token == "" {
+		resp, err := c.do(http.MethodGet, "token", url.Values{})
+		if err != nil {
+			return fmt.Errorf("token: %w", err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("token: %w", err)
+		}
+		c.token = strings.TrimSpace(string(data))
+	}
+	form.Set("T", c.token)
+	return c.call(http.MethodPost, path, form, v)
+}
+
+func (c *Client) call(method, path string, params url.Values, v interface{}) error {
+	resp, err := c.do(method, path, params)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if v == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", path, err)
+	}
+	return nil
+}
+
+// Subscription is a feed the account is subscribed to
+type Subscription struct {
+	ID      string // stream ID, "feed/..."
+	Title   string
+	URL     string
+	HTMLURL string
+}
+
+// Subscriptions lists the subscribed feeds
+func (c *Client) Subscriptions() ([]Subscription, error) {
+	var resp struct {
+		Subscriptions []subscription `json:"subscriptions"`
+	}
+	if err := c.get("subscription/list", nil, &resp); err != nil {
+		return nil, err
+	}
+	subs := make([]Subscription, 0, len(resp.Subscriptions))
+	for _, s := range resp.Subscriptions {
+		subs = append(subs, Subscription{ID: s.ID, Title: s.Title, URL: s.URL, HTMLURL: s.HTMLURL})
+	}
+	return subs, nil
+}
+
+// idPage and contentBatch are the number of IDs asked for per request and
+// the number of items fetched per request; servers cap both anyway
+const (
+	idPage       = 10000
+	contentBatch = 250
+)
+
+// ItemIDs returns the long IDs of the items in stream that are not in exclude
+// (if set), newest first; at most limit of them unless limit is 0
+func (c *Client) ItemIDs(stream, exclude string, limit int) ([]string, error) {
+	var ids []string
+	continuation := ""
+	for limit == 0 || len(ids) < limit {
+		n := idPage
+		if limit > 0 {
+			n = min(n, limit-len(ids))
+		}
+		params := url.Values{"s": {stream}, "n": {strconv.Itoa(n)}}
+		if exclude != "" {
+			params.Set("xt", exclude)
+		}
+		if continuation != "" {
+			params.Set("c", continuation)
+		}
+		var resp struct {
+			ItemRefs     []itemRef `json:"itemRefs"`
+			Continuation string    `json:"continuation"`
+		}
+		if err := c.get("stream/items/ids", params, &resp); err != nil {
+			return nil, err
+		}
+		for _, ref := range resp.ItemRefs {
+			if id, ok := normalizeItemID(ref.ID); ok {
+				ids = append(ids, id)
+			}
+		}
+		if resp.Continuation == "" || len(resp.ItemRefs) == 0 {
+			break
+		}
+		continuation = resp.Continuation
+	}
+	return ids, nil
+}
+
+// Items fetches the items with the given IDs. Items the server doesn't know
+// are left out.
+func (c *Client) Items(ids []string) ([]item, error) {
+	var items []item
+	for start := 0; start < len(ids); start += contentBatch {
+		batch := ids[start:min(start+contentBatch, len(ids))]
+		var resp struct {
+			Items []item `json:"items"`
+		}
+		form := url.Values{"i": batch, "output": {"json"}}
+		if err := c.post("stream/items/contents", form, &resp); err != nil {
+			return nil, err
+		}
+		items = append(items, resp.Items...)
+	}
+	return items, nil
+}
+
+// EditTag adds tag to (on) or removes it from the items
+func (c *Client) EditTag(ids []string, tag string, on bool) error {
+	form := url.Values{"i": ids}
+	if on {
+		form.Set("a", tag)
+	} else {
+		form.Set("r", tag)
+	}
+	return c.post("edit-tag", form, nil)
+}
+
+// normalizeItemID turns either form of an item ID into the long one
+func normalizeItemID(s string) (string, bool) {
+	if hexID, ok := strings.CutPrefix(s, itemIDPrefix); ok {
+		n, err := strconv.ParseUint(hexID, 16, 64)
+		if err != nil {
+			return "", false
+		}
+		return fmt.Sprintf("%s%016x", itemIDPrefix, n), true
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s%016x", itemIDPrefix, n), true
+}
@@ -0,0 +1,295 @@
+// Package greader implements the subset of the Google Reader API that mobile
+// RSS apps such as Reeder and NetNewsWire use to sync with FreshRSS or
+// Miniflux, so they can use `gorss serve` as their backend. Subscriptions are
+// the daemon's feeds and the read and starred marks are the same ones the
+// TUI uses.
+//
+// Apps log in at /accounts/ClientLogin with the configured username and
+// password and then call /reader/api/0/... with the returned token. Only JSON
+// output is supported. gorss has no folders, so there are no labels.
+//
+// The package also has the other side: Client and Syncer let gorss itself
+// sync with FreshRSS, Miniflux or another gorss (backend.type: greader).
+package greader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/JohanLi233/gorss/api"
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+// Patterns are the paths to mount the server at
+var Patterns = []string{"/accounts/", "/reader/"}
+
+const apiPrefix = "/reader/api/0/"
+
+// Server serves the Google Reader API
+type Server struct {
+	daemon   api.Daemon
+	fm       *feed.FeedManager
+	stateDir string
+	username string
+	password string
+	auth     string // token handed out by ClientLogin
+}
+
+// NewServer creates the server. Like the JSON API it loads the reading state
+// for every request, so marks made elsewhere are seen at once. It needs a
+// username and password: the API is meant to be reached from other machines.
+func NewServer(d api.Daemon, fm *feed.FeedManager, stateDir string, cfg config.GReaderConfig) (*Server, error) {
+	if cfg.Username == "" || cfg.Password == "" {
+		return nil, errors.New("serve.greader requires both username and password")
+	}
+	// The token is derived from the credentials, so it survives restarts and
+	// changing the password logs every app out
+	mac := hmac.New(sha256.New, []byte(cfg.Password))
+	mac.Write([]byte("gorss greader " + cfg.Username))
+	return &Server{
+		daemon:   d,
+		fm:       fm,
+		stateDir: stateDir,
+		username: cfg.Username,
+		password: cfg.Password,
+		auth:     cfg.Username + "/" + hex.EncodeToString(mac.Sum(nil)),
+	}, nil
+}
+
+// ServeHTTP dispatches a request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Path == "/accounts/ClientLogin" {
+		s.clientLogin(w, r)
+		return
+	}
+	method, ok := strings.CutPrefix(r.URL.Path, apiPrefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("Google-Bad-Token", "true")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Stream IDs in the path are escaped ("feed%2Fhttps%3A%2F%2F..."), so
+	// they are taken from the raw path and unescaped once
+	if strings.HasPrefix(method, "stream/contents") {
+		stream := strings.TrimPrefix(r.URL.EscapedPath(), apiPrefix+"stream/contents")
+		if unescaped, err := url.PathUnescape(strings.TrimPrefix(stream, "/")); err == nil {
+			stream = unescaped
+		}
+		s.streamContents(w, r, stream)
+		return
+	}
+	handlers := map[string]http.HandlerFunc{
+		"token":                  s.token,
+		"user-info":              s.userInfo,
+		"subscription/list":      s.subscriptionList,
+		"subscription/edit":      s.subscriptionEdit,
+		"subscription/quickadd":  s.quickAdd,
+		"tag/list":               s.tagList,
+		"unread-count":           s.unreadCount,
+		"stream/items/ids":       s.itemIDs,
+		"stream/items/contents":  s.itemContents,
+		"edit-tag":               s.editTag,
+		"mark-all-as-read":       s.markAllAsRead,
+		"preference/list":        s.empty("prefs"),
+		"preference/stream/list": s.empty("streamprefs"),
+	}
+	if h, ok := handlers[method]; ok {
+		h(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (s *Server) clientLogin(w http.ResponseWriter, r *http.Request) {
+	user := r.Form.Get("Email")
+	pass := r.Form.Get("Passwd")
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(s.username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(s.password)) == 1
+	if !userOK || !passOK {
+		http.Error(w, "Error=BadAuthentication", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%s\nLSID=%s\nAuth=%s\n", s.auth, s.auth, s.auth)
+}
+
+// authorized checks the "Authorization: GoogleLogin auth=<token>" header
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.auth)) == 1
+}
+
+// token returns the token for edits. Google Reader used it against CSRF,
+// which doesn't apply to header authentication, so it is not checked.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, s.auth)
+}
+
+func (s *Server) userInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"userId":        "1",
+		"userName":      s.username,
+		"userProfileId": "1",
+		"userEmail":     s.username,
+	})
+}
+
+// empty answers calls apps make but gorss has nothing for
+func (s *Server) empty(key string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string][]string{key: {}})
+	}
+}
+
+type subscription struct {
+	ID         string        `json:"id"`
+	Title      string        `json:"title"`
+	Categories []interface{} `json:"categories"`
+	URL        string        `json:"url"`
+	HTMLURL    string        `json:"htmlUrl"`
+	IconURL    string        `json:"iconUrl"`
+}
+
+func (s *Server) subscriptionList(w http.ResponseWriter, r *http.Request) {
+	subs := []subscription{}
+	for _, f := range s.daemon.Feeds() {
+		if f.URL == "" {
+			continue
+		}
+		subs = append(subs, subscription{ID: feedStream(f), Title: f.Name, Categories: []interface{}{}, URL: f.URL, HTMLURL: f.URL})
+	}
+	writeJSON(w, map[string]interface{}{"subscriptions": subs})
+}
+
+// subscriptionEdit subscribes (ac=subscribe, title t) or unsubscribes
+// (ac=unsubscribe) streams s; renaming and labels aren't supported and are
+// accepted without change
+func (s *Server) subscriptionEdit(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	for _, stream := range r.Form["s"] {
+		feedURL, ok := strings.CutPrefix(stream, "feed/")
+		if !ok {
+			http.Error(w, "not a feed: "+stream, http.StatusBadRequest)
+			return
+		}
+		switch r.Form.Get("ac") {
+		case "subscribe":
+			if _, err := s.subscribe(feedURL, r.Form.Get("t")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case "unsubscribe":
+			f, ok := s.feedByURL(feedURL)
+			if !ok {
+				http.Error(w, "not subscribed: "+feedURL, http.StatusNotFound)
+				return
+			}
+			if _, err := s.daemon.RemoveFeed(f.Name); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	writeOK(w)
+}
+
+func (s *Server) quickAdd(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	feedURL := strings.TrimPrefix(r.Form.Get("quickadd"), "feed/")
+	f, err := s.subscribe(feedURL, "")
+	if err != nil {
+		writeJSON(w, map[string]interface{}{"numResults": 0, "query": feedURL, "error": err.Error()})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"numResults": 1, "query": feedURL, "streamId": feedStream(f), "streamName": f.Name})
+}
+
+// subscribe adds a feed, named after its title or else its host; subscribing
+// to a URL twice is not an error
+func (s *Server) subscribe(feedURL, title string) (config.Feed, error) {
+	if f, ok := s.feedByURL(feedURL); ok {
+		return f, nil
+	}
+	u, err := url.Parse(feedURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return config.Feed{}, fmt.Errorf("not a feed URL: %q", feedURL)
+	}
+	base := strings.TrimSpace(title)
+	if base == "" {
+		base = strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	name := base
+	for i := 2; s.nameTaken(name); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	f := config.Feed{Name: name, URL: feedURL}
+	return f, s.daemon.AddFeed(f)
+}
+
+func (s *Server) nameTaken(name string) bool {
+	for _, f := range s.daemon.Feeds() {
+		if strings.EqualFold(f.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) feedByURL(u string) (config.Feed, bool) {
+	for _, f := range s.daemon.Feeds() {
+		if f.URL == u {
+			return f, true
+		}
+	}
+	return config.Feed{}, false
+}
+
+func (s *Server) tagList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{"tags": []map[string]string{
+		{"id": streamStarred},
+	}})
+}
+
+func requirePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+func writeOK(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
@@ -0,0 +1,407 @@
+package greader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JohanLi233/gorss/api"
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+const (
+	testUser     = "alice"
+	testPassword = "correct horse"
+)
+
+// fakeDaemon serves a fixed list of feeds
+type fakeDaemon struct {
+	feeds []config.Feed
+}
+
+func (d *fakeDaemon) Feeds() []config.Feed                            { return d.feeds }
+func (d *fakeDaemon) Status() api.Status                              { return api.Status{} }
+func (d *fakeDaemon) Refresh(feeds []config.Feed) []api.RefreshResult { return nil }
+
+func (d *fakeDaemon) AddFeed(f config.Feed) error {
+	d.feeds = append(d.feeds, f)
+	return nil
+}
+
+func (d *fakeDaemon) RemoveFeed(name string) (config.Feed, error) {
+	for i, f := range d.feeds {
+		if strings.EqualFold(f.Name, name) {
+			d.feeds = append(d.feeds[:i:i], d.feeds[i+1:]...)
+			return f, nil
+		}
+	}
+	return config.Feed{}, fmt.Errorf("no feed named %q", name)
+}
+
+var (
+	blog = config.Feed{Name: "Blog", URL: "https://example.com/feed.xml"}
+	news = config.Feed{Name: "News", URL: "https://news.example.org/rss?lang=en"}
+)
+
+// rss returns a feed document with one item per day from the given day of
+// May 2024 on
+func rss(prefix string, first, items int) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>t</title>`)
+	for i := 0; i < items; i++ {
+		day := time.Date(2024, 5, first+i, 12, 0, 0, 0, time.UTC)
+		fmt.Fprintf(&b, `<item><title>%s %d</title><link>https://example.com/%s/%d</link><guid>%s-%d</guid><pubDate>%s</pubDate></item>`,
+			prefix, i, prefix, i, prefix, i, day.Format(time.RFC1123Z))
+	}
+	b.WriteString(`</channel></rss>`)
+	return []byte(b.String())
+}
+
+// testServer is a greader server with articles from 1 to 3 May in Blog and
+// one from 10 May in News
+type testServer struct {
+	*httptest.Server
+	fm       *feed.FeedManager
+	stateDir string
+	auth     string      // token from ClientLogin
+	down     atomic.Bool // answer every request with 503
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	d := &fakeDaemon{feeds: []config.Feed{blog, news}}
+	fm := feed.NewFeedManager(d.feeds, t.TempDir())
+	for _, f := range []struct {
+		feed         config.Feed
+		first, items int
+	}{{blog, 1, 3}, {news, 10, 1}} {
+		if _, err := fm.MergePushed(f.feed, rss(f.feed.Name, f.first, f.items)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stateDir := t.TempDir()
+	s, err := NewServer(d, fm, stateDir, config.GReaderConfig{Username: testUser, Password: testPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	for _, pattern := range Patterns {
+		mux.Handle(pattern, s)
+	}
+	ts := &testServer{fm: fm, stateDir: stateDir}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ts.down.Load() {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	ts.auth = ts.login(t, testUser, testPassword)
+	if ts.auth == "" {
+		t.Fatal("login failed")
+	}
+	return ts
+}
+
+// login posts the credentials as a form, like Reeder and NetNewsWire, and
+// returns the Auth token, or "" if the login is refused
+func (ts *testServer) login(t *testing.T, user, pass string) string {
+	t.Helper()
+	resp, err := http.PostForm(ts.URL+"/accounts/ClientLogin", url.Values{"Email": {user}, "Passwd": {pass}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if auth, ok := strings.CutPrefix(line, "Auth="); ok {
+			return auth
+		}
+	}
+	t.Fatalf("no Auth in %q", body)
+	return ""
+}
+
+// call sends an authorized API request; rawPath is appended to the API
+// prefix as is, so it may hold escapes. A form makes it a POST.
+func (ts *testServer) call(t *testing.T, rawPath string, form url.Values) (int, []byte) {
+	t.Helper()
+	method, body := "GET", io.Reader(nil)
+	if form != nil {
+		form.Set("T", ts.auth)
+		method, body = "POST", strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, ts.URL+apiPrefix+rawPath, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Authorization", "GoogleLogin auth="+ts.auth)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+type streamResponse struct {
+	Items []struct {
+		ID         string   `json:"id"`
+		Title      string   `json:"title"`
+		Categories []string `json:"categories"`
+		Origin     struct {
+			StreamID string `json:"streamId"`
+		} `json:"origin"`
+	} `json:"items"`
+	ItemRefs []struct {
+		ID string `json:"id"`
+	} `json:"itemRefs"`
+	Continuation string `json:"continuation"`
+}
+
+func (ts *testServer) stream(t *testing.T, rawPath string) streamResponse {
+	t.Helper()
+	status, data := ts.call(t, rawPath, nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", rawPath, status, data)
+	}
+	var resp streamResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("GET %s: %v", rawPath, err)
+	}
+	return resp
+}
+
+// article returns the ID of the article with the given title
+func (ts *testServer) article(t *testing.T, title string) string {
+	t.Helper()
+	for _, a := range ts.fm.GetArticles() {
+		if a.Title == title {
+			return a.ID
+		}
+	}
+	t.Fatalf("no article %q", title)
+	return ""
+}
+
+func (ts *testServer) state(t *testing.T) *feed.State {
+	t.Helper()
+	state, _, err := feed.LoadState(ts.stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestNewServerNeedsCredentials(t *testing.T) {
+	for _, cfg := range []config.GReaderConfig{
+		{Listen: ":8088"},
+		{Listen: ":8088", Username: testUser},
+		{Listen: ":8088", Password: testPassword},
+	} {
+		if _, err := NewServer(&fakeDaemon{}, nil, t.TempDir(), cfg); err == nil {
+			t.Errorf("NewServer(%+v) succeeded", cfg)
+		}
+	}
+}
+
+func TestClientLogin(t *testing.T) {
+	ts := newTestServer(t)
+	if !strings.HasPrefix(ts.auth, testUser+"/") {
+		t.Errorf("token %q doesn't name the user", ts.auth)
+	}
+	if auth := ts.login(t, testUser, testPassword); auth != ts.auth {
+		t.Errorf("second login gave token %q, want %q", auth, ts.auth)
+	}
+	for _, creds := range [][2]string{{testUser, "wrong"}, {"bob", testPassword}, {testUser, ""}, {"", ""}} {
+		if auth := ts.login(t, creds[0], creds[1]); auth != "" {
+			t.Errorf("login as %q with %q succeeded", creds[0], creds[1])
+		}
+	}
+}
+
+func TestAuthHeader(t *testing.T) {
+	ts := newTestServer(t)
+	for _, header := range []string{"", "GoogleLogin auth=" + ts.auth + "x", "Bearer " + ts.auth, ts.auth} {
+		req, _ := http.NewRequest("GET", ts.URL+apiPrefix+"user-info?output=json", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status %d, want 401", header, resp.StatusCode)
+		}
+		if resp.Header.Get("Google-Bad-Token") != "true" {
+			t.Errorf("Authorization %q: no Google-Bad-Token header", header)
+		}
+	}
+	if status, data := ts.call(t, "user-info?output=json", nil); status != http.StatusOK || !strings.Contains(string(data), testUser) {
+		t.Errorf("user-info: %d %s", status, data)
+	}
+}
+
+func TestStreamContentsPath(t *testing.T) {
+	ts := newTestServer(t)
+	tests := []struct {
+		name    string
+		rawPath string
+		titles  []string
+	}{
+		// Reeder escapes the whole stream ID into the path
+		{"escaped feed", "stream/contents/" + url.PathEscape(feedStream(news)) + "?output=json", []string{"News 0"}},
+		// NetNewsWire and others use url.QueryEscape, which escapes ":" and "/" too
+		{"query escaped feed", "stream/contents/" + url.QueryEscape(feedStream(blog)) + "?n=10", []string{"Blog 2", "Blog 1", "Blog 0"}},
+		{"escaped state with user ID", "stream/contents/user%2F1000%2Fstate%2Fcom.google%2Freading-list?r=o", []string{"Blog 0", "Blog 1", "Blog 2", "News 0"}},
+		{"unescaped state", "stream/contents/user/-/state/com.google/reading-list?n=1", []string{"News 0"}},
+		{"stream in s", "stream/contents?s=" + url.QueryEscape(feedStream(news)), []string{"News 0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := ts.stream(t, tt.rawPath)
+			var titles []string
+			for _, it := range resp.Items {
+				titles = append(titles, it.Title)
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.titles, ",") {
+				t.Errorf("titles = %v, want %v", titles, tt.titles)
+			}
+		})
+	}
+
+	// The query string of a feed URL survives the unescaping
+	resp := ts.stream(t, "stream/contents/"+url.PathEscape(feedStream(news)))
+	if len(resp.Items) != 1 || resp.Items[0].Origin.StreamID != feedStream(news) {
+		t.Errorf("origin of %s items: %+v", news.URL, resp.Items)
+	}
+}
+
+func TestContinuation(t *testing.T) {
+	ts := newTestServer(t)
+	for _, method := range []string{"stream/contents/" + url.PathEscape(streamReadingList) + "?", "stream/items/ids?s=" + url.QueryEscape(streamReadingList) + "&"} {
+		var seen []string
+		c := ""
+		for page := 0; ; page++ {
+			if page > 3 {
+				t.Fatalf("%s: continuation never ends", method)
+			}
+			resp := ts.stream(t, method+"n=3&c="+c)
+			for _, it := range resp.Items {
+				seen = append(seen, it.ID)
+			}
+			for _, ref := range resp.ItemRefs {
+				seen = append(seen, ref.ID)
+			}
+			if resp.Continuation == "" {
+				break
+			}
+			c = resp.Continuation
+		}
+		if len(seen) != 4 {
+			t.Errorf("%s: %d items over all pages, want 4: %v", method, len(seen), seen)
+		}
+		unique := make(map[string]bool)
+		for _, id := range seen {
+			unique[id] = true
+		}
+		if len(unique) != len(seen) {
+			t.Errorf("%s: pages overlap: %v", method, seen)
+		}
+	}
+}
+
+func TestEditTag(t *testing.T) {
+	ts := newTestServer(t)
+	blog0 := ts.article(t, "Blog 0")
+	blog1 := ts.article(t, "Blog 1")
+
+	edit := func(form url.Values) {
+		t.Helper()
+		if status, data := ts.call(t, "edit-tag", form); status != http.StatusOK || string(data) != "OK" {
+			t.Fatalf("edit-tag %v: %d %s", form, status, data)
+		}
+	}
+
+	// Reeder sends long item IDs, NetNewsWire the decimal ones from
+	// stream/items/ids, each as its own i
+	edit(url.Values{"i": {longID(blog0), shortID(blog1)}, "a": {streamRead}})
+	if s := ts.state(t); !s.IsRead(blog0) || !s.IsRead(blog1) {
+		t.Errorf("after a=read: read %v %v, want both", s.IsRead(blog0), s.IsRead(blog1))
+	}
+	edit(url.Values{"i": {longID(blog0)}, "r": {streamRead}})
+	if s := ts.state(t); s.IsRead(blog0) || !s.IsRead(blog1) {
+		t.Errorf("after r=read: read %v %v, want only the second", s.IsRead(blog0), s.IsRead(blog1))
+	}
+
+	// Marking unread the way older clients do
+	edit(url.Values{"i": {shortID(blog1)}, "a": {"user/-/state/com.google/kept-unread"}, "r": {streamRead}})
+	if ts.state(t).IsRead(blog1) {
+		t.Error("still read after a=kept-unread")
+	}
+
+	// A user ID instead of "-" is the same stream
+	edit(url.Values{"i": {longID(blog0)}, "a": {"user/1000/state/com.google/starred"}})
+	if !ts.state(t).IsStarred(blog0) {
+		t.Error("not starred after a=starred")
+	}
+	resp := ts.stream(t, "stream/contents/"+url.PathEscape(streamStarred))
+	if len(resp.Items) != 1 || resp.Items[0].ID != longID(blog0) {
+		t.Errorf("starred stream: %+v", resp.Items)
+	}
+	edit(url.Values{"i": {longID(blog0)}, "r": {streamStarred}})
+	if ts.state(t).IsStarred(blog0) {
+		t.Error("still starred after r=starred")
+	}
+
+	if status, _ := ts.call(t, "edit-tag?i="+shortID(blog0)+"&a="+url.QueryEscape(streamRead), nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("GET edit-tag: status %d, want 405", status)
+	}
+}
+
+func TestMarkAllAsRead(t *testing.T) {
+	ts := newTestServer(t)
+
+	// Everything in Blog up to 2 May, the day of "Blog 1"
+	ts2May := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC).UnixMicro()
+	form := url.Values{"s": {feedStream(blog)}, "ts": {fmt.Sprint(ts2May)}}
+	if status, data := ts.call(t, "mark-all-as-read", form); status != http.StatusOK {
+		t.Fatalf("mark-all-as-read: %d %s", status, data)
+	}
+	s := ts.state(t)
+	for title, want := range map[string]bool{"Blog 0": true, "Blog 1": true, "Blog 2": false, "News 0": false} {
+		if got := s.IsRead(ts.article(t, title)); got != want {
+			t.Errorf("%s read = %v, want %v", title, got, want)
+		}
+	}
+
+	// Without ts the whole stream is marked
+	if status, data := ts.call(t, "mark-all-as-read", url.Values{"s": {streamReadingList}}); status != http.StatusOK {
+		t.Fatalf("mark-all-as-read: %d %s", status, data)
+	}
+	resp := ts.stream(t, "stream/items/ids?s="+url.QueryEscape(streamReadingList)+"&xt="+url.QueryEscape(streamRead))
+	if len(resp.ItemRefs) != 0 {
+		t.Errorf("%d items unread after marking the reading list", len(resp.ItemRefs))
+	}
+}
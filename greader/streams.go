@@ -0,0 +1,457 @@
+package greader
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/export"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+// Streams are article sets: the states below, or "feed/<url>"
+const (
+	streamReadingList = "user/-/state/com.google/reading-list"
+	streamRead        = "user/-/state/com.google/read"
+	streamStarred     = "user/-/state/com.google/starred"
+	streamKeptUnread  = "user/-/state/com.google/kept-unread"
+)
+
+const itemIDPrefix = "tag:google.com,2005:reader/item/"
+
+func feedStream(f config.Feed) string {
+	return "feed/" + f.URL
+}
+
+// normalizeStream replaces the user ID in "user/1234/state/..." with "-"
+func normalizeStream(stream string) string {
+	if rest, ok := strings.CutPrefix(stream, "user/"); ok {
+		if i := strings.Index(rest, "/"); i >= 0 {
+			return "user/-" + rest[i:]
+		}
+	}
+	return stream
+}
+
+// Items have 64-bit IDs, written as 16 hex digits in the long form and in
+// decimal in the short one. Article IDs are 10 hex digits, so they convert
+// both ways.
+
+func longID(id string) string {
+	return itemIDPrefix + strings.Repeat("0", 16-len(id)) + id
+}
+
+func shortID(id string) string {
+	n, _ := strconv.ParseUint(id, 16, 64)
+	return strconv.FormatUint(n, 10)
+}
+
+// parseItemID turns either form back into an article ID
+func parseItemID(s string) (string, bool) {
+	var n uint64
+	var err error
+	if hexID, ok := strings.CutPrefix(s, itemIDPrefix); ok {
+		n, err = strconv.ParseUint(hexID, 16, 64)
+	} else {
+		n, err = strconv.ParseUint(s, 10, 64)
+	}
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%010x", n), true
+}
+
+func usec(t time.Time) string {
+	return strconv.FormatInt(t.UnixMicro(), 10)
+}
+
+// view is the state a request works with: the articles, the reading state
+// and the feeds they came from
+type view struct {
+	articles []feed.Article
+	state    *feed.State
+	feeds    map[string]config.Feed // by lower-case name
+}
+
+func (s *Server) view() (*view, error) {
+	state, _, err := feed.LoadState(s.stateDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load read state: %w", err)
+	}
+	v := &view{state: state, feeds: make(map[string]config.Feed)}
+	for _, f := range s.daemon.Feeds() {
+		v.feeds[strings.ToLower(f.Name)] = f
+	}
+	// Only articles of current subscriptions, so unsubscribing removes them
+	for _, a := range s.fm.GetArticles() {
+		if _, ok := v.feed(a); ok {
+			v.articles = append(v.articles, a)
+		}
+	}
+	return v, nil
+}
+
+func (v *view) feed(a feed.Article) (config.Feed, bool) {
+	name := a.Source
+	if name == "" {
+		name = a.FeedName
+	}
+	f, ok := v.feeds[strings.ToLower(name)]
+	return f, ok
+}
+
+// in reports whether an article belongs to a stream
+func (v *view) in(a feed.Article, stream string) bool {
+	switch normalizeStream(stream) {
+	case streamReadingList:
+		return true
+	case streamRead:
+		return v.state.IsRead(a.ID)
+	case streamKeptUnread:
+		return !v.state.IsRead(a.ID)
+	case streamStarred:
+		return v.state.IsStarred(a.ID)
+	}
+	if u, ok := strings.CutPrefix(stream, "feed/"); ok {
+		f, ok := v.feed(a)
+		return ok && f.URL == u
+	}
+	// Labels and anything else: gorss has no folders
+	return false
+}
+
+// query selects the articles of a stream with the usual parameters: n
+// (count), xt (exclude stream), it (include stream), ot and nt (oldest and
+// newest time in seconds), r=o (oldest first) and c (continuation)
+func (v *view) query(form url.Values, stream string) ([]feed.Article, string) {
+	if stream == "" {
+		stream = streamReadingList
+	}
+	var oldest, newest time.Time
+	if ot, err := strconv.ParseInt(form.Get("ot"), 10, 64); err == nil && ot > 0 {
+		oldest = time.Unix(ot, 0)
+	}
+	if nt, err := strconv.ParseInt(form.Get("nt"), 10, 64); err == nil && nt > 0 {
+		newest = time.Unix(nt, 0)
+	}
+
+	var out []feed.Article
+	for _, a := range v.articles {
+		if !v.in(a, stream) {
+			continue
+		}
+		if xt := form.Get("xt"); xt != "" && v.in(a, xt) {
+			continue
+		}
+		if it := form.Get("it"); it != "" && !v.in(a, it) {
+			continue
+		}
+		if !oldest.IsZero() && a.Published.Before(oldest) {
+			continue
+		}
+		if !newest.IsZero() && a.Published.After(newest) {
+			continue
+		}
+		out = append(out, a)
+	}
+	oldestFirst := form.Get("r") == "o"
+	sort.SliceStable(out, func(i, j int) bool {
+		if oldestFirst {
+			return out[i].Published.Before(out[j].Published)
+		}
+		return out[i].Published.After(out[j].Published)
+	})
+
+	offset, _ := strconv.Atoi(form.Get("c"))
+	if offset < 0 || offset > len(out) {
+		offset = len(out)
+	}
+	out = out[offset:]
+	n, err := strconv.Atoi(form.Get("n"))
+	if err != nil || n <= 0 {
+		n = 20
+	}
+	if n > 10000 {
+		n = 10000
+	}
+	continuation := ""
+	if len(out) > n {
+		out = out[:n]
+		continuation = strconv.Itoa(offset + n)
+	}
+	return out, continuation
+}
+
+type itemRef struct {
+	ID              string   `json:"id"`
+	DirectStreamIDs []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+func (s *Server) itemIDs(w http.ResponseWriter, r *http.Request) {
+	v, err := s.view()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	articles, continuation := v.query(r.Form, r.Form.Get("s"))
+	refs := make([]itemRef, 0, len(articles))
+	for _, a := range articles {
+		refs = append(refs, itemRef{ID: shortID(a.ID), DirectStreamIDs: []string{}, TimestampUsec: usec(a.Published)})
+	}
+	resp := map[string]interface{}{"itemRefs": refs}
+	if continuation != "" {
+		resp["continuation"] = continuation
+	}
+	writeJSON(w, resp)
+}
+
+type link struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type content struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type origin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HTMLURL  string `json:"htmlUrl"`
+}
+
+type enclosure struct {
+	Href   string `json:"href"`
+	Type   string `json:"type,omitempty"`
+	Length string `json:"length,omitempty"`
+}
+
+type item struct {
+	ID            string      `json:"id"`
+	CrawlTimeMsec string      `json:"crawlTimeMsec"`
+	TimestampUsec string      `json:"timestampUsec"`
+	Published     int64       `json:"published"`
+	Updated       int64       `json:"updated"`
+	Title         string      `json:"title"`
+	Author        string      `json:"author,omitempty"`
+	Canonical     []link      `json:"canonical"`
+	Alternate     []link      `json:"alternate"`
+	Categories    []string    `json:"categories"`
+	Origin        origin      `json:"origin"`
+	Summary       content     `json:"summary"`
+	Content       *content    `json:"content,omitempty"` // some servers send the body here instead of in summary
+	Enclosure     []enclosure `json:"enclosure,omitempty"`
+}
+
+func (v *view) item(a feed.Article) item {
+	it := item{
+		ID:            longID(a.ID),
+		CrawlTimeMsec: strconv.FormatInt(a.Published.UnixMilli(), 10),
+		TimestampUsec: usec(a.Published),
+		Published:     a.Published.Unix(),
+		Updated:       a.Published.Unix(),
+		Title:         a.Title,
+		Author:        strings.Join(a.Authors, ", "),
+		Canonical:     []link{},
+		Alternate:     []link{},
+		Categories:    []string{streamReadingList},
+		Summary:       content{Direction: "ltr", Content: export.CleanHTML(a)},
+	}
+	if !a.Updated.IsZero() {
+		it.Updated = a.Updated.Unix()
+	}
+	if a.Link != "" {
+		it.Canonical = []link{{Href: a.Link}}
+		it.Alternate = []link{{Href: a.Link, Type: "text/html"}}
+	}
+	if v.state.IsRead(a.ID) {
+		it.Categories = append(it.Categories, streamRead)
+	}
+	if v.state.IsStarred(a.ID) {
+		it.Categories = append(it.Categories, streamStarred)
+	}
+	if f, ok := v.feed(a); ok {
+		it.Origin = origin{StreamID: feedStream(f), Title: f.Name, HTMLURL: f.URL}
+	}
+	for _, e := range a.Enclosures {
+		enc := enclosure{Href: e.URL, Type: e.Type}
+		if e.Length > 0 {
+			enc.Length = strconv.FormatInt(e.Length, 10)
+		}
+		it.Enclosure = append(it.Enclosure, enc)
+	}
+	return it
+}
+
+func (v *view) writeItems(w http.ResponseWriter, id string, articles []feed.Article, continuation string) {
+	items := make([]item, 0, len(articles))
+	for _, a := range articles {
+		items = append(items, v.item(a))
+	}
+	resp := map[string]interface{}{
+		"id":      id,
+		"updated": time.Now().Unix(),
+		"items":   items,
+	}
+	if continuation != "" {
+		resp["continuation"] = continuation
+	}
+	writeJSON(w, resp)
+}
+
+// itemContents returns the items with the IDs in i
+func (s *Server) itemContents(w http.ResponseWriter, r *http.Request) {
+	v, err := s.view()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[string]feed.Article, len(v.articles))
+	for _, a := range v.articles {
+		byID[a.ID] = a
+	}
+	var articles []feed.Article
+	for _, i := range r.Form["i"] {
+		if id, ok := parseItemID(i); ok {
+			if a, ok := byID[id]; ok {
+				articles = append(articles, a)
+			}
+		}
+	}
+	v.writeItems(w, streamReadingList, articles, "")
+}
+
+func (s *Server) streamContents(w http.ResponseWriter, r *http.Request, stream string) {
+	if stream == "" {
+		stream = r.Form.Get("s")
+	}
+	v, err := s.view()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	articles, continuation := v.query(r.Form, stream)
+	v.writeItems(w, stream, articles, continuation)
+}
+
+func (s *Server) unreadCount(w http.ResponseWriter, r *http.Request) {
+	v, err := s.view()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	type count struct {
+		ID                      string    `json:"id"`
+		Count                   int       `json:"count"`
+		NewestItemTimestampUsec string    `json:"newestItemTimestampUsec"`
+		newest                  time.Time // of the unread items
+	}
+	counts := make(map[string]*count)
+	var order []string
+	add := func(stream string, a feed.Article) {
+		c, ok := counts[stream]
+		if !ok {
+			c = &count{ID: stream}
+			counts[stream] = c
+			order = append(order, stream)
+		}
+		c.Count++
+		if a.Published.After(c.newest) {
+			c.newest = a.Published
+		}
+	}
+	for _, a := range v.articles {
+		if v.state.IsRead(a.ID) {
+			continue
+		}
+		add(streamReadingList, a)
+		if f, ok := v.feed(a); ok {
+			add(feedStream(f), a)
+		}
+	}
+	out := make([]count, 0, len(order))
+	maxCount := 0
+	for _, stream := range order {
+		counts[stream].NewestItemTimestampUsec = usec(counts[stream].newest)
+		out = append(out, *counts[stream])
+		if counts[stream].Count > maxCount {
+			maxCount = counts[stream].Count
+		}
+	}
+	writeJSON(w, map[string]interface{}{"max": maxCount, "unreadcounts": out})
+}
+
+// editTag adds (a) or removes (r) the read and starred states of items i
+func (s *Server) editTag(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	v, err := s.view()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	apply := func(tags []string, on bool) {
+		for _, tag := range tags {
+			for _, i := range r.Form["i"] {
+				id, ok := parseItemID(i)
+				if !ok {
+					continue
+				}
+				switch normalizeStream(tag) {
+				case streamRead:
+					v.state.SetRead(id, on)
+				case streamKeptUnread:
+					v.state.SetRead(id, !on)
+				case streamStarred:
+					v.state.SetStarred(id, on)
+				}
+			}
+		}
+	}
+	apply(r.Form["a"], true)
+	apply(r.Form["r"], false)
+	if err := v.state.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeOK(w)
+}
+
+// markAllAsRead marks the items of stream s read, up to time ts in
+// microseconds if given
+func (s *Server) markAllAsRead(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	v, err := s.view()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var until time.Time
+	if ts, err := strconv.ParseInt(r.Form.Get("ts"), 10, 64); err == nil && ts > 0 {
+		until = time.UnixMicro(ts)
+	}
+	stream := r.Form.Get("s")
+	if stream == "" {
+		stream = streamReadingList
+	}
+	for _, a := range v.articles {
+		if v.in(a, stream) && (until.IsZero() || !a.Published.After(until)) {
+			v.state.SetRead(a.ID, true)
+		}
+	}
+	if err := v.state.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeOK(w)
+}
@@ -0,0 +1,426 @@
+package greader
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+// Syncer mirrors a Google Reader compatible server (backend.type: greader):
+// the server fetches the feeds, gorss pulls its subscriptions, articles and
+// read and starred marks and pushes back the marks changed here.
+//
+// Marks changed while the server is unreachable stay queued: each sync
+// records the marks both sides agreed on, so whatever differs from that
+// record locally is a change still to be pushed. Changes are pushed before
+// the server's marks are pulled, so when an article was changed on both
+// sides since the last sync the local change wins; every other article takes
+// the server's marks.
+type Syncer struct {
+	client   *Client
+	server   string
+	username string
+	limit    int
+	fm       *feed.FeedManager
+	paths    config.Paths
+
+	mu sync.Mutex // one sync at a time
+}
+
+// SyncResult describes what a sync did
+type SyncResult struct {
+	Feeds    []config.Feed // subscriptions on the server
+	New      int           // articles not seen before
+	Pushed   int           // local changes sent to the server
+	Dropped  int           // local changes the server refused; its marks apply instead
+	Pulled   int           // marks changed on the server and applied here
+	Warnings []string
+}
+
+// NewSyncer creates a syncer for the configured backend
+func NewSyncer(cfg config.BackendConfig, fm *feed.FeedManager, paths config.Paths) *Syncer {
+	return &Syncer{
+		client:   NewClient(cfg.URL, cfg.Username, cfg.Password),
+		server:   strings.TrimRight(cfg.URL, "/"),
+		username: cfg.Username,
+		limit:    cfg.LimitOrDefault(),
+		fm:       fm,
+		paths:    paths,
+	}
+}
+
+// remoteFeed is a subscription as saved between syncs
+type remoteFeed struct {
+	StreamID string `json:"stream_id"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+}
+
+func feedsPath(cacheDir string) string {
+	return filepath.Join(cacheDir, "greader_feeds.json")
+}
+
+func loadRemoteFeeds(cacheDir string) []remoteFeed {
+	var feeds []remoteFeed
+	_, _ = feed.LoadJSON(feedsPath(cacheDir), &feeds)
+	return feeds
+}
+
+// Feeds returns the feeds of cfg: the configured ones, or with the greader
+// backend the subscriptions found by the last sync, so the feed list is there
+// before the first sync of a session or without network
+func Feeds(cfg *config.Config) []config.Feed {
+	if !cfg.Backend.Remote() {
+		return cfg.Feeds
+	}
+	var feeds []config.Feed
+	for _, f := range loadRemoteFeeds(cfg.Paths.CacheDir) {
+		feeds = append(feeds, config.Feed{Name: f.Name, URL: f.URL})
+	}
+	return feeds
+}
+
+// marks are the read and starred marks of an item
+type marks struct {
+	Read    bool `json:"read,omitempty"`
+	Starred bool `json:"starred,omitempty"`
+}
+
+// syncBase is what both sides agreed on at the end of the last sync
+type syncBase struct {
+	Server string           `json:"server"`
+	User   string           `json:"user"`
+	Synced time.Time        `json:"synced"`
+	Items  map[string]marks `json:"items"` // by long item ID
+}
+
+func (s *Syncer) basePath() string {
+	return filepath.Join(s.paths.StateDir, "greader_sync.json")
+}
+
+// loadBase reads the record of the last sync. A record for another server or
+// account doesn't count, so the first sync with a new account pushes nothing.
+func (s *Syncer) loadBase() (syncBase, string) {
+	var base syncBase
+	warning, err := feed.LoadJSON(s.basePath(), &base)
+	if err != nil {
+		warning = fmt.Sprintf("failed to read %s: %v", filepath.Base(s.basePath()), err)
+	}
+	if base.Server != s.server || base.User != s.username || base.Items == nil {
+		base = syncBase{Items: make(map[string]marks)}
+	}
+	return base, warning
+}
+
+// remoteArticles returns the articles pulled from the server, by item ID
+func (s *Syncer) remoteArticles() map[string]feed.Article {
+	out := make(map[string]feed.Article)
+	for _, a := range s.fm.GetArticles() {
+		if strings.HasPrefix(a.GUID, itemIDPrefix) {
+			out[a.GUID] = a
+		}
+	}
+	return out
+}
+
+// Pending counts the local changes not yet pushed to the server
+func (s *Syncer) Pending() (int, error) {
+	state, _, err := feed.LoadState(s.paths.StateDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load read state: %w", err)
+	}
+	base, _ := s.loadBase()
+	n := 0
+	for id, a := range s.remoteArticles() {
+		if was, ok := base.Items[id]; ok {
+			if state.IsRead(a.ID) != was.Read {
+				n++
+			}
+			if state.IsStarred(a.ID) != was.Starred {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+// Sync pushes local changes, then pulls subscriptions, articles and marks. If
+// it fails, unpushed changes stay queued for the next sync.
+func (s *Syncer) Sync() (SyncResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res SyncResult
+	state, warning, err := feed.LoadState(s.paths.StateDir)
+	if err != nil {
+		return res, fmt.Errorf("failed to load read state: %w", err)
+	}
+	if warning != "" {
+		res.Warnings = append(res.Warnings, warning)
+	}
+	base, warning := s.loadBase()
+	if warning != "" {
+		res.Warnings = append(res.Warnings, warning)
+	}
+
+	if err := s.push(state, base, &res); err != nil {
+		return res, fmt.Errorf("sync: %w", err)
+	}
+
+	feeds, err := s.pullFeeds()
+	if err != nil {
+		return res, fmt.Errorf("sync: %w", err)
+	}
+	unread, err := s.client.ItemIDs(streamReadingList, streamRead, 0)
+	if err != nil {
+		return res, fmt.Errorf("sync: %w", err)
+	}
+	starred, err := s.client.ItemIDs(streamStarred, "", 0)
+	if err != nil {
+		return res, fmt.Errorf("sync: %w", err)
+	}
+	recent, err := s.client.ItemIDs(streamReadingList, "", s.limit)
+	if err != nil {
+		return res, fmt.Errorf("sync: %w", err)
+	}
+	articles, err := s.pullArticles(feeds, recent, unread, starred)
+	if err != nil {
+		return res, fmt.Errorf("sync: %w", err)
+	}
+	res.New = s.fm.ReplaceArticles(articles)
+
+	// Take the server's marks and record them as agreed
+	isUnread := make(map[string]bool, len(unread))
+	for _, id := range unread {
+		isUnread[id] = true
+	}
+	isStarred := make(map[string]bool, len(starred))
+	for _, id := range starred {
+		isStarred[id] = true
+	}
+	next := syncBase{Server: s.server, User: s.username, Synced: time.Now(), Items: make(map[string]marks)}
+	for _, a := range s.fm.GetArticles() {
+		m := marks{Read: !isUnread[a.GUID], Starred: isStarred[a.GUID]}
+		if state.IsRead(a.ID) != m.Read {
+			state.SetRead(a.ID, m.Read)
+			res.Pulled++
+		}
+		if state.IsStarred(a.ID) != m.Starred {
+			state.SetStarred(a.ID, m.Starred)
+			res.Pulled++
+		}
+		next.Items[a.GUID] = m
+	}
+	if err := state.Save(); err != nil {
+		return res, fmt.Errorf("failed to save read state: %w", err)
+	}
+	if err := feed.SaveJSON(s.basePath(), next); err != nil {
+		return res, fmt.Errorf("failed to save sync state: %w", err)
+	}
+
+	for _, f := range feeds {
+		res.Feeds = append(res.Feeds, config.Feed{Name: f.Name, URL: f.URL})
+	}
+	return res, nil
+}
+
+// editBatch is the number of items changed per edit-tag request
+const editBatch = 100
+
+// push sends the marks changed locally since the last sync. Changes the server
+// refuses are dropped; any other failure stops the sync with the rest queued.
+func (s *Syncer) push(state *feed.State, base syncBase, res *SyncResult) error {
+	type change struct {
+		tag string
+		on  bool
+	}
+	changes := []change{{streamRead, true}, {streamRead, false}, {streamStarred, true}, {streamStarred, false}}
+	pending := make(map[change][]string)
+	for id, a := range s.remoteArticles() {
+		was, ok := base.Items[id]
+		if !ok {
+			continue // never synced, so the server's marks apply
+		}
+		if read := state.IsRead(a.ID); read != was.Read {
+			pending[change{streamRead, read}] = append(pending[change{streamRead, read}], id)
+		}
+		if starred := state.IsStarred(a.ID); starred != was.Starred {
+			pending[change{streamStarred, starred}] = append(pending[change{streamStarred, starred}], id)
+		}
+	}
+
+	for _, c := range changes {
+		ids := pending[c]
+		for start := 0; start < len(ids); start += editBatch {
+			batch := ids[start:min(start+editBatch, len(ids))]
+			err := s.client.EditTag(batch, c.tag, c.on)
+			if Rejected(err) {
+				res.Dropped += len(batch)
+				res.Warnings = append(res.Warnings, fmt.Sprintf("server refused %d changes, keeping its marks: %v", len(batch), err))
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			res.Pushed += len(batch)
+		}
+	}
+	return nil
+}
+
+// pullFeeds fetches the subscriptions and names them. A subscription keeps
+// the name it was given before, so renaming it on the server doesn't orphan
+// its marks; new ones are named after their title or else their host.
+func (s *Syncer) pullFeeds() ([]remoteFeed, error) {
+	subs, err := s.client.Subscriptions()
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[string]string)
+	for _, f := range loadRemoteFeeds(s.paths.CacheDir) {
+		previous[f.StreamID] = f.Name
+	}
+
+	taken := map[string]bool{"all": true} // reserved for the combined list
+	feeds := make([]remoteFeed, 0, len(subs))
+	name := func(base string) string {
+		n := base
+		for i := 2; taken[strings.ToLower(n)]; i++ {
+			n = fmt.Sprintf("%s-%d", base, i)
+		}
+		taken[strings.ToLower(n)] = true
+		return n
+	}
+	for _, sub := range subs {
+		feedURL := sub.URL
+		if feedURL == "" {
+			feedURL = strings.TrimPrefix(sub.ID, "feed/")
+		}
+		base := previous[sub.ID]
+		if base == "" {
+			base = strings.TrimSpace(sub.Title)
+		}
+		if base == "" {
+			if u, err := url.Parse(feedURL); err == nil && u.Host != "" {
+				base = strings.TrimPrefix(u.Hostname(), "www.")
+			} else {
+				base = sub.ID
+			}
+		}
+		feeds = append(feeds, remoteFeed{StreamID: sub.ID, Name: name(base), URL: feedURL})
+	}
+	if err := feed.SaveJSON(feedsPath(s.paths.CacheDir), feeds); err != nil {
+		return nil, fmt.Errorf("failed to save subscriptions: %w", err)
+	}
+	return feeds, nil
+}
+
+// pullArticles returns the articles to keep: the newest ones, up to the
+// limit, plus unread and starred ones. Items already pulled are not
+// fetched again.
+func (s *Syncer) pullArticles(feeds []remoteFeed, recent, unread, starred []string) ([]feed.Article, error) {
+	names := make(map[string]string, len(feeds))
+	subscribed := make(map[string]bool, len(feeds))
+	for _, f := range feeds {
+		names[f.StreamID] = f.Name
+		subscribed[f.Name] = true
+	}
+	cached := s.remoteArticles()
+
+	var wanted, missing []string
+	seen := make(map[string]bool)
+	add := func(ids []string, limit int) {
+		n := 0
+		for _, id := range ids {
+			if n == limit {
+				return
+			}
+			n++
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			wanted = append(wanted, id)
+			if _, ok := cached[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+	}
+	add(recent, s.limit)
+	add(unread, s.limit)
+	add(starred, -1)
+
+	items, err := s.client.Items(missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range items {
+		id, ok := normalizeItemID(it.ID)
+		if !ok {
+			continue
+		}
+		name, ok := names[it.Origin.StreamID]
+		if !ok {
+			continue // from a feed no longer subscribed
+		}
+		cached[id] = article(it, id, name)
+	}
+
+	articles := make([]feed.Article, 0, len(wanted))
+	for _, id := range wanted {
+		if a, ok := cached[id]; ok && subscribed[a.Source] {
+			articles = append(articles, a)
+		}
+	}
+	return articles, nil
+}
+
+// article converts an item of feed name to an article. The item ID is the
+// GUID, so the article ID stays the same across syncs.
+func article(it item, id, name string) feed.Article {
+	body := it.Summary.Content
+	if it.Content != nil && it.Content.Content != "" {
+		body = it.Content.Content
+	}
+	a := feed.Article{
+		Title:     it.Title,
+		Content:   body,
+		Published: time.Unix(it.Published, 0),
+		FeedName:  name,
+		GUID:      id,
+		Source:    name,
+	}
+	if it.Published == 0 {
+		a.Published = time.Now()
+	}
+	if it.Updated > it.Published {
+		a.Updated = time.Unix(it.Updated, 0)
+	}
+	for _, links := range [][]link{it.Canonical, it.Alternate} {
+		if len(links) > 0 && a.Link == "" {
+			a.Link = links[0].Href
+		}
+	}
+	if it.Author != "" {
+		a.Authors = []string{it.Author}
+	}
+	for _, c := range it.Categories {
+		if label, ok := strings.CutPrefix(normalizeStream(c), "user/-/label/"); ok {
+			a.Categories = append(a.Categories, label)
+		} else if !strings.HasPrefix(c, "user/") {
+			a.Categories = append(a.Categories, c)
+		}
+	}
+	for _, e := range it.Enclosure {
+		length, _ := strconv.ParseInt(e.Length, 10, 64)
+		a.Enclosures = append(a.Enclosures, feed.Enclosure{URL: e.Href, Type: e.Type, Length: length})
+	}
+	return a
+}
@@ -0,0 +1,171 @@
+package greader
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+// testClient is a gorss profile syncing with a test server
+type testClient struct {
+	syncer *Syncer
+	fm     *feed.FeedManager
+	paths  config.Paths
+}
+
+func newTestClient(t *testing.T, ts *testServer) *testClient {
+	t.Helper()
+	paths := config.Paths{CacheDir: t.TempDir(), StateDir: t.TempDir()}
+	fm := feed.NewFeedManager(nil, paths.CacheDir)
+	backend := config.BackendConfig{Type: config.BackendGReader, URL: ts.URL, Username: testUser, Password: testPassword}
+	return &testClient{syncer: NewSyncer(backend, fm, paths), fm: fm, paths: paths}
+}
+
+func (c *testClient) sync(t *testing.T) SyncResult {
+	t.Helper()
+	res, err := c.syncer.Sync()
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	return res
+}
+
+func (c *testClient) pending(t *testing.T) int {
+	t.Helper()
+	n, err := c.syncer.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// article returns the local ID of the article with the given title
+func (c *testClient) article(t *testing.T, title string) string {
+	t.Helper()
+	for _, a := range c.fm.GetArticles() {
+		if a.Title == title {
+			return a.ID
+		}
+	}
+	t.Fatalf("no article %q", title)
+	return ""
+}
+
+func (c *testClient) state(t *testing.T) *feed.State {
+	t.Helper()
+	state, _, err := feed.LoadState(c.paths.StateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// setRead marks an article the way the TUI does
+func (c *testClient) setRead(t *testing.T, title string, read bool) {
+	t.Helper()
+	state := c.state(t)
+	state.SetRead(c.article(t, title), read)
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncPulls(t *testing.T) {
+	ts := newTestServer(t)
+	c := newTestClient(t, ts)
+
+	res := c.sync(t)
+	if len(res.Feeds) != 2 || res.New != 4 {
+		t.Fatalf("first sync: %d feeds, %d new articles, want 2 and 4", len(res.Feeds), res.New)
+	}
+	if n := c.pending(t); n != 0 {
+		t.Errorf("%d changes pending after the first sync", n)
+	}
+	for _, a := range c.fm.GetArticles() {
+		if a.Source != "Blog" && a.Source != "News" {
+			t.Errorf("article %q from %q", a.Title, a.Source)
+		}
+	}
+}
+
+func TestSyncQueuesAcrossFailure(t *testing.T) {
+	ts := newTestServer(t)
+	c := newTestClient(t, ts)
+	c.sync(t)
+
+	c.setRead(t, "Blog 0", true)
+	if n := c.pending(t); n != 1 {
+		t.Fatalf("%d changes pending, want 1", n)
+	}
+
+	ts.down.Store(true)
+	if _, err := c.syncer.Sync(); err == nil {
+		t.Fatal("sync with the server down succeeded")
+	}
+	if n := c.pending(t); n != 1 {
+		t.Errorf("%d changes pending after the failed sync, want 1", n)
+	}
+	if !c.state(t).IsRead(c.article(t, "Blog 0")) {
+		t.Error("the failed sync undid the local change")
+	}
+	if ts.state(t).IsRead(ts.article(t, "Blog 0")) {
+		t.Error("the server got the change while down")
+	}
+
+	ts.down.Store(false)
+	res := c.sync(t)
+	if res.Pushed != 1 || res.Dropped != 0 {
+		t.Errorf("pushed %d, dropped %d, want 1 and 0", res.Pushed, res.Dropped)
+	}
+	if n := c.pending(t); n != 0 {
+		t.Errorf("%d changes pending after the sync, want 0", n)
+	}
+	if !ts.state(t).IsRead(ts.article(t, "Blog 0")) {
+		t.Error("the queued change didn't reach the server")
+	}
+	if !c.state(t).IsRead(c.article(t, "Blog 0")) {
+		t.Error("the change was lost locally")
+	}
+}
+
+func TestSyncConflictLocalWins(t *testing.T) {
+	ts := newTestServer(t)
+	c := newTestClient(t, ts)
+	c.sync(t)
+
+	// Blog 0 is read on both sides
+	if status, data := ts.call(t, "edit-tag", url.Values{"i": {longID(ts.article(t, "Blog 0"))}, "a": {streamRead}}); status != http.StatusOK {
+		t.Fatalf("edit-tag: %d %s", status, data)
+	}
+	c.sync(t)
+	if !c.state(t).IsRead(c.article(t, "Blog 0")) {
+		t.Fatal("the server's mark wasn't pulled")
+	}
+
+	// Then the TUI marks it unread to come back to it, while a phone marks
+	// the whole feed read
+	c.setRead(t, "Blog 0", false)
+	if status, data := ts.call(t, "mark-all-as-read", url.Values{"s": {feedStream(blog)}}); status != http.StatusOK {
+		t.Fatalf("mark-all-as-read: %d %s", status, data)
+	}
+
+	res := c.sync(t)
+	if res.Pushed != 1 {
+		t.Errorf("pushed %d changes, want 1", res.Pushed)
+	}
+	local, server := c.state(t), ts.state(t)
+	for title, want := range map[string]bool{"Blog 0": false, "Blog 1": true, "Blog 2": true, "News 0": false} {
+		if got := local.IsRead(c.article(t, title)); got != want {
+			t.Errorf("%s read locally = %v, want %v", title, got, want)
+		}
+		if got := server.IsRead(ts.article(t, title)); got != want {
+			t.Errorf("%s read on the server = %v, want %v", title, got, want)
+		}
+	}
+	if n := c.pending(t); n != 0 {
+		t.Errorf("%d changes pending after the sync, want 0", n)
+	}
+}
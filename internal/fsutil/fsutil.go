@@ -0,0 +1,42 @@
+// Package fsutil has the file helpers shared by the packages that keep
+// files other processes may read at any time.
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile replaces path with data without ever leaving a partially written
+// file behind: data goes to a temporary file in the same directory, is
+// synced, gets perm and is then renamed over the target. Missing parent
+// directories are created.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	// Clean up the temporary file on any failure below
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
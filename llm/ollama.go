@@ -9,27 +9,9 @@ import (
 	"net/http"
 	"strings"
 	"time"
-)
-
-// OllamaConfig stores configuration for the Ollama LLM
-type OllamaConfig struct {
-	Enabled     bool   `mapstructure:"enabled"`
-	URL         string `mapstructure:"url"`
-	Model       string `mapstructure:"model"`
-	MaxArticles int    `mapstructure:"max_articles"`
-	Timeout     int    `mapstructure:"timeout"`
-}
 
-// DefaultOllamaConfig returns default config
-func DefaultOllamaConfig() OllamaConfig {
-	return OllamaConfig{
-		Enabled:     true,
-		URL:         "http://localhost:11434",
-		Model:       "qwen3:32b",
-		MaxArticles: 100,
-		Timeout:     3000,
-	}
-}
+	"github.com/JohanLi233/gorss/config"
+)
 
 // OllamaRequest is the request structure for Ollama API
 type OllamaRequest struct {
@@ -57,8 +39,8 @@ type StreamingResponseMsg struct {
 }
 
 // AskOllama sends an arbitrary prompt to the Ollama LLM and returns the response
-func AskOllama(prompt string, config OllamaConfig) (string, error) {
-	if !config.Enabled {
+func AskOllama(prompt string, config config.OllamaConfig) (string, error) {
+	if !config.IsEnabled() {
 		return "", fmt.Errorf("LLM is disabled in config")
 	}
 
@@ -109,13 +91,13 @@ func AskOllama(prompt string, config OllamaConfig) (string, error) {
 
 // AskOllamaStreaming sends a prompt to Ollama and streams back responses
 // It returns a channel that will receive streaming responses
-func AskOllamaStreaming(prompt string, config OllamaConfig) chan StreamingResponseMsg {
+func AskOllamaStreaming(prompt string, config config.OllamaConfig) chan StreamingResponseMsg {
 	responseChannel := make(chan StreamingResponseMsg, 10)
 
 	go func() {
 		defer close(responseChannel)
 
-		if !config.Enabled {
+		if !config.IsEnabled() {
 			responseChannel <- StreamingResponseMsg{Content: "", Done: true, Err: fmt.Errorf("LLM is disabled in config")}
 			return
 		}
@@ -201,17 +183,21 @@ func AskOllamaStreaming(prompt string, config OllamaConfig) chan StreamingRespon
 }
 
 // GetAvailableModels retrieves the list of available models from Ollama
-func GetAvailableModels(config OllamaConfig) ([]string, error) {
+func GetAvailableModels(config config.OllamaConfig) ([]string, error) {
 	client := &http.Client{
 		Timeout: time.Duration(config.Timeout) * time.Second,
 	}
 
-	resp, err := client.Get(config.URL + "/api/tags")
+	resp, err := client.Get(strings.TrimSuffix(config.URL, "/") + "/api/tags")
 	if err != nil {
 		return nil, fmt.Errorf("error fetching models from Ollama: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Ollama API returned non-200 status code %d", resp.StatusCode)
+	}
+
 	type Model struct {
 		Name string `json:"name"`
 	}
@@ -232,3 +218,26 @@ func GetAvailableModels(config OllamaConfig) ([]string, error) {
 
 	return modelNames, nil
 }
+
+// CheckConnection verifies that Ollama is reachable at the configured URL and
+// that the configured model is installed
+func CheckConnection(config config.OllamaConfig) error {
+	// The configured timeout is sized for generating answers, not for a ping
+	if config.Timeout <= 0 || config.Timeout > 10 {
+		config.Timeout = 10
+	}
+	models, err := GetAvailableModels(config)
+	if err != nil {
+		return err
+	}
+	for _, name := range models {
+		// "qwen3" refers to "qwen3:latest"
+		if name == config.Model || name == config.Model+":latest" {
+			return nil
+		}
+	}
+	if len(models) == 0 {
+		return fmt.Errorf("model %q is not installed (no models are installed)", config.Model)
+	}
+	return fmt.Errorf("model %q is not installed (available: %s)", config.Model, strings.Join(models, ", "))
+}
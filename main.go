@@ -1,39 +1,116 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 
 	"github.com/JohanLi233/gorss/config"
 	"github.com/JohanLi233/gorss/feed"
+	"github.com/JohanLi233/gorss/greader"
 	"github.com/JohanLi233/gorss/ui"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+const commandHelp = `  fetch [--feed name] [-q]              fetch feeds and report new articles, or sync with
+                                        the server of a greader backend (backend.type)
+  feeds list [--json]                   list the configured feeds
+  feeds add <name> <url>                subscribe to a feed
+  feeds remove <name>                   unsubscribe from a feed
+  articles [--feed name] [--since 24h] [--filter query] [--unread] [--starred] [--limit n] [--json]
+                                        list cached articles, newest first
+  open [--link] <id>                    print an article and mark it read
+  star <id>... / unstar <id>...         star or unstar articles
+  export --format markdown|json|ndjson|csv|html|epub [--out path] [selection] [id...]
+                                        export articles selected like in articles
+  publish [--dir dir] [--format atom,rss,json] [name...]
+                                        write the feeds configured under publish
+  serve [--interval 30m]                run as a daemon that fetches feeds (with WebSub
+                                        push) and serves the JSON API (serve.api), the
+                                        Google Reader API for mobile apps (serve.greader)
+                                        and the published feeds (publish.listen); a TUI
+                                        elsewhere attaches with a greader backend
+                                        pointing at serve.greader
+  config check                          validate the config file
+`
+
 func main() {
-	// Load configuration
-	cfg, err := config.LoadConfig()
+	// Global flags come before the subcommand: gorss --profile work serve
+	flags := flag.NewFlagSet("gorss", flag.ExitOnError)
+	configFile := flags.String("config", "", "config file to use (default $GORSS_CONFIG or the profile's config.yaml)")
+	profile := flags.String("profile", "", "profile with its own config, cache and state (default $GORSS_PROFILE)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: gorss [flags] [command]\n\nWithout a command gorss starts the TUI. Commands:\n%s\nFlags:\n", commandHelp)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[1:])
+
+	paths, err := config.ResolvePaths(*configFile, *profile)
 	if err != nil {
-		fmt.Printf("Error loading configuration: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Initialize feed manager
-	feeds := cfg.Feeds
-	feedManager := feed.NewFeedManager(feeds)
+	// Subcommands run without the TUI
+	if args := flags.Args(); len(args) > 0 {
+		switch args[0] {
+		case "serve":
+			err = runServe(paths, args[1:])
+		case "config":
+			err = runConfigCommand(paths, args[1:])
+		case "fetch":
+			err = runFetch(paths, args[1:])
+		case "feeds":
+			err = runFeedsCommand(paths, args[1:])
+		case "articles":
+			err = runArticles(paths, args[1:])
+		case "open":
+			err = runOpen(paths, args[1:])
+		case "star", "unstar":
+			err = runStar(paths, args[1:], args[0] == "star")
+		case "export":
+			err = runExport(paths, args[1:])
+		case "publish":
+			err = runPublish(paths, args[1:])
+		default:
+			flags.Usage()
+			os.Exit(2)
+		}
+		var usage usageError
+		if errors.As(err, &usage) {
+			if usage != "" {
+				fmt.Fprintln(os.Stderr, usage)
+			}
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfig(paths)
+	if err != nil {
+		reportConfigError(err)
+	}
 
-	// Add an "All" feed option
-	feeds = append([]config.Feed{{Name: "All", URL: ""}}, feeds...)
-	feedManager.Feeds = feeds
+	// Initialize feed manager; the TUI adds its "All" view itself
+	feedManager := feed.NewFeedManager(greader.Feeds(cfg), paths.CacheDir)
 
 	// Create and start the Bubble Tea program
+	model := ui.NewModel(feedManager, cfg)
 	p := tea.NewProgram(
-		ui.NewModel(feedManager),
+		model,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
 
-	if _, err := p.Run(); err != nil {
+	_, err = p.Run()
+	model.Close()
+	if err != nil {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)
 	}
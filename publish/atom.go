@@ -0,0 +1,131 @@
+package publish
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Atom (RFC 4287)
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Author    atomPerson  `xml:"author"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length string `xml:"length,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomSource struct {
+	Title string     `xml:"title"`
+	Links []atomLink `xml:"link,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Content    *atomText      `xml:"content"`
+	Source     *atomSource    `xml:"source"`
+}
+
+func writeAtom(w io.Writer, d *document) error {
+	updated := d.updated
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	f := atomFeed{
+		Title:     d.title,
+		Subtitle:  d.description,
+		ID:        d.id,
+		Updated:   updated.UTC().Format(time.RFC3339),
+		Author:    atomPerson{Name: "gorss"},
+		Generator: "gorss",
+	}
+	if d.self != "" {
+		f.Links = append(f.Links,
+			atomLink{Rel: "self", Href: d.self + Atom.Ext(), Type: "application/atom+xml"},
+			atomLink{Rel: "alternate", Href: d.home, Type: "text/html"})
+	}
+
+	for _, e := range d.entries {
+		ae := atomEntry{
+			Title:   e.Title,
+			ID:      e.id,
+			Updated: e.lastChange().UTC().Format(time.RFC3339),
+		}
+		if e.Link != "" {
+			ae.Links = append(ae.Links, atomLink{Rel: "alternate", Href: e.Link})
+		}
+		for _, enc := range e.Enclosures {
+			l := atomLink{Rel: "enclosure", Href: enc.URL, Type: enc.Type}
+			if enc.Length > 0 {
+				l.Length = strconv.FormatInt(enc.Length, 10)
+			}
+			ae.Links = append(ae.Links, l)
+		}
+		if !e.Published.IsZero() {
+			ae.Published = e.Published.UTC().Format(time.RFC3339)
+		}
+		for _, name := range e.Authors {
+			ae.Authors = append(ae.Authors, atomPerson{Name: name})
+		}
+		for _, c := range e.Categories {
+			ae.Categories = append(ae.Categories, atomCategory{Term: c})
+		}
+		if e.html != "" {
+			ae.Content = &atomText{Type: "html", Body: e.html}
+		}
+		if e.FeedName != "" {
+			ae.Source = &atomSource{Title: e.FeedName}
+			if isWebURL(e.sourceURL) {
+				ae.Source.Links = []atomLink{{Rel: "self", Href: e.sourceURL}}
+			}
+		}
+		f.Entries = append(f.Entries, ae)
+	}
+
+	return writeXML(w, f)
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,77 @@
+package publish
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// PathPrefix is where ServeHTTP expects to be mounted: the published feeds
+// are served as /feeds/<name>.atom, .rss and .json, with an index at /feeds/
+const PathPrefix = "/feeds/"
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gorss feeds</title>
+{{- range .}}
+<link rel="alternate" type="application/atom+xml" title="{{if .Title}}{{.Title}}{{else}}{{.Name}}{{end}}" href="{{.Name}}.atom">
+{{- end}}
+</head>
+<body>
+<h1>gorss feeds</h1>
+<ul>
+{{- range .}}
+<li>{{if .Title}}{{.Title}}{{else}}{{.Name}}{{end}}{{if .Filter}} <code>{{.Filter}}</code>{{end}}:
+<a href="{{.Name}}.atom">Atom</a> · <a href="{{.Name}}.rss">RSS</a> · <a href="{{.Name}}.json">JSON Feed</a></li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
+
+// ServeHTTP serves the published feeds. Feeds are rendered on every request
+// from the articles in memory; Last-Modified is the newest article, so
+// conditional requests from polling readers are cheap to answer.
+func (p *Publisher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	if file == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = indexTemplate.Execute(w, p.Feeds())
+		return
+	}
+
+	ext := path.Ext(file)
+	format, err := ParseFormat(ext)
+	if ext == "" || err != nil || strings.Contains(file, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	pf, ok := p.Lookup(strings.TrimSuffix(file, ext))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	doc, err := p.build(pf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := doc.write(&buf, format); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	http.ServeContent(w, r, "", doc.updated, bytes.NewReader(buf.Bytes()))
+}
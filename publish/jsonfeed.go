@@ -0,0 +1,101 @@
+package publish
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSON Feed 1.1 (https://www.jsonfeed.org/version/1.1/)
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url,omitempty"`
+	FeedURL     string     `json:"feed_url,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentHTML   string           `json:"content_html"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonAuthor     `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	Attachments   []jsonAttachment `json:"attachments,omitempty"`
+	Source        *jsonSource      `json:"_gorss,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonAttachment struct {
+	URL      string `json:"url"`
+	MIMEType string `json:"mime_type"`
+	Size     int64  `json:"size_in_bytes,omitempty"`
+}
+
+// jsonSource is an extension with the feed an item came from; JSON Feed
+// readers ignore keys starting with an underscore
+type jsonSource struct {
+	Feed    string `json:"feed"`
+	FeedURL string `json:"feed_url,omitempty"`
+}
+
+func writeJSONFeed(w io.Writer, d *document) error {
+	f := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       d.title,
+		HomePageURL: d.home,
+		Description: d.description,
+		Items:       []jsonItem{},
+	}
+	if d.self != "" {
+		f.FeedURL = d.self + JSONFeed.Ext()
+	}
+
+	for _, e := range d.entries {
+		item := jsonItem{
+			ID:          e.id,
+			URL:         e.Link,
+			Title:       e.Title,
+			ContentHTML: e.html,
+			Image:       e.Image,
+			Tags:        e.Categories,
+		}
+		if !e.Published.IsZero() {
+			item.DatePublished = e.Published.Format(time.RFC3339)
+		}
+		if !e.Updated.IsZero() {
+			item.DateModified = e.Updated.Format(time.RFC3339)
+		}
+		for _, name := range e.Authors {
+			item.Authors = append(item.Authors, jsonAuthor{Name: name})
+		}
+		for _, enc := range e.Enclosures {
+			mimeType := enc.Type
+			if mimeType == "" {
+				mimeType = "application/octet-stream"
+			}
+			item.Attachments = append(item.Attachments, jsonAttachment{URL: enc.URL, MIMEType: mimeType, Size: enc.Length})
+		}
+		if e.FeedName != "" {
+			item.Source = &jsonSource{Feed: e.FeedName}
+			if isWebURL(e.sourceURL) {
+				item.Source.FeedURL = e.sourceURL
+			}
+		}
+		f.Items = append(f.Items, item)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(f)
+}
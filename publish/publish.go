@@ -0,0 +1,291 @@
+// Package publish re-publishes articles as feeds other tools can subscribe
+// to. A published feed is a selection of the articles gorss has, e.g. all of
+// them, a few sources or a saved filter such as "is:starred", written as
+// Atom, RSS 2.0 or JSON Feed, either to files or over HTTP.
+package publish
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/export"
+	"github.com/JohanLi233/gorss/feed"
+	"github.com/JohanLi233/gorss/internal/fsutil"
+)
+
+// Format is a feed format
+type Format string
+
+const (
+	Atom     Format = "atom"
+	RSS      Format = "rss"
+	JSONFeed Format = "json"
+)
+
+// Formats lists the formats in the order they are written
+var Formats = []Format{Atom, RSS, JSONFeed}
+
+// ParseFormat looks up a format by name or file extension
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), ".")) {
+	case "atom":
+		return Atom, nil
+	case "rss", "rss2", "xml":
+		return RSS, nil
+	case "json", "jsonfeed":
+		return JSONFeed, nil
+	}
+	return "", fmt.Errorf("unknown feed format %q, expected atom, rss or json", name)
+}
+
+// Ext returns the file extension used for the format
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case Atom:
+		return "application/atom+xml; charset=utf-8"
+	case RSS:
+		return "application/rss+xml; charset=utf-8"
+	}
+	return "application/feed+json; charset=utf-8"
+}
+
+// Publisher renders the published feeds of a config from the articles of a
+// FeedManager
+type Publisher struct {
+	cfg     config.PublishConfig
+	sources map[string]string // configured feed name (lower case) -> feed URL
+	fm      *feed.FeedManager
+	state   func() (*feed.State, error)
+}
+
+// New creates a publisher. state loads the current reading state, which
+// filters like is:starred depend on; it is called for every rendered feed so
+// stars added in the TUI show up without a restart.
+func New(cfg *config.Config, fm *feed.FeedManager, state func() (*feed.State, error)) *Publisher {
+	sources := make(map[string]string)
+	for _, f := range cfg.Feeds {
+		sources[strings.ToLower(f.Name)] = f.URL
+	}
+	return &Publisher{cfg: cfg.Publish, sources: sources, fm: fm, state: state}
+}
+
+// Feeds returns the published feeds
+func (p *Publisher) Feeds() []config.PublishedFeed {
+	return p.cfg.PublishedFeeds()
+}
+
+// Lookup finds a published feed by name, ignoring case
+func (p *Publisher) Lookup(name string) (config.PublishedFeed, bool) {
+	for _, pf := range p.Feeds() {
+		if strings.EqualFold(pf.Name, name) {
+			return pf, true
+		}
+	}
+	return config.PublishedFeed{}, false
+}
+
+// BaseURL returns the URL the feeds are reachable at without the trailing
+// slash, or "" if it isn't known
+func (p *Publisher) BaseURL() string {
+	if p.cfg.BaseURL != "" {
+		return strings.TrimRight(p.cfg.BaseURL, "/")
+	}
+	if p.cfg.Listen == "" {
+		return ""
+	}
+	host, port, err := net.SplitHostPort(p.cfg.Listen)
+	if err != nil {
+		return ""
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// document is a published feed ready to be written in any format
+type document struct {
+	name        string
+	title       string
+	description string
+	id          string
+	home        string // the HTML index of the published feeds
+	self        string // the feed itself without the extension
+	updated     time.Time
+	entries     []entry
+}
+
+// entry is an article in a published feed
+type entry struct {
+	feed.Article
+	id        string
+	html      string // cleaned content
+	sourceURL string // URL of the configured feed it came from
+}
+
+// build selects the articles of a published feed, newest first
+func (p *Publisher) build(pf config.PublishedFeed) (*document, error) {
+	state, err := p.state()
+	if err != nil {
+		return nil, err
+	}
+
+	filter := feed.ParseFilter(pf.Filter)
+	sources := make(map[string]bool)
+	for _, s := range pf.Sources {
+		sources[strings.ToLower(s)] = true
+	}
+	var articles []feed.Article
+	for _, a := range p.fm.GetArticles() {
+		if len(sources) > 0 && !sources[strings.ToLower(a.SourceName())] {
+			continue
+		}
+		if filter.MatchState(a, state) {
+			articles = append(articles, a)
+		}
+	}
+	sort.SliceStable(articles, func(i, j int) bool { return articles[i].Published.After(articles[j].Published) })
+
+	limit := pf.Limit
+	if limit <= 0 {
+		limit = p.cfg.Limit
+	}
+	if limit <= 0 {
+		limit = config.DefaultPublishLimit
+	}
+	if len(articles) > limit {
+		articles = articles[:limit]
+	}
+
+	doc := &document{
+		name:        pf.Name,
+		title:       pf.Title,
+		description: pf.Description,
+		id:          "urn:gorss:feed:" + pf.Name,
+	}
+	if doc.title == "" {
+		doc.title = "gorss: " + pf.Name
+	}
+	if doc.description == "" {
+		doc.description = "Articles published by gorss"
+		if pf.Filter != "" {
+			doc.description += " matching " + pf.Filter
+		}
+	}
+	if base := p.BaseURL(); base != "" {
+		doc.home = base + PathPrefix
+		doc.self = base + PathPrefix + pf.Name
+		doc.id = doc.self
+	}
+	for _, a := range articles {
+		e := entry{
+			Article:   a,
+			id:        entryID(a),
+			html:      export.CleanHTML(a),
+			sourceURL: p.sources[strings.ToLower(a.SourceName())],
+		}
+		doc.entries = append(doc.entries, e)
+		if t := e.lastChange(); t.After(doc.updated) {
+			doc.updated = t
+		}
+	}
+	return doc, nil
+}
+
+// entryID returns the GUID if it is already a URI, else an id derived from
+// the article's, so entries keep their identity across published feeds
+func entryID(a feed.Article) string {
+	if i := strings.Index(a.GUID, ":"); i > 0 && !strings.ContainsAny(a.GUID, " <>\"") {
+		return a.GUID
+	}
+	return "urn:gorss:article:" + a.ID
+}
+
+func (e entry) lastChange() time.Time {
+	if e.Updated.After(e.Published) {
+		return e.Updated
+	}
+	return e.Published
+}
+
+// Render writes a published feed in the given format
+func (p *Publisher) Render(w io.Writer, name string, format Format) error {
+	pf, ok := p.Lookup(name)
+	if !ok {
+		return fmt.Errorf("no published feed named %q", name)
+	}
+	doc, err := p.build(pf)
+	if err != nil {
+		return err
+	}
+	return doc.write(w, format)
+}
+
+func (d *document) write(w io.Writer, format Format) error {
+	switch format {
+	case Atom:
+		return writeAtom(w, d)
+	case RSS:
+		return writeRSS(w, d)
+	case JSONFeed:
+		return writeJSONFeed(w, d)
+	}
+	return fmt.Errorf("unknown feed format %q", format)
+}
+
+// WriteFiles writes the named published feeds (all if names is empty) into
+// dir in every format, as <name>.atom, <name>.rss and <name>.json, and
+// returns the paths written. Files are replaced atomically, so a web server
+// serving dir never sees half a feed.
+func (p *Publisher) WriteFiles(dir string, names []string, formats []Format) ([]string, error) {
+	feeds := p.Feeds()
+	if len(names) > 0 {
+		feeds = nil
+		for _, name := range names {
+			pf, ok := p.Lookup(name)
+			if !ok {
+				return nil, fmt.Errorf("no published feed named %q", name)
+			}
+			feeds = append(feeds, pf)
+		}
+	}
+	if len(formats) == 0 {
+		formats = Formats
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, pf := range feeds {
+		doc, err := p.build(pf)
+		if err != nil {
+			return written, err
+		}
+		for _, format := range formats {
+			var buf bytes.Buffer
+			if err := doc.write(&buf, format); err != nil {
+				return written, err
+			}
+			path := filepath.Join(dir, pf.Name+format.Ext())
+			if err := fsutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				return written, err
+			}
+			written = append(written, path)
+		}
+	}
+	return written, nil
+}
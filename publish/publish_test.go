@@ -0,0 +1,217 @@
+package publish
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+	"github.com/mmcdole/gofeed"
+)
+
+var published = time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+
+func newTestPublisher(t *testing.T) *Publisher {
+	t.Helper()
+	cfg := &config.Config{
+		Feeds: []config.Feed{
+			{Name: "Go Blog", URL: "https://go.dev/blog/feed.atom"},
+			{Name: "Inbox", URL: "maildir:~/Mail/feeds"},
+		},
+		Publish: config.PublishConfig{
+			BaseURL: "https://example.com/",
+			Feeds: []config.PublishedFeed{
+				{Name: "all", Title: "Everything"},
+				{Name: "go", Sources: []string{"go blog"}},
+				{Name: "starred", Filter: "is:starred"},
+				{Name: "latest", Limit: 1},
+			},
+		},
+	}
+	fm := feed.NewFeedManager(cfg.Feeds, t.TempDir())
+	fm.ReplaceArticles([]feed.Article{
+		{
+			ID: "00000000a1", Source: "Go Blog", FeedName: "Go Blog", Title: "Go 1.23 & iterators",
+			Link: "https://go.dev/blog/go1.23", GUID: "tag:go.dev,2024:go1.23",
+			Published: published, Updated: published.Add(time.Hour),
+			Authors: []string{"Gopher"}, Categories: []string{"release"},
+			Enclosures: []feed.Enclosure{{URL: "https://go.dev/talk.mp3", Type: "audio/mpeg", Length: 1234}},
+			Content:    `<p>New <b>range</b> funcs<script>alert(1)</script><img src="/gopher.png" alt="gopher"></p>`,
+		},
+		{
+			ID: "00000000b2", Source: "Inbox", FeedName: "Inbox", Title: "A letter",
+			GUID: "<1@example.com>", Published: published.Add(-24 * time.Hour),
+			Description: "plain description",
+		},
+	})
+	stateDir := t.TempDir()
+	state, _, err := feed.LoadState(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SetStarred("00000000b2", true)
+	return New(cfg, fm, func() (*feed.State, error) { return state, nil })
+}
+
+func render(t *testing.T, p *Publisher, name string, format Format) *gofeed.Feed {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := p.Render(&buf, name, format); err != nil {
+		t.Fatal(err)
+	}
+	f, err := gofeed.NewParser().Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", format, err, buf.String())
+	}
+	return f
+}
+
+func TestRenderParses(t *testing.T) {
+	p := newTestPublisher(t)
+	feedTypes := map[Format]string{Atom: "atom", RSS: "rss", JSONFeed: "json"}
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			f := render(t, p, "all", format)
+			if f.FeedType != feedTypes[format] {
+				t.Errorf("feed type %q, want %q", f.FeedType, feedTypes[format])
+			}
+			if f.Title != "Everything" {
+				t.Errorf("title %q", f.Title)
+			}
+			if format != RSS && f.FeedLink != "https://example.com/feeds/all"+format.Ext() {
+				t.Errorf("self link %q", f.FeedLink)
+			}
+			if len(f.Items) != 2 {
+				t.Fatalf("%d items, want 2", len(f.Items))
+			}
+
+			// Newest first
+			item := f.Items[0]
+			if item.Title != "Go 1.23 & iterators" || item.Link != "https://go.dev/blog/go1.23" || item.GUID != "tag:go.dev,2024:go1.23" {
+				t.Errorf("item %q %q %q", item.Title, item.Link, item.GUID)
+			}
+			if item.PublishedParsed == nil || !item.PublishedParsed.Equal(published) {
+				t.Errorf("published %v", item.PublishedParsed)
+			}
+			if len(item.Authors) != 1 || item.Authors[0].Name != "Gopher" {
+				t.Errorf("authors %+v", item.Authors)
+			}
+			if !reflect.DeepEqual(item.Categories, []string{"release"}) {
+				t.Errorf("categories %q", item.Categories)
+			}
+			// gofeed reads the duration of JSON Feed attachments as their length
+			if len(item.Enclosures) != 1 {
+				t.Errorf("%d enclosures, want 1", len(item.Enclosures))
+			} else if enc := item.Enclosures[0]; enc.URL != "https://go.dev/talk.mp3" || enc.Type != "audio/mpeg" || format != JSONFeed && enc.Length != "1234" {
+				t.Errorf("enclosure %+v", *enc)
+			}
+			content := item.Content
+			if format == RSS {
+				content = item.Description
+			}
+			if !strings.Contains(content, "<b>range</b>") || !strings.Contains(content, `src="https://go.dev/gopher.png"`) || strings.Contains(content, "script") {
+				t.Errorf("content %q", content)
+			}
+
+			// Message-IDs aren't URIs, so the item gets an ID of its own
+			if f.Items[1].GUID != "urn:gorss:article:00000000b2" {
+				t.Errorf("second item GUID %q", f.Items[1].GUID)
+			}
+		})
+	}
+}
+
+func TestRenderSelects(t *testing.T) {
+	p := newTestPublisher(t)
+	tests := map[string][]string{
+		"go":      {"Go 1.23 & iterators"},
+		"starred": {"A letter"},
+		"latest":  {"Go 1.23 & iterators"},
+	}
+	for name, want := range tests {
+		var titles []string
+		for _, item := range render(t, p, name, Atom).Items {
+			titles = append(titles, item.Title)
+		}
+		if !reflect.DeepEqual(titles, want) {
+			t.Errorf("%s: items %q, want %q", name, titles, want)
+		}
+	}
+	if err := p.Render(&bytes.Buffer{}, "nope", Atom); err == nil {
+		t.Error("unknown feed rendered")
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	p := newTestPublisher(t)
+	dir := filepath.Join(t.TempDir(), "feeds")
+	paths, err := p.WriteFiles(dir, []string{"GO"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0644 {
+			t.Errorf("%s mode %v, want 0644", path, info.Mode().Perm())
+		}
+	}
+	if want := []string{"go.atom", "go.rss", "go.json"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("wrote %q, want %q", names, want)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != len(paths) {
+		t.Errorf("%d files in the directory, want %d", len(entries), len(paths))
+	}
+	if _, err := p.WriteFiles(dir, []string{"nope"}, nil); err == nil {
+		t.Error("unknown feed written")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	p := newTestPublisher(t)
+	tests := []struct {
+		method, path string
+		status       int
+		contentType  string
+	}{
+		{"GET", "/feeds/", http.StatusOK, "text/html; charset=utf-8"},
+		{"GET", "/feeds/go.atom", http.StatusOK, Atom.ContentType()},
+		{"HEAD", "/feeds/go.rss", http.StatusOK, RSS.ContentType()},
+		{"GET", "/feeds/go.json", http.StatusOK, JSONFeed.ContentType()},
+		{"GET", "/feeds/go", http.StatusNotFound, ""},
+		{"GET", "/feeds/nope.atom", http.StatusNotFound, ""},
+		{"GET", "/feeds/go.pdf", http.StatusNotFound, ""},
+		{"POST", "/feeds/go.atom", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+		}
+		if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s %s: content type %q", tt.method, tt.path, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	// Polling readers get 304 until an article changes
+	req := httptest.NewRequest("GET", "/feeds/go.atom", nil)
+	req.Header.Set("If-Modified-Since", published.Add(time.Hour).Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional request: status %d, want 304", rec.Code)
+	}
+}
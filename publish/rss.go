@@ -0,0 +1,113 @@
+package publish
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RSS 2.0, with the Atom self link and Dublin Core creators most readers know
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Generator     string    `xml:"generator"`
+	Self          *atomLink `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssSource struct {
+	URL   string `xml:"url,attr"`
+	Title string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link,omitempty"`
+	Description string        `xml:"description,omitempty"`
+	Creators    []string      `xml:"dc:creator"`
+	Categories  []string      `xml:"category"`
+	Comments    string        `xml:"comments,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Source      *rssSource    `xml:"source"`
+}
+
+func writeRSS(w io.Writer, d *document) error {
+	f := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       d.title,
+			Link:        d.home,
+			Description: d.description,
+			Generator:   "gorss",
+		},
+	}
+	if !d.updated.IsZero() {
+		f.Channel.LastBuildDate = d.updated.Format(time.RFC1123Z)
+	}
+	if d.self != "" {
+		f.Channel.Self = &atomLink{Rel: "self", Href: d.self + RSS.Ext(), Type: "application/rss+xml"}
+	}
+
+	for _, e := range d.entries {
+		item := rssItem{
+			Title:       e.Title,
+			Link:        e.Link,
+			Description: e.html,
+			Creators:    e.Authors,
+			Categories:  e.Categories,
+			Comments:    e.CommentsURL,
+			GUID:        rssGUID{IsPermaLink: "false", Value: e.id},
+		}
+		if !e.Published.IsZero() {
+			item.PubDate = e.Published.Format(time.RFC1123Z)
+		}
+		// RSS allows a single enclosure per item
+		if len(e.Enclosures) > 0 {
+			enc := e.Enclosures[0]
+			item.Enclosure = &rssEnclosure{URL: enc.URL, Length: strconv.FormatInt(enc.Length, 10), Type: enc.Type}
+			if item.Enclosure.Type == "" {
+				item.Enclosure.Type = "application/octet-stream"
+			}
+		}
+		if e.FeedName != "" && isWebURL(e.sourceURL) {
+			item.Source = &rssSource{URL: e.sourceURL, Title: e.FeedName}
+		}
+		f.Channel.Items = append(f.Channel.Items, item)
+	}
+
+	return writeXML(w, f)
+}
+
+// isWebURL reports whether a feed URL can be fetched by other readers, unlike
+// maildir or gemini sources
+func isWebURL(u string) bool {
+	return strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")
+}
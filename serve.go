@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/JohanLi233/gorss/api"
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+	"github.com/JohanLi233/gorss/greader"
+	"github.com/JohanLi233/gorss/publish"
+	"github.com/JohanLi233/gorss/websub"
+)
+
+const (
+	defaultRefreshInterval = 30 // minutes
+	defaultLeaseSeconds    = 86400 * 5
+	websubFile             = "websub.json"
+)
+
+// runServe runs gorss as a long-running daemon: feeds that advertise a WebSub
+// hub are subscribed to and receive pushed updates, the rest are polled. The
+// published feeds are served on publish.listen and rewritten to publish.dir
+// after every poll, the JSON API (see package api) on serve.api.listen and
+// the Google Reader API for mobile apps on serve.greader.listen. With a sync
+// backend the server fetches the feeds and every poll is a sync with it.
+func runServe(paths config.Paths, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	interval := fs.Duration("interval", 0, "poll interval for feeds without push (overrides serve.refresh_interval)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(paths)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	logger := log.New(os.Stderr, "gorss: ", log.LstdFlags)
+	for _, p := range cfg.Warnings {
+		logger.Printf("config: %s", p)
+	}
+	cfg.Feeds = greader.Feeds(cfg)
+	fm := feed.NewFeedManager(cfg.Feeds, paths.CacheDir)
+	fm.SetStripParams(cfg.Links.StripParams)
+
+	pollEvery := *interval
+	if pollEvery <= 0 {
+		minutes := cfg.Serve.RefreshInterval
+		if minutes <= 0 {
+			minutes = defaultRefreshInterval
+		}
+		pollEvery = time.Duration(minutes) * time.Minute
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d := newDaemon(paths, cfg.Feeds, fm, logger)
+	if cfg.Backend.Remote() {
+		d.syncer = greader.NewSyncer(cfg.Backend, fm, paths)
+	}
+
+	// Initial fetch of everything; this also discovers hubs
+	d.poll(pollEvery)
+	logger.Printf("loaded %d articles from %d feeds", len(fm.GetArticles()), len(d.Feeds()))
+
+	// HTTP handlers by listen address, so WebSub callbacks and the published
+	// feeds can share a port
+	muxes := make(map[string]*http.ServeMux)
+	handle := func(addr, pattern string, h http.Handler) {
+		if muxes[addr] == nil {
+			muxes[addr] = http.NewServeMux()
+		}
+		muxes[addr].Handle(pattern, h)
+	}
+
+	if cfg.Serve.WebSub.Enabled && d.syncer != nil {
+		logger.Printf("websub: not used with a sync backend, the server fetches the feeds")
+	} else if cfg.Serve.WebSub.Enabled {
+		d.sub, err = newWebSub(cfg.Serve.WebSub, paths.StateDir, d.Feeds, fm, logger)
+		if err != nil {
+			return err
+		}
+		handle(cfg.Serve.WebSub.Listen, "/websub/", d.sub)
+	}
+
+	var pub *publish.Publisher
+	if cfg.Publish.Listen != "" || cfg.Publish.Dir != "" {
+		pub = publish.New(cfg, fm, func() (*feed.State, error) {
+			state, _, err := feed.LoadState(paths.StateDir)
+			return state, err
+		})
+		if cfg.Publish.Listen != "" {
+			handle(cfg.Publish.Listen, publish.PathPrefix, pub)
+			logger.Printf("publish: serving %d feeds at %s%s", len(pub.Feeds()), pub.BaseURL(), publish.PathPrefix)
+		}
+		writePublished(pub, cfg.Publish, logger)
+	}
+
+	if gc := cfg.Serve.GReader; gc.Listen != "" {
+		gr, err := greader.NewServer(d, fm, paths.StateDir, gc)
+		if err != nil {
+			return err
+		}
+		for _, pattern := range greader.Patterns {
+			handle(gc.Listen, pattern, gr)
+		}
+		logger.Printf("greader: serving the Google Reader API for %s", gc.Username)
+	}
+
+	servers, err := startServers(muxes, logger)
+	if err != nil {
+		return err
+	}
+	if cfg.Serve.API.Listen != "" {
+		server, err := startAPI(cfg.Serve.API, paths, d, fm, logger)
+		if err != nil {
+			for _, s := range servers {
+				s.Close()
+			}
+			return err
+		}
+		servers = append(servers, server)
+	}
+	if d.sub != nil {
+		// The callback server must be up before hubs verify the subscriptions
+		subscribeHubs(d.sub, d.Feeds(), fm, logger)
+		go d.sub.Run(ctx)
+	}
+
+	ticker := time.NewTicker(pollEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Printf("shutting down")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			for _, server := range servers {
+				_ = server.Shutdown(shutdownCtx)
+			}
+			return nil
+
+		case <-ticker.C:
+			d.poll(pollEvery)
+			if d.sub != nil {
+				subscribeHubs(d.sub, d.Feeds(), fm, logger)
+			}
+			if pub != nil {
+				writePublished(pub, cfg.Publish, logger)
+			}
+		}
+	}
+}
+
+// startServers starts an HTTP server for every address with handlers. All
+// addresses are bound before any server starts, so a port that is taken is
+// an error instead of a log line.
+func startServers(muxes map[string]*http.ServeMux, logger *log.Logger) ([]*http.Server, error) {
+	listeners := make(map[string]net.Listener)
+	for addr := range muxes {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners[addr] = l
+	}
+
+	var servers []*http.Server
+	for addr, mux := range muxes {
+		server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		listener := listeners[addr]
+		go func() {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Printf("server on %s: %v", server.Addr, err)
+			}
+		}()
+		logger.Printf("listening on %s", addr)
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+// startAPI starts the JSON API server on a loopback address or unix socket
+func startAPI(ac config.APIConfig, paths config.Paths, d *daemon, fm *feed.FeedManager, logger *log.Logger) (*http.Server, error) {
+	token, err := api.Token(ac, paths.StateDir)
+	if err != nil {
+		return nil, fmt.Errorf("api: %w", err)
+	}
+	listener, err := api.Listen(ac.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("api: %w", err)
+	}
+	server := &http.Server{Handler: api.NewServer(d, fm, paths.StateDir, token), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Printf("api: %v", err)
+		}
+	}()
+	if ac.Token == "" {
+		logger.Printf("api: listening on %s, token in %s", ac.Listen, api.TokenFile(paths.StateDir))
+	} else {
+		logger.Printf("api: listening on %s", ac.Listen)
+	}
+	return server, nil
+}
+
+// writePublished rewrites the published feed files, if publish.dir is set
+func writePublished(pub *publish.Publisher, pc config.PublishConfig, logger *log.Logger) {
+	dir := pc.Directory()
+	if dir == "" {
+		return
+	}
+	if _, err := pub.WriteFiles(dir, nil, nil); err != nil {
+		logger.Printf("publish: %v", err)
+	}
+}
+
+// newWebSub creates the subscriber that handles the callbacks of hubs. Its
+// subscriptions are kept in the state directory, so a restart only asks hubs
+// for the feeds that have none yet.
+func newWebSub(wc config.WebSubConfig, stateDir string, feeds func() []config.Feed, fm *feed.FeedManager, logger *log.Logger) (*websub.Subscriber, error) {
+	if wc.Listen == "" || wc.CallbackURL == "" {
+		return nil, fmt.Errorf("serve.websub requires both listen and callback_url")
+	}
+
+	lease := wc.LeaseSeconds
+	if lease <= 0 {
+		lease = defaultLeaseSeconds
+	}
+
+	deliver := func(topic string, body []byte) {
+		for _, f := range feeds() {
+			if hub, ok := fm.Hub(f.Name); ok && hub.Self == topic {
+				added, err := fm.MergePushed(f, body)
+				if err != nil {
+					logger.Printf("websub: bad content for %s: %v", f.Name, err)
+					return
+				}
+				logger.Printf("websub: %d new articles pushed for %s", added, f.Name)
+				return
+			}
+		}
+		logger.Printf("websub: delivery for unknown topic %s", topic)
+	}
+
+	sub := websub.NewSubscriber(wc.CallbackURL+"/websub", wc.Secret, time.Duration(lease)*time.Second, deliver)
+	sub.Logf = logger.Printf
+	if err := sub.SetStore(filepath.Join(stateDir, websubFile)); err != nil {
+		logger.Printf("websub: %v, subscribing again", err)
+	}
+	return sub, nil
+}
+
+// subscribeHubs subscribes to hubs for feeds that advertise one and are not yet subscribed
+func subscribeHubs(sub *websub.Subscriber, feeds []config.Feed, fm *feed.FeedManager, logger *log.Logger) {
+	known := make(map[string]bool)
+	for _, s := range sub.Subscriptions() {
+		known[s.Topic] = true
+	}
+	for _, f := range feeds {
+		hub, ok := fm.Hub(f.Name)
+		if !ok || known[hub.Self] {
+			continue
+		}
+		if err := sub.Subscribe(hub.Hub, hub.Self); err != nil {
+			logger.Printf("websub: %v", err)
+		}
+	}
+}
@@ -6,9 +6,13 @@ import (
 	"strings"
 
 	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/download"
+	"github.com/JohanLi233/gorss/export"
 	"github.com/JohanLi233/gorss/feed"
+	"github.com/JohanLi233/gorss/greader"
 	"github.com/JohanLi233/gorss/llm"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -21,23 +25,42 @@ const (
 	viewConfig  // 配置视图
 	viewSummary // 摘要视图
 	viewAskLLM  // Ask LLM prompt view
+	viewDownloads
+	viewComments
+	viewThemes
 )
 
 // Messages
 type fetchCompleteMsg struct{ err error }
+type syncCompleteMsg struct {
+	result greader.SyncResult
+	err    error
+}
 type fetchStartMsg struct{}
-type saveConfigCompleteMsg struct{ err error }
+type saveConfigCompleteMsg struct {
+	cfg          *config.Config // the saved config, reloaded from disk
+	feedsChanged bool           // feeds need to be refreshed
+	err          error
+}
+type configReloadMsg struct{ reload config.Reload }
 type exitConfigMsg struct{}
+type fullTextMsg struct {
+	article feed.Article
+	err     error
+}
+type stateSavedMsg struct{ err error }
 
 // Model represents the main application UI model
 type Model struct {
 	feedManager  *feed.FeedManager
+	state        *feed.State // read articles, shared with the CLI
 	feeds        []string
 	feedsList    list.Model
 	articlesList list.Model
 	articleView  *ArticleView
 	configView   *ConfigView // 配置视图
 	askLLMView   *AskLLMView // Ask LLM prompt view
+	commentsView *CommentsView
 	currentView  int
 	currentFeed  string
 	width        int
@@ -47,13 +70,36 @@ type Model struct {
 
 	errorMessage   string
 	statusMessage  string
-	ollamaConfig   llm.OllamaConfig
+	cfg            *config.Config // the config in effect
+	themeName      string
+	themePicker    *ThemePicker
+	ollamaConfig   config.OllamaConfig
+	keys           *Keymap
+	paths          config.Paths    // config file and data directories of the active profile
+	configWarnings []string        // config problems not yet shown
+	configWatcher  *config.Watcher // reloads config.yaml when it is edited, nil if watching failed
+	syncer         *greader.Syncer // nil unless feeds come from a sync server (backend.type: greader)
 	askLLMResult   string                        // last ask result
 	liveResponseCh chan llm.StreamingResponseMsg // channel for streaming responses
 
 	// 多选模式及选中索引
 	multiSelectMode        bool
 	selectedArticleIndexes map[int]struct{}
+
+	// Article filter ("/"), e.g. "author:alice tag:go"
+	filterInput textinput.Model
+	filtering   bool
+	filter      feed.Filter
+
+	// Export prompt ("e"), asking for the format
+	exportInput    textinput.Model
+	exporting      bool
+	exportArticles []feed.Article // articles the prompt is for
+
+	// Enclosure downloads and playback
+	downloads    *download.Manager
+	player       []string
+	previousView int // view to return to when leaving the downloads view
 }
 
 type askLLMCompleteMsg struct {
@@ -69,16 +115,13 @@ type streamingLLMResponseMsg struct {
 }
 
 // NewModel creates a new application model
-func NewModel(feedManager *feed.FeedManager) Model {
+func NewModel(feedManager *feed.FeedManager, cfg *config.Config) Model {
 	// Add 'All' feed option at the beginning
 	feeds := []string{"All"}
-	for _, f := range feedManager.Feeds {
+	for _, f := range feedManager.Feeds() {
 		feeds = append(feeds, f.Name)
 	}
 
-	// Initialize with default Ollama config
-	ollamaConfig := llm.DefaultOllamaConfig()
-
 	// Create the model
 	m := Model{
 		feedManager:  feedManager,
@@ -86,8 +129,34 @@ func NewModel(feedManager *feed.FeedManager) Model {
 		currentView:  viewFeeds,
 		currentFeed:  "All", // Start with 'All' selected
 		loading:      false, // Start with loading false since we're using cached data
-		ollamaConfig: ollamaConfig,
+		downloads:    download.NewManager(cfg.Downloads.Directory(), cfg.Downloads.Concurrency),
+	}
+
+	m.applyConfig(cfg)
+	state, warning, err := feed.LoadState(cfg.Paths.StateDir)
+	m.state = state
+	if warning != "" {
+		m.configWarnings = append(m.configWarnings, warning)
 	}
+	if err != nil {
+		m.configWarnings = append(m.configWarnings, fmt.Sprintf("failed to load read state: %v", err))
+	}
+	for _, p := range cfg.Warnings {
+		m.configWarnings = append(m.configWarnings, "config "+p.String())
+	}
+	if w, err := config.Watch(cfg.Paths); err == nil {
+		m.configWatcher = w
+	} else {
+		m.configWarnings = append(m.configWarnings, fmt.Sprintf("config changes won't be picked up: %v", err))
+	}
+
+	// Initialize the filter input
+	m.filterInput = textinput.New()
+	m.filterInput.Prompt = "/"
+	m.filterInput.Placeholder = "author:name tag:topic feed:name is:starred words"
+
+	// Initialize the export prompt
+	m.exportInput = textinput.New()
 
 	// Initialize the feeds list
 	m.feedsList = CreateFeedsList(feeds, 30, 20) // Height will be adjusted later
@@ -99,7 +168,9 @@ func NewModel(feedManager *feed.FeedManager) Model {
 	m.articleView = NewArticleView(feed.Article{}, 80, 20) // Size will be adjusted later
 
 	// Initialize the config view with current feeds
-	m.configView = NewConfigView(feedManager.Feeds, 80, 20) // Size will be adjusted later
+	m.configView = NewConfigView(feedManager.Feeds(), cfg.Paths, 80, 20) // Size will be adjusted later
+	m.configView.SetOllama(m.ollamaConfig)
+	m.configView.remote = m.syncer != nil
 
 	// Initialize the Ask LLM view
 	m.askLLMView = NewAskLLMView(80, 8)
@@ -111,12 +182,9 @@ func NewModel(feedManager *feed.FeedManager) Model {
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		tea.EnterAltScreen,
+		waitForDownloads(m.downloads),
+		waitForConfig(m.configWatcher),
 		func() tea.Msg {
-			// Just load configuration without refreshing feeds
-			cfg, err := config.LoadConfig()
-			if err == nil && cfg != nil {
-				m.feedManager.Feeds = cfg.Feeds
-			}
 			// Instead of fetching, immediately initialize the UI with cached articles
 			return fetchCompleteMsg{err: nil}
 		},
@@ -126,15 +194,67 @@ func (m Model) Init() tea.Cmd {
 // fetchFeeds is a command that fetches feeds
 func fetchFeeds(fm *feed.FeedManager) tea.Cmd {
 	return func() tea.Msg {
-		cfg, err := config.LoadConfig()
-		if err == nil && cfg != nil {
-			fm.Feeds = cfg.Feeds
-		}
-		err2 := fm.RefreshFeeds()
-		if err != nil {
-			return fetchCompleteMsg{err: err}
+		return fetchCompleteMsg{err: fm.RefreshFeeds()}
+	}
+}
+
+// syncFeeds is a command that syncs with the server of the sync backend
+func syncFeeds(s *greader.Syncer) tea.Cmd {
+	return func() tea.Msg {
+		result, err := s.Sync()
+		return syncCompleteMsg{result: result, err: err}
+	}
+}
+
+// refresh fetches the feeds, or syncs with the server when it fetches them
+func (m *Model) refresh() tea.Cmd {
+	if m.syncer != nil {
+		return syncFeeds(m.syncer)
+	}
+	return fetchFeeds(m.feedManager)
+}
+
+// saveState is a command that writes the read state
+func saveState(s *feed.State) tea.Cmd {
+	return func() tea.Msg {
+		return stateSavedMsg{err: s.Save()}
+	}
+}
+
+// waitForConfig waits for the next reload of the config file
+func waitForConfig(w *config.Watcher) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		reload, ok := <-w.Reloads()
+		if !ok {
+			return nil // the watcher was closed
 		}
-		return fetchCompleteMsg{err: err2}
+		return configReloadMsg{reload: reload}
+	}
+}
+
+// Close stops watching the config file. Call it once the program has exited.
+func (m Model) Close() {
+	if m.configWatcher != nil {
+		m.configWatcher.Close()
+	}
+}
+
+// fetchFullText is a command that extracts the full content of an article from its link
+func fetchFullText(fm *feed.FeedManager, article feed.Article) tea.Cmd {
+	return func() tea.Msg {
+		updated, err := fm.FetchFullText(article)
+		return fullTextMsg{article: updated, err: err}
+	}
+}
+
+// fetchComments is a command that fetches the discussion thread of an article
+func fetchComments(fm *feed.FeedManager, article feed.Article) tea.Cmd {
+	return func() tea.Msg {
+		thread, err := fm.FetchComments(article)
+		return commentsMsg{article: article, thread: thread, err: err}
 	}
 }
 
@@ -205,215 +325,80 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		// 配置视图中，将所有键盘输入传递给ConfigView处理
-		if m.currentView == viewConfig {
-			cv, cmd := m.configView.Handle(msg)
-			m.configView = cv
-			if cmd != nil {
+		// Filter input captures all keys while it is open
+		if m.filtering {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.filtering = false
+				m.filterInput.Blur()
+				m.filter = feed.ParseFilter(m.filterInput.Value())
+				m.updateArticlesList()
+				if m.currentView == viewFeeds {
+					m.currentView = viewArticles
+				}
+			case tea.KeyEsc:
+				m.filtering = false
+				m.filterInput.Blur()
+				m.filterInput.SetValue("")
+				m.filter = feed.Filter{}
+				m.updateArticlesList()
+			default:
+				var cmd tea.Cmd
+				m.filterInput, cmd = m.filterInput.Update(msg)
 				return m, cmd
 			}
 			return m, nil
 		}
 
-		// Ask LLM entry: 'a' from feeds, articles, or article detail view
-		if (m.currentView == viewFeeds || m.currentView == viewArticles || m.currentView == viewArticleDetail) && msg.String() == "a" {
-			presetPrompt := ""
-			
-			// 多选模式下处理
-			if m.currentView == viewArticles && m.multiSelectMode && len(m.selectedArticleIndexes) > 0 {
-				// 拼接所有选中文章内容
-				var prompt strings.Builder
-				for idx := range m.selectedArticleIndexes {
-					item := m.articlesList.Items()[idx].(Item)
-					article, ok := item.data.(feed.Article)
-					if ok {
-						prompt.WriteString("标题: ")
-						prompt.WriteString(article.Title)
-						prompt.WriteString("\n内容: ")
-						prompt.WriteString(article.Content)
-						prompt.WriteString("\n\n")
-					}
-				}
-				presetPrompt = prompt.String()
-				// 退出多选模式
-				m.multiSelectMode = false
-				m.selectedArticleIndexes = nil
-			} else if m.currentView == viewArticleDetail && m.articleView != nil {
-				// 单篇文章详情页处理
-				article := m.articleView.article
-				if article.Title != "" || article.Content != "" {
-					presetPrompt = fmt.Sprintf("Title: %s\nContent: %s\n\n", article.Title, article.Content)
+		// Export prompt captures all keys while it is open
+		if m.exporting {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.exporting = false
+				m.exportInput.Blur()
+				format, err := export.ParseFormat(m.exportInput.Value())
+				if err != nil {
+					m.errorMessage = err.Error()
+					return m, nil
 				}
+				m.errorMessage = ""
+				m.statusMessage = "Exporting..."
+				articles := m.exportArticles
+				m.exportArticles = nil
+				return m, exportArticles(m.cfg.Export.Directory(), format, articles, m.state)
+			case tea.KeyEsc:
+				m.exporting = false
+				m.exportInput.Blur()
+				m.exportArticles = nil
+			default:
+				var cmd tea.Cmd
+				m.exportInput, cmd = m.exportInput.Update(msg)
+				return m, cmd
 			}
-			
-			// 创建新的 AskLLMView 并填充预设内容
-			m.askLLMView = NewAskLLMView(m.width, m.height)
-			if presetPrompt != "" {
-				m.askLLMView.input.SetValue(presetPrompt)
-			}
-			m.currentView = viewAskLLM
 			return m, nil
 		}
 
-		// 摘要视图中，处理滚动（仅用于 LLM 结果浏览）
-		if m.currentView == viewSummary {
-			switch msg.String() {
-			case "j", "down":
-
-				return m, nil
-			case "k", "up":
-
-				return m, nil
-			case "tab", "h", "left", "esc":
-				// Return to previous view
-				m.currentView = viewArticles
-				return m, nil
+		// 配置视图中，将所有键盘输入传递给ConfigView处理
+		if m.currentView == viewConfig {
+			cv, cmd := m.configView.Handle(msg)
+			m.configView = cv
+			if cmd != nil {
+				return m, cmd
 			}
+			return m, nil
 		}
 
-		switch msg.String() {
-		case "q", "ctrl+c":
-			return m, tea.Quit
-
-		case "r":
-			// Refresh feeds
-			m.loading = true
-			m.statusMessage = "Refreshing feeds..."
-			return m, fetchFeeds(m.feedManager)
-
-		case "c":
-			// 切换到配置视图
-			if m.currentView != viewConfig {
-				m.currentView = viewConfig
-				// 更新配置视图中的feeds列表
-				m.configView.UpdateFeeds(m.feedManager.Feeds)
-			}
-
-		case "tab", "h", "left":
-			// Navigate between views
-			if m.currentView == viewArticles {
-				m.currentView = viewFeeds
-			} else if m.currentView == viewArticleDetail {
-				m.currentView = viewArticles
-			} else if m.currentView == viewConfig {
-				// 从配置视图返回到feeds视图
-				m.currentView = viewFeeds
-			}
-
-		case "l", "right", "enter":
-			// Navigate forward
-			if m.currentView == viewFeeds && m.feedsList.Index() >= 0 {
-				m.currentView = viewArticles
-				m.currentFeed = m.feeds[m.feedsList.Index()]
-				m.updateArticlesList()
-			} else if m.currentView == viewArticles && m.articlesList.Index() >= 0 {
-				// 多选模式下回车处理
-				if m.multiSelectMode && len(m.selectedArticleIndexes) > 0 {
-					// 拼接所有选中文章内容
-					var prompt strings.Builder
-					for idx := range m.selectedArticleIndexes {
-						item := m.articlesList.Items()[idx].(Item)
-						article, ok := item.data.(feed.Article)
-						if ok {
-							prompt.WriteString("标题: ")
-							prompt.WriteString(article.Title)
-							prompt.WriteString("\n内容: ")
-							prompt.WriteString(article.Content)
-							prompt.WriteString("\n\n")
-						}
-					}
-					// 跳转 askLLMView 并填充 prompt
-					m.currentView = viewAskLLM
-					m.askLLMView.input.SetValue(prompt.String())
-					m.multiSelectMode = false
-					m.selectedArticleIndexes = nil
-					m.statusMessage = ""
-				} else {
-					// 正常模式进入文章详情
-					m.currentView = viewArticleDetail
-					if len(m.articlesList.Items()) > 0 {
-						item := m.articlesList.SelectedItem().(Item)
-						article := item.data.(feed.Article)
-						m.articleView.SetArticle(article)
-					}
-				}
-			}
-
-		case "j", "down":
-			// Move down
-			if m.currentView == viewFeeds {
-				if m.feedsList.Index() < len(m.feedsList.Items())-1 {
-					m.feedsList.Select(m.feedsList.Index() + 1)
-					// Update articles list when feed selection changes
-					if len(m.feedsList.Items()) > 0 {
-						m.currentFeed = m.feeds[m.feedsList.Index()]
-						m.updateArticlesList()
-					}
-				}
-			} else if m.currentView == viewArticles {
-				if m.multiSelectMode {
-					// 多选模式下，j/k 只移动高亮
-					if m.articlesList.Index() < len(m.articlesList.Items())-1 {
-						m.articlesList.Select(m.articlesList.Index() + 1)
-					}
-				} else {
-					if m.articlesList.Index() < len(m.articlesList.Items())-1 {
-						m.articlesList.Select(m.articlesList.Index() + 1)
-					}
-				}
-			} else if m.currentView == viewArticleDetail {
-				m.articleView.ScrollDown()
-			}
-
-		case "k", "up":
-			// Move up
-			if m.currentView == viewFeeds {
-				if m.feedsList.Index() > 0 {
-					m.feedsList.Select(m.feedsList.Index() - 1)
-					// Update articles list when feed selection changes
-					if len(m.feedsList.Items()) > 0 {
-						m.currentFeed = m.feeds[m.feedsList.Index()]
-						m.updateArticlesList()
-					}
-				}
-			} else if m.currentView == viewArticles {
-				if m.articlesList.Index() > 0 {
-					m.articlesList.Select(m.articlesList.Index() - 1)
-				}
-			} else if m.currentView == viewArticleDetail {
-				m.articleView.ScrollUp()
-			}
-
-		case "v":
-			// 多选模式切换
-			if m.currentView == viewArticles {
-				if !m.multiSelectMode {
-					// 开启多选模式
-					m.multiSelectMode = true
-					m.selectedArticleIndexes = make(map[int]struct{})
-					m.statusMessage = "多选模式：空格选择，回车发送，v退出"
-				} else {
-					// 关闭多选模式
-					m.multiSelectMode = false
-					m.selectedArticleIndexes = nil
-					m.statusMessage = ""
-				}
-				return m, nil
-			}
+		action, count := m.keys.Resolve(keymapView(m.currentView), msg)
+		if action == "" {
+			// Waiting for the rest of a sequence, which may time out
+			return m, m.keys.Timeout()
+		}
+		return m.handleAction(action, count)
 
-		case " ":
-			// 多选模式下的选择/取消
-			if m.currentView == viewArticles && m.multiSelectMode {
-				idx := m.articlesList.Index()
-				if _, ok := m.selectedArticleIndexes[idx]; ok {
-					// 取消选择
-					delete(m.selectedArticleIndexes, idx)
-				} else {
-					// 添加选择
-					m.selectedArticleIndexes[idx] = struct{}{}
-				}
-				return m, nil
-			}
+	case keyTimeoutMsg:
+		// A key that is also the start of a longer sequence runs on its own
+		if action, count := m.keys.Flush(keymapView(m.currentView), msg); action != "" {
+			return m.handleAction(action, count)
 		}
 
 	case tea.WindowSizeMsg:
@@ -425,7 +410,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.feedsList = CreateFeedsList(m.feeds, 30, m.height)
 			m.articlesList = list.New([]list.Item{}, ItemDelegate{}, m.width-34, m.height)
 			m.articleView = NewArticleView(feed.Article{}, m.width-34, m.height)
-			m.configView = NewConfigView(m.feedManager.Feeds, m.width-4, m.height)
+			m.configView = NewConfigView(m.feedManager.Feeds(), m.paths, m.width-4, m.height)
+			m.configView.SetOllama(m.ollamaConfig)
+			m.configView.remote = m.syncer != nil
 
 			// Set list and view dimensions
 			m.resizeComponents()
@@ -442,7 +429,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case fetchStartMsg:
 		m.loading = true
 		m.statusMessage = "Loading feeds..."
-		cmds = append(cmds, fetchFeeds(m.feedManager))
+		cmds = append(cmds, m.refresh())
 
 	case exitConfigMsg:
 		m.currentView = viewFeeds
@@ -453,10 +440,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err != nil {
 			m.errorMessage = fmt.Sprintf("Failed to save config: %v", msg.err)
 		} else {
+			m.applyConfig(msg.cfg)
+			if !msg.feedsChanged {
+				m.statusMessage = "Config saved"
+				if len(m.configWarnings) > 0 {
+					m.errorMessage = "Warning: " + strings.Join(m.configWarnings, "; ")
+					m.configWarnings = nil
+				}
+				return m, nil
+			}
 			// 配置保存成功后重新加载feed数据
 			m.loading = true
 			m.statusMessage = "Config saved, refreshing feeds..."
-			return m, fetchFeeds(m.feedManager)
+			return m, m.refresh()
 		}
 
 	case fetchCompleteMsg:
@@ -469,24 +465,117 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.errorMessage = ""
 			m.statusMessage = "Loaded from cache"
 
-			// 用最新的 feedManager.Feeds 更新 m.feeds
-			m.feeds = []string{"All"}
-			for _, f := range m.feedManager.Feeds {
-				m.feeds = append(m.feeds, f.Name)
+			m.refreshFeedsList()
+		}
+
+		// Cache problems (corrupt files, failed saves) are collected by the feed
+		// manager instead of being printed over the TUI
+		warnings := append(m.configWarnings, m.feedManager.TakeWarnings()...)
+		m.configWarnings = nil
+		if len(warnings) > 0 && m.errorMessage == "" {
+			m.errorMessage = "Warning: " + strings.Join(warnings, "; ")
+		}
+
+	case syncCompleteMsg:
+		m.loading = false
+
+		if msg.err != nil {
+			m.errorMessage = fmt.Sprintf("Error: %v", msg.err)
+			if n, err := m.syncer.Pending(); err == nil && n > 0 {
+				m.errorMessage += fmt.Sprintf(" (%d changes queued)", n)
 			}
-			m.feedsList = CreateFeedsList(m.feeds, 30, m.height-4)
+			m.statusMessage = "Sync failed, working offline"
+		} else {
+			// The sync saved the server's marks; pick them up
+			if err := m.state.Reload(); err != nil {
+				m.configWarnings = append(m.configWarnings, fmt.Sprintf("failed to reload read state: %v", err))
+			}
+			m.feedManager.SetFeeds(msg.result.Feeds)
+			m.errorMessage = ""
+			m.statusMessage = fmt.Sprintf("Synced: %d new, %d changes sent, %d from the server",
+				msg.result.New, msg.result.Pushed, msg.result.Pulled)
+			m.refreshFeedsList()
+		}
 
-			// Make sure we have a valid current feed selected
-			if m.feedsList.Index() >= 0 && m.feedsList.Index() < len(m.feeds) {
-				m.currentFeed = m.feeds[m.feedsList.Index()]
+		warnings := append(m.configWarnings, msg.result.Warnings...)
+		warnings = append(warnings, m.feedManager.TakeWarnings()...)
+		m.configWarnings = nil
+		if len(warnings) > 0 && m.errorMessage == "" {
+			m.errorMessage = "Warning: " + strings.Join(warnings, "; ")
+		}
+
+	case exportCompleteMsg:
+		if msg.err != nil {
+			m.errorMessage = fmt.Sprintf("Export failed: %v", msg.err)
+			break
+		}
+		m.errorMessage = ""
+		m.statusMessage = fmt.Sprintf("Exported %d article(s) to %s", msg.count, msg.path)
+
+	case stateSavedMsg:
+		if msg.err != nil {
+			m.errorMessage = fmt.Sprintf("Failed to save read state: %v", msg.err)
+		}
+
+	case commentsMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.errorMessage = fmt.Sprintf("Failed to fetch comments: %v", msg.err)
+			break
+		}
+		m.errorMessage = ""
+		m.statusMessage = fmt.Sprintf("Loaded %d comments", msg.thread.Count())
+		m.commentsView = NewCommentsView(msg.article, msg.thread, m.width-34, m.height-2)
+		m.currentView = viewComments
+
+	case llmTestMsg:
+		m.configView, _ = m.configView.Handle(msg)
+
+	case configReloadMsg:
+		// config.yaml was edited while gorss is running
+		if msg.reload.Err != nil {
+			// Keep running with the previous config until the file is fixed
+			m.errorMessage = "Config not reloaded: " + strings.ReplaceAll(msg.reload.Err.Error(), "\n", " ")
+		} else {
+			m.applyConfig(msg.reload.Config)
+			m.refreshFeedsList()
+			m.errorMessage = ""
+			m.statusMessage = "Config reloaded"
+			warnings := m.configWarnings
+			m.configWarnings = nil
+			for _, p := range msg.reload.Config.Warnings {
+				warnings = append(warnings, "config "+p.String())
+			}
+			if len(warnings) > 0 {
+				m.errorMessage = "Warning: " + strings.Join(warnings, "; ")
 			}
+		}
+		return m, waitForConfig(m.configWatcher)
 
-			// Update the articles list
-			m.updateArticlesList()
+	case downloadUpdateMsg:
+		// Re-render with the new progress and keep listening
+		return m, waitForDownloads(m.downloads)
 
-			// 更新配置视图中的feeds列表
-			if m.configView != nil {
-				m.configView.UpdateFeeds(m.feedManager.Feeds)
+	case playerExitedMsg:
+		if msg.err != nil {
+			m.errorMessage = fmt.Sprintf("Player failed: %v", msg.err)
+		}
+
+	case fullTextMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.errorMessage = fmt.Sprintf("Failed to fetch full article: %v", msg.err)
+			break
+		}
+		m.errorMessage = ""
+		m.statusMessage = "Full article loaded"
+		if m.articleView.article.Link == msg.article.Link {
+			m.articleView.SetArticle(msg.article)
+		}
+		// Keep the list item in sync so reopening the article shows the full text
+		for i, it := range m.articlesList.Items() {
+			if a, ok := it.(Item).data.(feed.Article); ok && a.Link == msg.article.Link {
+				m.articlesList.SetItem(i, NewArticleItem(msg.article, m.state.IsStarred(msg.article.ID)))
 			}
 		}
 
@@ -561,6 +650,367 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// handleAction performs the action a key binding resolved to in the
+// current view; count is the count typed before it, 0 if none
+func (m Model) handleAction(action string, count int) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	// Movements repeat count times; top and bottom take it as a position
+	n := max(count, 1)
+
+	// Ask LLM entry from feeds, articles, or article detail view
+	if action == actAsk {
+		presetPrompt := ""
+		
+		// 多选模式下处理
+		if m.currentView == viewArticles && m.multiSelectMode && len(m.selectedArticleIndexes) > 0 {
+			// 拼接所有选中文章内容
+			presetPrompt = m.selectedArticlesPrompt()
+			// 退出多选模式
+			m.multiSelectMode = false
+			m.selectedArticleIndexes = nil
+		} else if m.currentView == viewArticleDetail && m.articleView != nil {
+			// 单篇文章详情页处理
+			article := m.articleView.article
+			if article.Title != "" || article.Content != "" {
+				presetPrompt = articlePrompt(article)
+			}
+		}
+		
+		// 创建新的 AskLLMView 并填充预设内容
+		m.askLLMView = NewAskLLMView(m.width, m.height)
+		if presetPrompt != "" {
+			m.askLLMView.SetPrompt(presetPrompt)
+		}
+		m.currentView = viewAskLLM
+		return m, nil
+	}
+
+	// 摘要视图中，处理滚动（仅用于 LLM 结果浏览）
+	if m.currentView == viewSummary {
+		switch action {
+		case actDown, actUp:
+			return m, nil
+		case actBack:
+			// Return to previous view
+			m.currentView = viewArticles
+			return m, nil
+		}
+	}
+
+	// 主题选择：移动光标即预览，Enter 保存，Esc 恢复原主题
+	if m.currentView == viewThemes {
+		switch action {
+		case actQuit:
+			return m, tea.Quit
+		case actDown:
+			m.previewTheme(m.themePicker.cursor + n)
+		case actUp:
+			m.previewTheme(max(m.themePicker.cursor-n, 0))
+		case actTop:
+			m.previewTheme(count - 1)
+		case actBottom:
+			if count == 0 {
+				count = len(m.themePicker.names)
+			}
+			m.previewTheme(count - 1)
+		case actOpen:
+			name := m.themePicker.Selected()
+			m.currentView = m.previousView
+			m.statusMessage = "Theme: " + name
+			return m, saveTheme(m.paths, name)
+		case actBack:
+			m.previewTheme(-1)
+			m.currentView = m.previousView
+		}
+		return m, nil
+	}
+
+	// 下载列表视图
+	if m.currentView == viewDownloads {
+		switch action {
+		case actQuit:
+			return m, tea.Quit
+		case actBack:
+			m.currentView = m.previousView
+		}
+		return m, nil
+	}
+
+	// 评论视图
+	if m.currentView == viewComments {
+		switch action {
+		case actQuit:
+			return m, tea.Quit
+		case actDown:
+			for i := 0; i < n; i++ {
+				m.commentsView.MoveDown()
+			}
+		case actUp:
+			for i := 0; i < n; i++ {
+				m.commentsView.MoveUp()
+			}
+		case actPageDown:
+			for i := 0; i < n*m.commentsView.height/2; i++ {
+				m.commentsView.MoveDown()
+			}
+		case actPageUp:
+			for i := 0; i < n*m.commentsView.height/2; i++ {
+				m.commentsView.MoveUp()
+			}
+		case actTop:
+			m.commentsView.MoveTo(count - 1)
+		case actBottom:
+			if count == 0 {
+				count = len(m.commentsView.visible())
+			}
+			m.commentsView.MoveTo(count - 1)
+		case actToggle:
+			m.commentsView.Toggle()
+		case actRefresh:
+			m.loading = true
+			m.statusMessage = "Refreshing comments..."
+			return m, fetchComments(m.feedManager, m.commentsView.article)
+		case actBack:
+			m.currentView = viewArticleDetail
+		}
+		return m, nil
+	}
+
+	switch action {
+	case actQuit:
+		return m, tea.Quit
+
+	case actComments:
+		// Show the article's comment thread, fetching it if it is not cached
+		if m.currentView == viewArticleDetail && m.articleView.article.Title != "" {
+			article := m.articleView.article
+			if thread, ok := m.feedManager.CachedComments(article); ok {
+				m.commentsView = NewCommentsView(article, thread, m.width-34, m.height-2)
+				m.currentView = viewComments
+				return m, nil
+			}
+			m.loading = true
+			m.statusMessage = "Fetching comments..."
+			return m, fetchComments(m.feedManager, article)
+		}
+
+	case actStar:
+		// Star or unstar the article under the cursor
+		if article, ok := m.currentArticle(); ok {
+			starred := !m.state.IsStarred(article.ID)
+			m.state.SetStarred(article.ID, starred)
+			for i, it := range m.articlesList.Items() {
+				if a, ok := it.(Item).data.(feed.Article); ok && a.ID == article.ID {
+					m.articlesList.SetItem(i, NewArticleItem(a, starred))
+				}
+			}
+			if starred {
+				m.statusMessage = "Starred"
+			} else {
+				m.statusMessage = "Unstarred"
+			}
+			return m, saveState(m.state)
+		}
+
+	case actExport:
+		// Export the selected articles, or all listed ones, or the open article
+		var articles []feed.Article
+		if m.currentView == viewArticles {
+			articles = m.listedArticles()
+		} else if article, ok := m.currentArticle(); ok {
+			articles = []feed.Article{article}
+		}
+		if len(articles) > 0 {
+			m.exportArticles = articles
+			m.exporting = true
+			m.exportInput.Prompt = fmt.Sprintf("Export %d article(s) to %s as (%s): ",
+				len(articles), m.cfg.Export.Directory(), strings.Join(config.ExportFormats, "/"))
+			m.exportInput.SetValue(m.cfg.Export.FormatOrDefault())
+			m.exportInput.CursorEnd()
+			m.exportInput.Focus()
+			if m.multiSelectMode {
+				m.multiSelectMode = false
+				m.selectedArticleIndexes = nil
+			}
+			return m, textinput.Blink
+		}
+
+	case actFilter:
+		// Filter articles by text, author, tag or feed
+		if m.currentView == viewFeeds || m.currentView == viewArticles {
+			m.filtering = true
+			m.filterInput.Focus()
+			return m, textinput.Blink
+		}
+
+	case actTheme:
+		// Pick a theme, previewing each one
+		m.themePicker = NewThemePicker(config.ThemeNames(m.cfg), m.themeName, m.paths.ThemeDir())
+		m.previousView = m.currentView
+		m.currentView = viewThemes
+		return m, nil
+
+	case actDownloads:
+		// Show the download queue
+		m.previousView = m.currentView
+		m.currentView = viewDownloads
+		return m, nil
+
+	case actDownload:
+		// Download every enclosure of the current article
+		if m.currentView == viewArticleDetail && len(m.articleView.article.Enclosures) > 0 {
+			article := m.articleView.article
+			for _, e := range article.Enclosures {
+				m.downloads.Enqueue(e.URL, article.Title)
+			}
+			m.statusMessage = fmt.Sprintf("Queued %d download(s) to %s", len(article.Enclosures), m.downloads.Dir())
+			return m, nil
+		}
+
+	case actPlay:
+		// Play the first enclosure with the external player
+		if m.currentView == viewArticleDetail && len(m.articleView.article.Enclosures) > 0 {
+			return m, playEnclosure(m.downloads, m.player, m.articleView.article.Enclosures[0])
+		}
+
+	case actRefresh:
+		// Refresh feeds
+		m.loading = true
+		m.statusMessage = "Refreshing feeds..."
+		return m, m.refresh()
+
+	case actConfig:
+		// 切换到配置视图
+		if m.currentView != viewConfig {
+			m.currentView = viewConfig
+			// 更新配置视图中的feeds列表
+			m.configView.UpdateFeeds(m.feedManager.Feeds())
+		}
+
+	case actBack:
+		// Navigate between views
+		if m.currentView == viewArticles {
+			m.currentView = viewFeeds
+		} else if m.currentView == viewArticleDetail {
+			m.currentView = viewArticles
+		} else if m.currentView == viewConfig {
+			// 从配置视图返回到feeds视图
+			m.currentView = viewFeeds
+		}
+
+	case actOpen:
+		// Navigate forward
+		if m.currentView == viewFeeds && m.feedsList.Index() >= 0 {
+			m.currentView = viewArticles
+			m.currentFeed = m.feeds[m.feedsList.Index()]
+			m.updateArticlesList()
+		} else if m.currentView == viewArticles && m.articlesList.Index() >= 0 {
+			// 多选模式下回车处理
+			if m.multiSelectMode && len(m.selectedArticleIndexes) > 0 {
+				// 跳转 askLLMView 并填充 prompt
+				m.currentView = viewAskLLM
+				m.askLLMView.input.SetValue(m.selectedArticlesPrompt())
+				m.multiSelectMode = false
+				m.selectedArticleIndexes = nil
+				m.statusMessage = ""
+			} else {
+				// 正常模式进入文章详情
+				m.currentView = viewArticleDetail
+				if len(m.articlesList.Items()) > 0 {
+					item := m.articlesList.SelectedItem().(Item)
+					article := item.data.(feed.Article)
+					m.articleView.SetArticle(article)
+					m.state.SetRead(article.ID, true)
+					cmds = append(cmds, saveState(m.state))
+				}
+			}
+		}
+
+	case actDown:
+		m.moveBy(n)
+
+	case actUp:
+		m.moveBy(-n)
+
+	case actPageDown, actPageUp:
+		if m.currentView == viewArticleDetail {
+			for i := 0; i < n; i++ {
+				if action == actPageDown {
+					m.articleView.ScrollDown()
+				} else {
+					m.articleView.ScrollUp()
+				}
+			}
+		} else if m.currentView == viewFeeds {
+			page := m.feedsList.Paginator.PerPage
+			if action == actPageUp {
+				page = -page
+			}
+			m.moveBy(n * page)
+		} else if m.currentView == viewArticles {
+			page := m.articlesList.Paginator.PerPage
+			if action == actPageUp {
+				page = -page
+			}
+			m.moveBy(n * page)
+		}
+
+	case actTop:
+		// "5gg" goes to the fifth item, like in vim
+		m.moveTo(count - 1)
+
+	case actBottom:
+		// "G" goes to the last item, "5G" to the fifth
+		if count == 0 {
+			m.moveTo(-1)
+		} else {
+			m.moveTo(count - 1)
+		}
+
+	case actFullText:
+		// Fetch the full article for truncated feeds (readability mode)
+		if m.currentView == viewArticleDetail && m.articleView.article.Link != "" {
+			m.loading = true
+			m.statusMessage = "Fetching full article..."
+			return m, fetchFullText(m.feedManager, m.articleView.article)
+		}
+
+	case actMultiSelect:
+		// 多选模式切换
+		if m.currentView == viewArticles {
+			if !m.multiSelectMode {
+				// 开启多选模式
+				m.multiSelectMode = true
+				m.selectedArticleIndexes = make(map[int]struct{})
+				m.statusMessage = "多选模式：" + strings.Join(m.multiSelectHelp(), "，")
+			} else {
+				// 关闭多选模式
+				m.multiSelectMode = false
+				m.selectedArticleIndexes = nil
+				m.statusMessage = ""
+			}
+			return m, nil
+		}
+
+	case actSelect:
+		// 多选模式下的选择/取消
+		if m.currentView == viewArticles && m.multiSelectMode {
+			idx := m.articlesList.Index()
+			if _, ok := m.selectedArticleIndexes[idx]; ok {
+				// 取消选择
+				delete(m.selectedArticleIndexes, idx)
+			} else {
+				// 添加选择
+				m.selectedArticleIndexes[idx] = struct{}{}
+			}
+			return m, nil
+		}
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
 // View renders the UI
 func (m Model) View() string {
 	// 多选高亮同步到 list.go 全局变量
@@ -604,6 +1054,12 @@ func (m Model) View() string {
 		} else {
 			contentView = articleListStyle.Width(m.width - 34).Height(m.height - 4).Render(m.articlesList.View())
 		}
+	case viewDownloads:
+		contentView = renderDownloads(m.downloads, m.width-34, m.height-2)
+	case viewThemes:
+		contentView = m.themePicker.Render(m.width-34, m.height-2)
+	case viewComments:
+		contentView = m.commentsView.Render()
 	case viewConfig:
 		// 配置视图模式
 		contentView = m.configView.Render()
@@ -614,29 +1070,57 @@ func (m Model) View() string {
 
 	// Status bar
 	var statusBar string
-	if m.loading {
+	if m.filtering {
+		statusBar = statusBarStyle.Render(m.filterInput.View())
+	} else if m.exporting {
+		statusBar = statusBarStyle.Render(m.exportInput.View())
+	} else if m.loading {
 		statusBar = statusBarStyle.Render("Loading...")
 	} else if m.errorMessage != "" {
-		statusBar = statusBarStyle.Copy().Foreground(lipgloss.Color("#FF0000")).Render(m.errorMessage)
+		statusBar = statusErrorStyle.Render(sanitizeLine(m.errorMessage))
 	} else if m.currentView == viewArticles && m.multiSelectMode {
 		// 多选模式下显示特殊状态栏
 		selectedCount := len(m.selectedArticleIndexes)
-		statusText := fmt.Sprintf("多选模式 | 已选择: %d | 空格: 选择/取消 | 回车/a: 发送至LLM | v: 退出", selectedCount)
-		statusBar = statusBarStyle.Copy().Foreground(bubbleTeaColor).Render(statusText)
+		statusText := fmt.Sprintf("多选模式 | 已选择: %d | %s", selectedCount, strings.Join(m.multiSelectHelp(), " | "))
+		statusBar = statusMultiSelectStyle.Render(statusText)
 	} else {
-		help := []string{
-			"j/k: navigate",
-			"h/l: change view",
-			"enter: select",
-			"r: refresh",
-			"a: ask LLM",
-			"c: config",
-			"q: quit",
-		}
+		// Generated from the keymap, so it shows the user's own bindings
+		view := keymapView(m.currentView)
+		help := m.keys.HelpPair(view, actDown, actUp, "navigate")
+		help = append(help, m.keys.HelpPair(view, actBack, actOpen, "change view")...)
+		help = append(help, m.keys.Help(view, actRefresh, actAsk, actConfig, actQuit)...)
 
-		// 在 articles 页面显示 v 多选提示
+		// 在 articles 页面显示多选提示
 		if m.currentView == viewArticles {
-			help = append(help, "v: multi-select")
+			help = append(help, m.keys.Help(view, actMultiSelect, actStar, actExport)...)
+		}
+		if m.currentView == viewArticleDetail {
+			help = append(help, m.keys.Help(view, actFullText, actComments, actStar)...)
+			if len(m.articleView.article.Enclosures) > 0 {
+				help = append(help, m.keys.Help(view, actDownload, actPlay)...)
+			}
+		}
+		help = append(help, m.keys.Help(view, actDownloads)...)
+		if m.currentView == viewFeeds || m.currentView == viewArticles {
+			if m.filter.Empty() {
+				help = append(help, m.keys.Help(view, actFilter)...)
+			} else {
+				help = append(help, "filter: "+m.filterInput.Value())
+			}
+		}
+		if m.currentView == viewThemes {
+			help = m.keys.HelpPair(view, actDown, actUp, "navigate")
+			help = append(help, m.keys.Help(view, actOpen, actBack)...)
+		} else {
+			help = append(help, m.keys.Help(view, actTheme)...)
+		}
+		if m.currentView == viewComments {
+			help = m.keys.HelpPair(view, actDown, actUp, "navigate")
+			help = append(help, m.keys.Help(view, actToggle, actRefresh, actBack, actQuit)...)
+		}
+		if pending := m.keys.Pending(); pending != "" {
+			// A count or sequence is being typed
+			help = append([]string{pending}, help...)
 		}
 		statusBar = statusBarStyle.Render(strings.Join(help, " • "))
 	}
@@ -655,6 +1139,240 @@ func (m Model) View() string {
 	)
 }
 
+// applyConfig puts a (re)loaded config into effect: feeds, link rewriting,
+// LLM settings, downloads, key bindings and the theme. Callers rebuild the feeds list after.
+func (m *Model) applyConfig(cfg *config.Config) {
+	m.paths = cfg.Paths
+	m.feedManager.SetFeeds(greader.Feeds(cfg))
+	m.syncer = nil
+	if cfg.Backend.Remote() {
+		m.syncer = greader.NewSyncer(cfg.Backend, m.feedManager, cfg.Paths)
+	}
+	m.feedManager.SetStripParams(cfg.Links.StripParams)
+	m.ollamaConfig = cfg.Ollama.WithDefaults()
+	m.downloads.SetDir(cfg.Downloads.Directory())
+	m.player = cfg.Downloads.PlayerCommand()
+	m.keys = NewKeymap(cfg.Keys)
+	m.cfg = cfg
+	name, err := applyTheme(cfg, cfg.Theme)
+	if err != nil {
+		m.configWarnings = append(m.configWarnings, fmt.Sprintf("%v; using the %s theme", err, name))
+	}
+	m.themeName = name
+	m.restyleLists()
+	if m.configView != nil {
+		m.configView.paths = cfg.Paths
+		m.configView.SetOllama(m.ollamaConfig)
+		m.configView.remote = m.syncer != nil
+	}
+}
+
+// refreshFeedsList rebuilds the feeds list from feedManager.Feeds, keeping the selected feed
+func (m *Model) refreshFeedsList() {
+	// 用最新的 feedManager.Feeds 更新 m.feeds
+	m.feeds = []string{"All"}
+	for _, f := range m.feedManager.Feeds() {
+		m.feeds = append(m.feeds, f.Name)
+	}
+	m.feedsList = CreateFeedsList(m.feeds, 30, m.height-4)
+
+	// Keep the current feed selected; if it is gone, fall back to "All"
+	selected := 0
+	for i, name := range m.feeds {
+		if strings.EqualFold(name, m.currentFeed) {
+			selected = i
+			break
+		}
+	}
+	m.feedsList.Select(selected)
+	m.currentFeed = m.feeds[selected]
+
+	// Update the articles list
+	m.updateArticlesList()
+
+	// 更新配置视图中的feeds列表
+	if m.configView != nil {
+		m.configView.UpdateFeeds(m.feedManager.Feeds())
+	}
+}
+
+// previewTheme applies the i-th theme of the picker; -1 restores the theme
+// the picker was opened with
+func (m *Model) previewTheme(i int) {
+	name := m.themePicker.original
+	if i >= 0 {
+		m.themePicker.MoveTo(i)
+		name = m.themePicker.Selected()
+	}
+	if applied, err := applyTheme(m.cfg, name); err != nil {
+		m.errorMessage = err.Error()
+	} else {
+		m.errorMessage = ""
+		m.themeName = applied
+	}
+	m.restyleLists()
+}
+
+// restyleLists gives the lists the styles of the current theme
+func (m *Model) restyleLists() {
+	for _, l := range []*list.Model{&m.feedsList, &m.articlesList} {
+		l.Styles.Title = titleStyle
+		l.Styles.PaginationStyle = helpStyle
+		l.Styles.HelpStyle = helpStyle
+	}
+}
+
+// keymapView names a view the way the keys section of the config does
+func keymapView(view int) string {
+	switch view {
+	case viewFeeds:
+		return "feeds"
+	case viewArticles:
+		return "articles"
+	case viewArticleDetail:
+		return "article"
+	case viewSummary:
+		return "summary"
+	case viewDownloads:
+		return "downloads"
+	case viewComments:
+		return "comments"
+	case viewThemes:
+		return "themes"
+	}
+	return ""
+}
+
+// moveBy moves the selection (or the article text) down by n, or up if n is negative
+func (m *Model) moveBy(n int) {
+	switch m.currentView {
+	case viewFeeds:
+		m.moveTo(m.feedsList.Index() + n)
+	case viewArticles:
+		m.moveTo(m.articlesList.Index() + n)
+	case viewArticleDetail:
+		for ; n > 0; n-- {
+			m.articleView.ScrollDown()
+		}
+		for ; n < 0; n++ {
+			m.articleView.ScrollUp()
+		}
+	}
+}
+
+// moveTo selects the i-th feed or article, clamped to the list; -1 selects the last
+func (m *Model) moveTo(i int) {
+	switch m.currentView {
+	case viewFeeds:
+		last := len(m.feedsList.Items()) - 1
+		if i < 0 || i > last {
+			i = last
+		}
+		if i >= 0 && i != m.feedsList.Index() {
+			m.feedsList.Select(i)
+			// Update articles list when feed selection changes
+			m.currentFeed = m.feeds[i]
+			m.updateArticlesList()
+		}
+	case viewArticles:
+		last := len(m.articlesList.Items()) - 1
+		if i < 0 || i > last {
+			i = last
+		}
+		if i >= 0 {
+			m.articlesList.Select(i)
+		}
+	case viewArticleDetail:
+		if i < 0 {
+			m.articleView.ScrollToEnd()
+		} else {
+			m.articleView.ScrollToTop()
+		}
+	}
+}
+
+// selectedArticlesPrompt 拼接多选模式下选中文章的内容，最多 MaxArticles 篇
+// articlePrompt is the preset prompt for asking about one article: its title
+// and the text of its content, sanitized like everything else from a feed
+func articlePrompt(article feed.Article) string {
+	return fmt.Sprintf("Title: %s\nContent: %s\n\n", sanitizeLine(article.Title), cleanHTMLContent(article.Content))
+}
+
+func (m *Model) selectedArticlesPrompt() string {
+	indexes := make([]int, 0, len(m.selectedArticleIndexes))
+	for idx := range m.selectedArticleIndexes {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	var prompt strings.Builder
+	count := 0
+	for _, idx := range indexes {
+		// Keep the prompt within the configured number of articles
+		if m.ollamaConfig.MaxArticles > 0 && count >= m.ollamaConfig.MaxArticles {
+			break
+		}
+		item := m.articlesList.Items()[idx].(Item)
+		article, ok := item.data.(feed.Article)
+		if ok {
+			prompt.WriteString("标题: ")
+			prompt.WriteString(sanitizeLine(article.Title))
+			prompt.WriteString("\n内容: ")
+			prompt.WriteString(cleanHTMLContent(article.Content))
+			prompt.WriteString("\n\n")
+			count++
+		}
+	}
+	return prompt.String()
+}
+
+// currentArticle returns the article under the cursor in the articles view,
+// or the open one in the article view
+func (m *Model) currentArticle() (feed.Article, bool) {
+	switch m.currentView {
+	case viewArticles:
+		if item, ok := m.articlesList.SelectedItem().(Item); ok {
+			article, ok := item.data.(feed.Article)
+			return article, ok
+		}
+	case viewArticleDetail:
+		return m.articleView.article, m.articleView.article.ID != ""
+	}
+	return feed.Article{}, false
+}
+
+// listedArticles returns the articles picked in multi-select mode, or else
+// every article in the list (the current feed and filter)
+func (m *Model) listedArticles() []feed.Article {
+	var articles []feed.Article
+	for i, it := range m.articlesList.Items() {
+		if m.multiSelectMode && len(m.selectedArticleIndexes) > 0 {
+			if _, picked := m.selectedArticleIndexes[i]; !picked {
+				continue
+			}
+		}
+		if a, ok := it.(Item).data.(feed.Article); ok {
+			articles = append(articles, a)
+		}
+	}
+	return articles
+}
+
+// multiSelectHelp 多选模式下的按键提示
+func (m *Model) multiSelectHelp() []string {
+	help := m.keys.Help("articles", actSelect)
+	if b := m.keys.Binding("articles", actOpen); b.Enabled() {
+		help = append(help, b.Help().Key+": 发送至LLM")
+	}
+	if b := m.keys.Binding("articles", actExport); b.Enabled() {
+		help = append(help, b.Help().Key+": 导出")
+	}
+	if b := m.keys.Binding("articles", actMultiSelect); b.Enabled() {
+		help = append(help, b.Help().Key+": 退出")
+	}
+	return help
+}
+
 // resizeComponents updates the size of UI components
 func (m *Model) resizeComponents() {
 	m.feedsList.SetSize(30, m.height-4)
@@ -663,6 +1381,9 @@ func (m *Model) resizeComponents() {
 	if m.configView != nil {
 		m.configView.SetSize(m.width-4, m.height-4)
 	}
+	if m.commentsView != nil {
+		m.commentsView.SetSize(m.width-34, m.height-2)
+	}
 	if m.askLLMView != nil {
 		m.askLLMView.width = m.width
 		m.askLLMView.height = m.height
@@ -679,16 +1400,27 @@ func (m *Model) updateArticlesList() {
 		filteredArticles = articles
 	} else {
 		for _, a := range articles {
-			if a.FeedName == m.currentFeed {
+			// Mail sources use the sender as FeedName, so also match on the configured source
+			if a.FeedName == m.currentFeed || a.Source == m.currentFeed {
 				filteredArticles = append(filteredArticles, a)
 			}
 		}
 	}
 
+	if !m.filter.Empty() {
+		var matched []feed.Article
+		for _, a := range filteredArticles {
+			if m.filter.MatchState(a, m.state) {
+				matched = append(matched, a)
+			}
+		}
+		filteredArticles = matched
+	}
+
 	// 按时间排序文章（从新到旧）
 	sort.Slice(filteredArticles, func(i, j int) bool {
 		return filteredArticles[i].Published.After(filteredArticles[j].Published)
 	})
 
-	m.articlesList = CreateArticlesList(filteredArticles, m.width-34, m.height-4)
+	m.articlesList = CreateArticlesList(filteredArticles, m.state, m.width-34, m.height-4)
 }
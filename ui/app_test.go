@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/feed"
+)
+
+func TestReloadKeepsSelectedFeed(t *testing.T) {
+	dir := t.TempDir()
+	paths := config.Paths{ConfigFile: filepath.Join(dir, "config.yaml"), CacheDir: filepath.Join(dir, "cache"), StateDir: filepath.Join(dir, "state")}
+	feeds := func(names ...string) []config.Feed {
+		var out []config.Feed
+		for _, n := range names {
+			out = append(out, config.Feed{Name: n, URL: "https://example.com/" + n})
+		}
+		return out
+	}
+	cfg := &config.Config{Paths: paths, Feeds: feeds("A", "B", "C")}
+	m := NewModel(feed.NewFeedManager(cfg.Feeds, paths.CacheDir), cfg)
+	defer m.Close()
+
+	m.feedsList.Select(2)
+	m.currentFeed = "B"
+
+	reload := func(names ...string) {
+		cfg := &config.Config{Paths: paths, Feeds: feeds(names...)}
+		m.applyConfig(cfg)
+		m.refreshFeedsList()
+	}
+	reload("C", "D", "B", "A")
+	if m.currentFeed != "B" || m.feedsList.Index() != 3 {
+		t.Errorf("after reordering: current %q at %d, want B at 3", m.currentFeed, m.feedsList.Index())
+	}
+	if got := len(m.feedManager.Feeds()); got != 4 {
+		t.Errorf("feed manager has %d feeds, want 4", got)
+	}
+
+	reload("A", "C")
+	if m.currentFeed != "All" || m.feedsList.Index() != 0 {
+		t.Errorf("after removing it: current %q at %d, want All at 0", m.currentFeed, m.feedsList.Index())
+	}
+}
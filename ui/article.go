@@ -6,6 +6,7 @@ import (
 
 	"github.com/JohanLi233/gorss/feed"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 	"golang.org/x/net/html"
 )
 
@@ -51,53 +52,19 @@ func (av *ArticleView) processContent() {
 		return
 	}
 
-	// Clean HTML content
-	content := cleanHTMLContent(av.article.Content)
-
 	// Allow for title, metadata, and padding
 	contentWidth := av.width - 6 // Account for padding and borders
 
-	// Split the content into lines and handle wrapping
-	lines := strings.Split(content, "\n")
-	av.contentLines = []string{}
-
-	for _, line := range lines {
-		// Skip empty lines
-		if strings.TrimSpace(line) == "" {
-			av.contentLines = append(av.contentLines, "")
-			continue
-		}
-
-		// Handle line wrapping for long lines
-		for len(line) > 0 {
-			// Trim leading whitespace after wrapping
-			line = strings.TrimLeftFunc(line, func(r rune) bool {
-				return r == ' ' || r == '\t'
-			})
-
-			// If line is now empty after trimming, break
-			if len(line) == 0 {
-				break
-			}
-
-			if len(line) <= contentWidth {
-				av.contentLines = append(av.contentLines, line)
-				break
-			}
-
-			// Find a good breaking point
-			wrapIdx := contentWidth
-			for wrapIdx > 0 && !isBreakableChar(line[wrapIdx]) {
-				wrapIdx--
-			}
+	if av.article.ContentType == feed.GemtextContentType {
+		av.contentLines = renderGemtext(sanitizeText(av.article.Content), contentWidth)
+	} else {
+		// Clean HTML content
+		content := cleanHTMLContent(av.article.Content)
 
-			// If we couldn't find a good break point, force a break
-			if wrapIdx == 0 {
-				wrapIdx = contentWidth
-			}
-
-			av.contentLines = append(av.contentLines, line[:wrapIdx])
-			line = line[wrapIdx:]
+		// Split the content into lines and handle wrapping
+		av.contentLines = []string{}
+		for _, line := range strings.Split(content, "\n") {
+			av.contentLines = append(av.contentLines, wrapLine(line, contentWidth)...)
 		}
 	}
 
@@ -110,22 +77,69 @@ func (av *ArticleView) processContent() {
 	av.contentLines = append(av.contentLines, "", "", "", "", "")
 }
 
+// wrapLine splits a single line into chunks no wider than width, breaking at
+// punctuation or spaces where possible. Blank lines are kept as one empty line.
+func wrapLine(line string, width int) []string {
+	// Skip empty lines
+	if strings.TrimSpace(line) == "" {
+		return []string{""}
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	var wrapped []string
+	// Handle line wrapping for long lines
+	for len(line) > 0 {
+		// Trim leading whitespace after wrapping
+		line = strings.TrimLeftFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t'
+		})
+
+		// If line is now empty after trimming, break
+		if len(line) == 0 {
+			break
+		}
+
+		if len(line) <= width {
+			wrapped = append(wrapped, line)
+			break
+		}
+
+		// Find a good breaking point
+		wrapIdx := width
+		for wrapIdx > 0 && !isBreakableChar(line[wrapIdx]) {
+			wrapIdx--
+		}
+
+		// If we couldn't find a good break point, force a break
+		if wrapIdx == 0 {
+			wrapIdx = width
+		}
+
+		wrapped = append(wrapped, line[:wrapIdx])
+		line = line[wrapIdx:]
+	}
+	return wrapped
+}
+
 // isBreakableChar checks if a character is suitable for line breaking
 func isBreakableChar(c byte) bool {
 	return c == ' ' || c == ',' || c == '.' || c == ';' || c == ':' || c == '-'
 }
 
-// cleanHTMLContent removes HTML tags from content
+// cleanHTMLContent removes HTML tags from content. The result is sanitized
+// after parsing, since entities such as &#27; only become control characters then.
 func cleanHTMLContent(content string) string {
 	doc, err := html.Parse(strings.NewReader(content))
 	if err != nil {
-		return content
+		return sanitizeText(content)
 	}
 
 	var textContent strings.Builder
 	extractText(doc, &textContent)
 
-	return strings.TrimSpace(textContent.String())
+	return strings.TrimSpace(sanitizeText(textContent.String()))
 }
 
 // extractText recursively extracts text content from HTML nodes
@@ -155,15 +169,37 @@ func (av *ArticleView) Render() string {
 	}
 
 	// Render title
-	title := articleTitleStyle.Render(av.article.Title)
+	title := articleTitleStyle.Render(sanitizeLine(av.article.Title))
 
 	// Render metadata
-	pubTime := av.article.Published.Format("2006-01-02 15:04")
-	metadata := articleMetaStyle.Render(fmt.Sprintf("Published: %s | Source: %s", pubTime, av.article.FeedName))
+	metadata := articleMetaStyle.Width(av.width - 6).Render(articleMetadata(av.article))
+
+	// Render enclosures (podcast episodes, videos, ...)
+	header := []string{title, metadata}
+	if av.article.Image != "" {
+		header = append(header, articleMetaStyle.Render("Image: "+sanitizeLine(av.article.Image)))
+	}
+	if len(av.article.Enclosures) > 0 {
+		var lines []string
+		for _, e := range av.article.Enclosures {
+			line := "▶ " + sanitizeLine(e.URL)
+			if e.Type != "" {
+				line += " · " + sanitizeLine(e.Type)
+			}
+			if e.Length > 0 {
+				line += " · " + formatBytes(e.Length)
+			}
+			if av.width > 10 {
+				line = runewidth.Truncate(line, av.width-6, "...")
+			}
+			lines = append(lines, line)
+		}
+		header = append(header, enclosureStyle.Render(strings.Join(lines, "\n")))
+	}
 
 	// Calculate available height for content - use exact height calculation
-	headerHeight := lipgloss.Height(title) + lipgloss.Height(metadata) + 1 // +1 for margins
-	contentHeight := av.height - headerHeight - 2                          // -2 for padding (reduced)
+	headerHeight := lipgloss.Height(strings.Join(header, "\n")) + 1 // +1 for margins
+	contentHeight := av.height - headerHeight - 2                   // -2 for padding (reduced)
 
 	// Ensure we have enough lines to display
 	totalLines := len(av.contentLines)
@@ -220,15 +256,64 @@ func (av *ArticleView) Render() string {
 	// Combine everything
 	full := lipgloss.JoinVertical(
 		lipgloss.Left,
-		title,
-		metadata,
-		content,
+		append(header, content)...,
 	)
 
 	// Make sure we use Height() instead of MaxHeight() to use all available space
 	return articleViewStyle.Width(av.width).Height(av.height).Render(full)
 }
 
+// articleMetadata builds the metadata line shown under the article title
+func articleMetadata(a feed.Article) string {
+	parts := []string{"Published: " + a.Published.Format("2006-01-02 15:04")}
+	if !a.Updated.IsZero() && !a.Updated.Equal(a.Published) {
+		parts = append(parts, "Updated: "+a.Updated.Format("2006-01-02 15:04"))
+	}
+	parts = append(parts, "Source: "+a.FeedName)
+	if len(a.Authors) > 0 {
+		parts = append(parts, "By: "+strings.Join(a.Authors, ", "))
+	}
+	if len(a.Categories) > 0 {
+		parts = append(parts, "Tags: "+strings.Join(a.Categories, ", "))
+	}
+	if a.CommentCount > 0 {
+		parts = append(parts, fmt.Sprintf("Comments: %d", a.CommentCount))
+	} else if a.CommentsURL != "" || a.CommentsFeed != "" {
+		parts = append(parts, "Comments available")
+	}
+	return sanitizeLine(strings.Join(parts, " | "))
+}
+
+// ArticleText renders an article as plain text without styling, for output
+// outside the TUI: title, metadata, link and the cleaned content
+func ArticleText(a feed.Article) string {
+	var sb strings.Builder
+	sb.WriteString(sanitizeLine(a.Title) + "\n")
+	sb.WriteString(articleMetadata(a) + "\n")
+	if a.Link != "" {
+		sb.WriteString(sanitizeLine(a.Link) + "\n")
+	}
+	for _, e := range a.Enclosures {
+		sb.WriteString("Enclosure: " + sanitizeLine(e.URL) + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(ArticleBody(a) + "\n")
+	return sb.String()
+}
+
+// ArticleBody returns the content of an article as sanitized plain text,
+// falling back to the description
+func ArticleBody(a feed.Article) string {
+	content := a.Content
+	if content == "" {
+		content = a.Description
+	}
+	if a.ContentType == feed.GemtextContentType {
+		return strings.TrimSpace(sanitizeText(content))
+	}
+	return cleanHTMLContent(content)
+}
+
 // ScrollUp moves the view up by one page
 func (av *ArticleView) ScrollUp() {
 	// Calculate visible height
@@ -278,3 +363,19 @@ func (av *ArticleView) ScrollDown() {
 		}
 	}
 }
+
+// ScrollToTop moves the view to the start of the article
+func (av *ArticleView) ScrollToTop() {
+	av.scrollOffset = 0
+}
+
+// ScrollToEnd moves the view to the end of the article
+func (av *ArticleView) ScrollToEnd() {
+	for {
+		before := av.scrollOffset
+		av.ScrollDown()
+		if av.scrollOffset == before {
+			return
+		}
+	}
+}
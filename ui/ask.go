@@ -36,6 +36,12 @@ func NewAskLLMView(width, height int) *AskLLMView {
 	}
 }
 
+// SetPrompt fills the input with a preset prompt. The text input only drops
+// control characters, so the prompt is sanitized first.
+func (v *AskLLMView) SetPrompt(prompt string) {
+	v.input.SetValue(sanitizeText(prompt))
+}
+
 func (v *AskLLMView) Update(msg tea.Msg) (*AskLLMView, bool, string) {
 	switch m := msg.(type) {
 	case tea.KeyMsg:
@@ -105,10 +111,7 @@ func (v *AskLLMView) scrollDown() {
 }
 
 func (v *AskLLMView) View(result ...string) string {
-	box := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#4B7BEC")).
-		Padding(1, 2).
+	box := askBoxStyle.Copy().
 		Width(v.width).
 		Height(v.height)
 
@@ -117,23 +120,15 @@ func (v *AskLLMView) View(result ...string) string {
 
 	// 回复模式：显示提问和回复内容
 	if hasResult {
-		// 创建标题样式
-		promptStyle := lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#2E86DE"))
-
-		answerTitleStyle := lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#10AC84"))
-
 		// 获取问题内容
-		question := v.input.Value()
+		question := sanitizeText(v.input.Value())
 		if question == "" {
 			question = "请输入问题"
 		}
 
 		// 分割并包装内容为行
-		allLines := strings.Split(result[0], "\n")
+		// 回复可能复述文章中的转义序列，显示前先清理
+		allLines := strings.Split(sanitizeText(result[0]), "\n")
 		contentWidth := v.width - 8 // 考虑padding和border
 		var wrappedLines []string
 		for _, line := range allLines {
@@ -208,10 +203,10 @@ func (v *AskLLMView) View(result ...string) string {
 		// 构建显示内容
 		content := lipgloss.JoinVertical(
 			lipgloss.Left,
-			promptStyle.Render("问题:"),
+			askPromptStyle.Render("问题:"),
 			question,
 			"",
-			answerTitleStyle.Render("LLM 回复:"),
+			askAnswerTitleStyle.Render("LLM 回复:"),
 			visibleContent,
 			"",
 			pageInfo,
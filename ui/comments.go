@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JohanLi233/gorss/feed"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// commentsMsg carries a fetched (or cached) comment thread
+type commentsMsg struct {
+	article feed.Article
+	thread  feed.CommentThread
+	err     error
+}
+
+// commentNode is a comment flattened for display, with its position in the tree
+type commentNode struct {
+	comment *feed.Comment
+	depth   int
+	path    string // stable id used to remember collapsed comments
+}
+
+// CommentsView shows an article's discussion as an indented, collapsible thread
+type CommentsView struct {
+	article   feed.Article
+	thread    feed.CommentThread
+	width     int
+	height    int
+	cursor    int             // index into visible()
+	offset    int             // first rendered line
+	collapsed map[string]bool // paths of collapsed comments
+}
+
+// NewCommentsView creates a comments view for an article's thread
+func NewCommentsView(article feed.Article, thread feed.CommentThread, width, height int) *CommentsView {
+	return &CommentsView{
+		article:   article,
+		thread:    thread,
+		width:     width,
+		height:    height,
+		collapsed: make(map[string]bool),
+	}
+}
+
+// SetSize updates the width and height of the view
+func (cv *CommentsView) SetSize(width, height int) {
+	cv.width = width
+	cv.height = height
+}
+
+// visible returns the comments that are not hidden inside a collapsed parent
+func (cv *CommentsView) visible() []commentNode {
+	var nodes []commentNode
+	var walk func(comments []feed.Comment, depth int, prefix string)
+	walk = func(comments []feed.Comment, depth int, prefix string) {
+		for i := range comments {
+			path := fmt.Sprintf("%s/%d", prefix, i)
+			nodes = append(nodes, commentNode{comment: &comments[i], depth: depth, path: path})
+			if !cv.collapsed[path] {
+				walk(comments[i].Children, depth+1, path)
+			}
+		}
+	}
+	walk(cv.thread.Comments, 0, "")
+	return nodes
+}
+
+// MoveDown moves the cursor to the next visible comment
+func (cv *CommentsView) MoveDown() {
+	if cv.cursor < len(cv.visible())-1 {
+		cv.cursor++
+	}
+}
+
+// MoveUp moves the cursor to the previous visible comment
+func (cv *CommentsView) MoveUp() {
+	if cv.cursor > 0 {
+		cv.cursor--
+	}
+}
+
+// MoveTo moves the cursor to the i-th visible comment, clamped to the thread
+func (cv *CommentsView) MoveTo(i int) {
+	if last := len(cv.visible()) - 1; i > last {
+		i = last
+	}
+	if i < 0 {
+		i = 0
+	}
+	cv.cursor = i
+}
+
+// Toggle collapses or expands the replies of the comment under the cursor
+func (cv *CommentsView) Toggle() {
+	nodes := cv.visible()
+	if cv.cursor < len(nodes) && len(nodes[cv.cursor].comment.Children) > 0 {
+		path := nodes[cv.cursor].path
+		cv.collapsed[path] = !cv.collapsed[path]
+	}
+}
+
+// replyCount counts all replies below a comment
+func replyCount(c *feed.Comment) int {
+	n := len(c.Children)
+	for i := range c.Children {
+		n += replyCount(&c.Children[i])
+	}
+	return n
+}
+
+// relativeTime formats a timestamp as a short "3h ago" style age
+func relativeTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+	return t.Format("2006-01-02")
+}
+
+// Render displays the thread, keeping the selected comment on screen
+func (cv *CommentsView) Render() string {
+	title := articleTitleStyle.Render("Comments: " + sanitizeLine(cv.article.Title))
+	meta := articleMetaStyle.Render(fmt.Sprintf("%d comments | %s | fetched %s",
+		cv.thread.Count(), sanitizeLine(cv.thread.Source), relativeTime(cv.thread.Fetched)))
+
+	nodes := cv.visible()
+	if cv.cursor >= len(nodes) {
+		cv.cursor = len(nodes) - 1
+	}
+	if cv.cursor < 0 {
+		cv.cursor = 0
+	}
+
+	var lines []string
+	cursorStart, cursorEnd := 0, 0
+	for i, node := range nodes {
+		indent := strings.Repeat("  ", node.depth)
+		guide := ""
+		if node.depth > 0 {
+			guide = commentGuideStyle.Render("│ ")
+		}
+
+		marker := "▾"
+		if len(node.comment.Children) == 0 {
+			marker = "•"
+		} else if cv.collapsed[node.path] {
+			marker = fmt.Sprintf("▸ [+%d]", replyCount(node.comment))
+		}
+
+		header := fmt.Sprintf("%s %s %s", marker, sanitizeLine(node.comment.Author), relativeTime(node.comment.Published))
+		style := commentAuthorStyle
+		if i == cv.cursor {
+			style = selectedArticleStyle
+			cursorStart = len(lines)
+		}
+		lines = append(lines, indent+guide+style.Render(header))
+
+		if !cv.collapsed[node.path] || len(node.comment.Children) == 0 {
+			bodyWidth := cv.width - 6 - len(indent) - 2
+			for _, para := range strings.Split(cleanHTMLContent(node.comment.Content), "\n") {
+				for _, l := range wrapLine(para, bodyWidth) {
+					lines = append(lines, indent+guide+l)
+				}
+			}
+		}
+		lines = append(lines, "")
+		if i == cv.cursor {
+			cursorEnd = len(lines)
+		}
+	}
+	if len(nodes) == 0 {
+		lines = append(lines, "No comments yet.")
+	}
+
+	// Scroll so the selected comment is visible
+	contentHeight := cv.height - lipgloss.Height(title) - lipgloss.Height(meta) - 4
+	if contentHeight < 1 {
+		contentHeight = 1
+	}
+	if cursorStart < cv.offset {
+		cv.offset = cursorStart
+	}
+	if cursorEnd > cv.offset+contentHeight {
+		cv.offset = cursorEnd - contentHeight
+		if cv.offset > cursorStart {
+			cv.offset = cursorStart
+		}
+	}
+	end := cv.offset + contentHeight
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if cv.offset > end {
+		cv.offset = end
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, title, meta, strings.Join(lines[cv.offset:end], "\n"))
+	return articleViewStyle.Width(cv.width).Height(cv.height).Render(content)
+}
@@ -2,9 +2,11 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/JohanLi233/gorss/config"
+	"github.com/JohanLi233/gorss/llm"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -12,22 +14,42 @@ import (
 
 // 用于配置编辑的消息类型
 type saveConfigMsg struct{ err error }
+type llmTestMsg struct{ err error }
+
+// LLM 设置页的字段
+const (
+	llmFieldURL = iota
+	llmFieldModel
+	llmFieldTimeout
+	llmFieldMaxArticles
+	llmFieldEnabled
+	llmFieldCount
+)
 
 // ConfigView 表示配置界面
 type ConfigView struct {
 	feeds       []config.Feed
+	paths       config.Paths // 保存的目标配置
 	width       int
 	height      int
 	cursor      int
-	mode        string // "view", "add", "edit", "delete"
+	mode        string // "view", "add", "edit", "delete", "llm"
 	activeInput int
 	nameInput   textinput.Model
 	urlInput    textinput.Model
 	message     string
+	remote      bool // 订阅由同步服务器管理（backend.type: greader），不能在这里修改
+
+	// LLM 设置页
+	ollama     config.OllamaConfig // 当前生效的设置
+	llmInputs  []textinput.Model   // URL、模型、超时、最大文章数
+	llmEnabled bool
+	llmField   int
+	testing    bool // 正在测试连接
 }
 
 // NewConfigView 创建一个新的配置视图
-func NewConfigView(feeds []config.Feed, width, height int) *ConfigView {
+func NewConfigView(feeds []config.Feed, paths config.Paths, width, height int) *ConfigView {
 	nameInput := textinput.New()
 	nameInput.Placeholder = "Feed 名称"
 	nameInput.Focus()
@@ -39,17 +61,32 @@ func NewConfigView(feeds []config.Feed, width, height int) *ConfigView {
 	urlInput.CharLimit = 100
 	urlInput.Width = 40
 
+	llmInputs := make([]textinput.Model, llmFieldEnabled)
+	for i, placeholder := range []string{"http://localhost:11434", "qwen3:32b", "秒", "篇"} {
+		llmInputs[i] = textinput.New()
+		llmInputs[i].Placeholder = placeholder
+		llmInputs[i].CharLimit = 100
+		llmInputs[i].Width = 40
+	}
+
 	return &ConfigView{
 		feeds:       feeds,
+		paths:       paths,
 		width:       width,
 		height:      height,
 		mode:        "view",
 		nameInput:   nameInput,
 		urlInput:    urlInput,
 		activeInput: 0,
+		llmInputs:   llmInputs,
 	}
 }
 
+// SetOllama 更新 LLM 设置页显示的当前设置
+func (cv *ConfigView) SetOllama(settings config.OllamaConfig) {
+	cv.ollama = settings
+}
+
 // SetSize 更新视图的大小
 func (cv *ConfigView) SetSize(width, height int) {
 	cv.width = width
@@ -65,6 +102,8 @@ func (cv *ConfigView) Render() string {
 		return cv.renderEditMode()
 	case "delete":
 		return cv.renderDeleteConfirmation()
+	case "llm":
+		return cv.renderLLMSettings()
 	default:
 		return cv.renderViewMode()
 	}
@@ -97,6 +136,7 @@ func (cv *ConfigView) renderViewMode() string {
 		"a: 添加feed",
 		"e: 编辑feed",
 		"d: 删除feed",
+		"l: LLM 设置",
 		"q: 返回主界面",
 	}
 	helpText := configHelpStyle.Render(strings.Join(help, " • "))
@@ -173,6 +213,59 @@ func (cv *ConfigView) renderEditMode() string {
 	return configViewStyle.Width(cv.width).Height(cv.height).Render(content)
 }
 
+// renderLLMSettings 显示 LLM 设置表单
+func (cv *ConfigView) renderLLMSettings() string {
+	title := configTitleStyle.Render("LLM 设置 (Ollama)")
+
+	labels := []string{"URL:      ", "模型:     ", "超时(秒): ", "最大文章: "}
+	var fields []string
+	for i, label := range labels {
+		fields = append(fields, label+cv.llmInputs[i].View())
+	}
+	enabled := "[ ]"
+	if cv.llmEnabled {
+		enabled = "[x]"
+	}
+	fields = append(fields, "启用:     "+enabled)
+
+	for i := range fields {
+		if i == cv.llmField {
+			fields[i] = selectedConfigFormStyle.Render(fields[i])
+		} else {
+			fields[i] = normalConfigFormStyle.Render(fields[i])
+		}
+	}
+
+	help := []string{
+		"Tab/↑/↓: 切换字段",
+		"Space: 启用/禁用",
+		"Ctrl+T: 测试连接",
+		"Enter: 保存",
+		"Esc: 取消",
+	}
+	helpText := configHelpStyle.Render(strings.Join(help, " • "))
+
+	var messageText string
+	if cv.testing {
+		messageText = configMessageStyle.Render("正在测试连接...")
+	} else if cv.message != "" {
+		messageText = configMessageStyle.Render(cv.message)
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		lipgloss.JoinVertical(lipgloss.Left, fields...),
+		"",
+		messageText,
+		"",
+		helpText,
+	)
+
+	return configViewStyle.Width(cv.width).Height(cv.height).Render(content)
+}
+
 // renderDeleteConfirmation 显示删除确认
 func (cv *ConfigView) renderDeleteConfirmation() string {
 	title := configTitleStyle.Render("删除Feed")
@@ -221,6 +314,10 @@ func (cv *ConfigView) Handle(msg tea.Msg) (*ConfigView, tea.Cmd) {
 	case tea.KeyMsg:
 		switch cv.mode {
 		case "view":
+			if key := msg.String(); cv.remote && (key == "a" || key == "e" || key == "d") {
+				cv.message = "订阅由同步服务器管理，请在服务器上添加、修改或删除。"
+				return cv, nil
+			}
 			switch msg.String() {
 			case "up", "k":
 				if cv.cursor > 0 {
@@ -256,6 +353,17 @@ func (cv *ConfigView) Handle(msg tea.Msg) (*ConfigView, tea.Cmd) {
 				if len(cv.feeds) > 0 {
 					cv.mode = "delete"
 				}
+			case "l":
+				// 切换到 LLM 设置页，用当前生效的设置填充表单
+				cv.mode = "llm"
+				cv.message = ""
+				cv.llmInputs[llmFieldURL].SetValue(cv.ollama.URL)
+				cv.llmInputs[llmFieldModel].SetValue(cv.ollama.Model)
+				cv.llmInputs[llmFieldTimeout].SetValue(strconv.Itoa(cv.ollama.Timeout))
+				cv.llmInputs[llmFieldMaxArticles].SetValue(strconv.Itoa(cv.ollama.MaxArticles))
+				cv.llmEnabled = cv.ollama.IsEnabled()
+				cv.focusLLMField(llmFieldURL)
+				return cv, nil
 			case "q", "h", "esc", "left":
 				// 退出配置模式
 				return cv, func() tea.Msg {
@@ -295,11 +403,9 @@ func (cv *ConfigView) Handle(msg tea.Msg) (*ConfigView, tea.Cmd) {
 					cv.feeds = append(cv.feeds, newFeed)
 					cv.cursor = len(cv.feeds) - 1
 				} else {
-					// 更新现有feed
-					cv.feeds[cv.cursor] = config.Feed{
-						Name: name,
-						URL:  url,
-					}
+					// 更新现有feed，保留 full_text、encoding 等其他设置
+					cv.feeds[cv.cursor].Name = name
+					cv.feeds[cv.cursor].URL = url
 				}
 
 				cv.mode = "view"
@@ -314,6 +420,42 @@ func (cv *ConfigView) Handle(msg tea.Msg) (*ConfigView, tea.Cmd) {
 				cv.message = ""
 			}
 
+		case "llm":
+			switch msg.String() {
+			case "tab", "down":
+				cv.focusLLMField((cv.llmField + 1) % llmFieldCount)
+				return cv, nil
+			case "shift+tab", "up":
+				cv.focusLLMField((cv.llmField + llmFieldCount - 1) % llmFieldCount)
+				return cv, nil
+			case " ":
+				if cv.llmField == llmFieldEnabled {
+					cv.llmEnabled = !cv.llmEnabled
+					return cv, nil
+				}
+			case "ctrl+t":
+				settings, err := cv.llmSettings()
+				if err != nil {
+					cv.message = err.Error()
+					return cv, nil
+				}
+				cv.testing = true
+				return cv, testLLMConnection(settings)
+			case "enter":
+				settings, err := cv.llmSettings()
+				if err != nil {
+					cv.message = err.Error()
+					return cv, nil
+				}
+				cv.mode = "view"
+				cv.message = "LLM 设置已保存。"
+				return cv, saveOllamaConfig(cv.paths, settings)
+			case "esc":
+				cv.mode = "view"
+				cv.message = ""
+				return cv, nil
+			}
+
 		case "delete":
 			switch msg.String() {
 			case "y", "Y":
@@ -345,6 +487,14 @@ func (cv *ConfigView) Handle(msg tea.Msg) (*ConfigView, tea.Cmd) {
 		} else {
 			cv.message = "配置已保存。"
 		}
+
+	case llmTestMsg:
+		cv.testing = false
+		if msg.err != nil {
+			cv.message = sanitizeLine(fmt.Sprintf("连接失败: %v", msg.err))
+		} else {
+			cv.message = "连接成功。"
+		}
 	}
 
 	// 处理输入字段更新
@@ -358,6 +508,11 @@ func (cv *ConfigView) Handle(msg tea.Msg) (*ConfigView, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 	}
+	if cv.mode == "llm" && cv.llmField < llmFieldEnabled {
+		var cmd tea.Cmd
+		cv.llmInputs[cv.llmField], cmd = cv.llmInputs[cv.llmField].Update(msg)
+		cmds = append(cmds, cmd)
+	}
 
 	return cv, tea.Batch(cmds...)
 }
@@ -365,5 +520,51 @@ func (cv *ConfigView) Handle(msg tea.Msg) (*ConfigView, tea.Cmd) {
 // saveConfig 保存配置到文件
 func (cv *ConfigView) saveConfig() tea.Cmd {
 	// 调用保存配置函数，传入当前feeds列表
-	return saveConfig(cv.feeds)
+	return saveConfig(cv.paths, cv.feeds)
+}
+
+// focusLLMField 将焦点移到 LLM 设置页的某个字段
+func (cv *ConfigView) focusLLMField(field int) {
+	cv.llmField = field
+	for i := range cv.llmInputs {
+		if i == field {
+			cv.llmInputs[i].Focus()
+		} else {
+			cv.llmInputs[i].Blur()
+		}
+	}
+}
+
+// llmSettings 读取并检查 LLM 设置表单
+func (cv *ConfigView) llmSettings() (config.OllamaConfig, error) {
+	enabled := cv.llmEnabled
+	settings := config.OllamaConfig{
+		Enabled: &enabled,
+		URL:     strings.TrimSpace(cv.llmInputs[llmFieldURL].Value()),
+		Model:   strings.TrimSpace(cv.llmInputs[llmFieldModel].Value()),
+	}
+	if !strings.HasPrefix(settings.URL, "http://") && !strings.HasPrefix(settings.URL, "https://") {
+		return settings, fmt.Errorf("URL 必须以 http:// 或 https:// 开头")
+	}
+	if settings.Model == "" {
+		return settings, fmt.Errorf("模型不能为空！")
+	}
+	timeout, err := strconv.Atoi(strings.TrimSpace(cv.llmInputs[llmFieldTimeout].Value()))
+	if err != nil || timeout < 1 || timeout > 3600 {
+		return settings, fmt.Errorf("超时必须是 1 到 3600 之间的秒数")
+	}
+	settings.Timeout = timeout
+	maxArticles, err := strconv.Atoi(strings.TrimSpace(cv.llmInputs[llmFieldMaxArticles].Value()))
+	if err != nil || maxArticles < 0 {
+		return settings, fmt.Errorf("最大文章数必须是非负整数")
+	}
+	settings.MaxArticles = maxArticles
+	return settings, nil
+}
+
+// testLLMConnection 用表单中的设置测试与 Ollama 的连接
+func testLLMConnection(settings config.OllamaConfig) tea.Cmd {
+	return func() tea.Msg {
+		return llmTestMsg{err: llm.CheckConnection(settings)}
+	}
 }
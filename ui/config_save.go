@@ -1,57 +1,57 @@
 package ui
 
 import (
-	"fmt"
-	"os"
-	"path/filepath"
-
 	"github.com/JohanLi233/gorss/config"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/spf13/viper"
 )
 
-// ConfigToSave 表示要保存的配置结构，与config包中的Config对应
-type ConfigToSave struct {
-	Feeds []config.Feed `mapstructure:"feeds"`
-}
-
-// saveConfig 保存配置更改到文件
-func saveConfig(feeds []config.Feed) tea.Cmd {
+// saveConfig 保存feeds列表到配置文件，文件中的其他部分（ollama 等设置、注释）保持不变
+func saveConfig(paths config.Paths, feeds []config.Feed) tea.Cmd {
 	return func() tea.Msg {
-		// 获取配置文件路径
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return saveConfigCompleteMsg{err: fmt.Errorf("无法获取用户主目录: %w", err)}
-		}
-
-		configDir := filepath.Join(homeDir, ".config", "gorss")
-		configPath := filepath.Join(configDir, "config.yaml")
-
-		// 确保配置目录存在
-		if err := os.MkdirAll(configDir, 0755); err != nil {
-			return saveConfigCompleteMsg{err: fmt.Errorf("无法创建配置目录: %w", err)}
+		// "All" 只是界面上的合并视图，不是真正的订阅源
+		var saved []config.Feed
+		for _, f := range feeds {
+			if f.Name == "All" && f.URL == "" {
+				continue
+			}
+			saved = append(saved, f)
 		}
 
-		// 使用viper保存配置
-		v := viper.New()
-		v.SetConfigFile(configPath)
-		v.SetConfigType("yaml")
+		err := config.Update(paths.ConfigFile, func(cfg *config.Config) error {
+			cfg.Feeds = saved
+			return nil
+		})
+		return reloadAfterSave(paths, err, true)
+	}
+}
 
-		// 设置配置内容
-		v.Set("feeds", feeds)
+// saveOllamaConfig 保存 LLM 设置到配置文件
+func saveOllamaConfig(paths config.Paths, settings config.OllamaConfig) tea.Cmd {
+	return func() tea.Msg {
+		err := config.Update(paths.ConfigFile, func(cfg *config.Config) error {
+			cfg.Ollama = settings
+			return nil
+		})
+		return reloadAfterSave(paths, err, false)
+	}
+}
 
-		// 保存到文件
-		if err := v.WriteConfig(); err != nil {
-			// 如果文件不存在，尝试创建
-			if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-				if err := v.SafeWriteConfig(); err != nil {
-					return saveConfigCompleteMsg{err: fmt.Errorf("无法创建配置文件: %w", err)}
-				}
-			} else {
-				return saveConfigCompleteMsg{err: fmt.Errorf("无法保存配置文件: %w", err)}
-			}
-		}
+// saveTheme 保存选中的主题到配置文件
+func saveTheme(paths config.Paths, name string) tea.Cmd {
+	return func() tea.Msg {
+		err := config.Update(paths.ConfigFile, func(cfg *config.Config) error {
+			cfg.Theme = name
+			return nil
+		})
+		return reloadAfterSave(paths, err, false)
+	}
+}
 
-		return saveConfigCompleteMsg{err: nil}
+// reloadAfterSave 重新加载保存后的配置，让新配置立即生效
+func reloadAfterSave(paths config.Paths, err error, feedsChanged bool) tea.Msg {
+	if err != nil {
+		return saveConfigCompleteMsg{err: err}
 	}
+	cfg, err := config.LoadConfig(paths)
+	return saveConfigCompleteMsg{cfg: cfg, feedsChanged: feedsChanged, err: err}
 }
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/JohanLi233/gorss/download"
+	"github.com/JohanLi233/gorss/feed"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// downloadUpdateMsg is sent whenever the download manager reports progress
+type downloadUpdateMsg struct{}
+
+// playerExitedMsg is sent when the external player returns control to the TUI
+type playerExitedMsg struct{ err error }
+
+// waitForDownloads blocks until the download manager reports a change
+func waitForDownloads(dm *download.Manager) tea.Cmd {
+	return func() tea.Msg {
+		<-dm.Updates()
+		return downloadUpdateMsg{}
+	}
+}
+
+// playEnclosure hands an enclosure to the external player, preferring the
+// downloaded file over streaming the URL
+func playEnclosure(dm *download.Manager, player []string, enclosure feed.Enclosure) tea.Cmd {
+	target := enclosure.URL
+	if local, ok := dm.LocalPath(enclosure.URL); ok {
+		target = local
+	}
+	args := append(append([]string{}, player[1:]...), target)
+	cmd := exec.Command(player[0], args...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return playerExitedMsg{err: err}
+	})
+}
+
+// formatBytes renders a byte count in human readable units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// progressBar draws a fixed-width text progress bar
+func progressBar(fraction float64, width int) string {
+	if width < 3 {
+		width = 3
+	}
+	if fraction < 0 {
+		return strings.Repeat("░", width)
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * float64(width))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// renderDownloads renders the download queue with per-job progress
+func renderDownloads(dm *download.Manager, width, height int) string {
+	title := articleTitleStyle.Render("Downloads")
+	dir := articleMetaStyle.Render("Saving to " + dm.Dir())
+
+	jobs := dm.Jobs()
+	var rows []string
+	if len(jobs) == 0 {
+		rows = append(rows, "No downloads yet. Press d on an article with enclosures to start one.")
+	}
+	barWidth := width / 3
+	for _, j := range jobs {
+		name := sanitizeLine(j.Title)
+		if name == "" {
+			name = sanitizeLine(j.URL)
+		}
+
+		var detail string
+		switch j.Status {
+		case download.Running:
+			if j.Total > 0 {
+				detail = fmt.Sprintf("%s / %s", formatBytes(j.Received), formatBytes(j.Total))
+			} else {
+				detail = formatBytes(j.Received)
+			}
+		case download.Failed:
+			detail = fmt.Sprintf("failed: %v", j.Err)
+		case download.Done:
+			detail = j.Path
+		default:
+			detail = j.Status.String()
+		}
+
+		style := normalArticleStyle
+		if j.Status == download.Failed {
+			style = configMessageStyle
+		} else if j.Status == download.Done {
+			style = selectedArticleStyle
+		}
+		rows = append(rows, style.Render(name))
+		rows = append(rows, fmt.Sprintf("%s %s", progressBar(j.Progress(), barWidth), detail), "")
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, append([]string{title, dir}, rows...)...)
+	return articleViewStyle.Width(width).Height(height).Render(content)
+}
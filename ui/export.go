@@ -0,0 +1,24 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/JohanLi233/gorss/export"
+	"github.com/JohanLi233/gorss/feed"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type exportCompleteMsg struct {
+	path  string // file written, or directory for Markdown
+	count int
+	err   error
+}
+
+// exportArticles 把文章导出到导出目录
+func exportArticles(dir string, format export.Format, articles []feed.Article, state *feed.State) tea.Cmd {
+	return func() tea.Msg {
+		prepared := export.Prepare(articles, state, ArticleBody)
+		path, err := export.ToDir(dir, format, prepared, time.Now())
+		return exportCompleteMsg{path: path, count: len(articles), err: err}
+	}
+}
@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// renderGemtext converts a gemtext document into styled, wrapped display lines.
+// Gemtext is line oriented: each line's type is decided by its prefix.
+func renderGemtext(gemtext string, width int) []string {
+	var lines []string
+	preformatted := false
+
+	for _, line := range strings.Split(gemtext, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		// ``` toggles preformatted mode; the rest of the toggle line is alt text
+		if strings.HasPrefix(line, "```") {
+			preformatted = !preformatted
+			continue
+		}
+
+		if preformatted {
+			// Preformatted text is shown verbatim and never wrapped, only clipped
+			// to the width in cells, so wide (CJK) characters aren't split
+			if width > 0 {
+				line = runewidth.Truncate(line, width, "")
+			}
+			lines = append(lines, geminiPreStyle.Render(line))
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "=>"):
+			fields := strings.Fields(strings.TrimPrefix(line, "=>"))
+			if len(fields) == 0 {
+				continue
+			}
+			target := fields[0]
+			label := strings.Join(fields[1:], " ")
+			if label == "" {
+				label = target
+			}
+			for _, l := range wrapLine("→ "+label, width) {
+				lines = append(lines, geminiLinkStyle.Render(l))
+			}
+			if label != target {
+				for _, l := range wrapLine("  "+target, width) {
+					lines = append(lines, geminiLinkURLStyle.Render(l))
+				}
+			}
+
+		case strings.HasPrefix(line, "###"):
+			lines = append(lines, geminiHeading3Style.Render(strings.TrimSpace(line[3:])))
+
+		case strings.HasPrefix(line, "##"):
+			lines = append(lines, "", geminiHeading2Style.Render(strings.TrimSpace(line[2:])))
+
+		case strings.HasPrefix(line, "#"):
+			lines = append(lines, "", geminiHeading1Style.Render(strings.TrimSpace(line[1:])), "")
+
+		case strings.HasPrefix(line, ">"):
+			for _, l := range wrapLine(strings.TrimSpace(line[1:]), width-2) {
+				lines = append(lines, geminiQuoteStyle.Render("│ "+l))
+			}
+
+		case strings.HasPrefix(line, "* "):
+			for i, l := range wrapLine(strings.TrimSpace(line[2:]), width-2) {
+				if i == 0 {
+					lines = append(lines, "• "+l)
+				} else {
+					lines = append(lines, "  "+l)
+				}
+			}
+
+		default:
+			lines = append(lines, wrapLine(line, width)...)
+		}
+	}
+
+	return lines
+}
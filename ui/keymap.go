@@ -0,0 +1,360 @@
+package ui
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Actions that can be bound to keys, as named in the keys section of the config
+const (
+	actQuit        = "quit"
+	actRefresh     = "refresh"
+	actConfig      = "config"
+	actDownloads   = "downloads"
+	actDown        = "down"
+	actUp          = "up"
+	actTop         = "top"
+	actBottom      = "bottom"
+	actPageDown    = "page_down"
+	actPageUp      = "page_up"
+	actBack        = "back"
+	actOpen        = "open"
+	actAsk         = "ask"
+	actFilter      = "filter"
+	actMultiSelect = "multi_select"
+	actSelect      = "select"
+	actFullText    = "full_text"
+	actComments    = "comments"
+	actDownload    = "download"
+	actPlay        = "play"
+	actToggle      = "toggle"
+	actTheme       = "theme"
+	actStar        = "star"
+	actExport      = "export"
+)
+
+// actionHelp describes the actions in the generated help
+var actionHelp = map[string]string{
+	actQuit:        "quit",
+	actRefresh:     "refresh",
+	actConfig:      "config",
+	actDownloads:   "downloads",
+	actDown:        "down",
+	actUp:          "up",
+	actTop:         "top",
+	actBottom:      "bottom",
+	actPageDown:    "page down",
+	actPageUp:      "page up",
+	actBack:        "back",
+	actOpen:        "select",
+	actAsk:         "ask LLM",
+	actFilter:      "filter",
+	actMultiSelect: "multi-select",
+	actSelect:      "select/deselect",
+	actFullText:    "full article",
+	actComments:    "comments",
+	actDownload:    "download",
+	actPlay:        "play",
+	actToggle:      "collapse/expand",
+	actTheme:       "theme",
+	actStar:        "star",
+	actExport:      "export",
+}
+
+// sequenceTimeout is how long a partly typed sequence waits for its next key.
+// A key that is a binding of its own and also starts a longer one ("g" and
+// "gg") runs on its own when the wait is over.
+const sequenceTimeout = time.Second
+
+// keyTimeoutMsg is sent when sequenceTimeout has passed since the key press
+// with the given number
+type keyTimeoutMsg struct{ press int }
+
+// keyBinding binds an action to one or more key sequences
+type keyBinding struct {
+	action    string
+	binding   key.Binding // for help; its keys are the sequences as written in the config
+	sequences [][]string
+}
+
+// Keymap resolves key presses to actions. Bindings may be sequences of
+// several keys ("gg") and may be preceded by a count ("10j"); the keys
+// typed so far are kept until they complete or break a binding.
+type Keymap struct {
+	views   map[string][]keyBinding // per view, with the global bindings merged in
+	pending []string
+	count   int
+	presses int // key presses resolved so far, to tell stale timeouts apart
+}
+
+// NewKeymap builds the keymap from the default bindings and the overrides
+// in the config. Bindings that don't parse are skipped; the config
+// validation reports them.
+func NewKeymap(overrides config.KeyConfig) *Keymap {
+	bound := config.DefaultKeys()
+	for view, actions := range overrides {
+		if bound[view] == nil {
+			continue
+		}
+		for action, keys := range actions {
+			bound[view][action] = keys
+		}
+	}
+
+	own := make(map[string][]keyBinding)
+	for view, actions := range bound {
+		own[view] = makeBindings(actions)
+	}
+
+	k := &Keymap{views: make(map[string][]keyBinding)}
+	for view, bindings := range own {
+		if view == "global" {
+			continue
+		}
+		// Global bindings apply unless the view uses the same keys
+		used := make(map[string]bool)
+		for _, b := range bindings {
+			for _, seq := range b.sequences {
+				used[strings.Join(seq, " ")] = true
+			}
+		}
+		merged := append([]keyBinding(nil), bindings...)
+		for _, g := range own["global"] {
+			if _, ok := bound[view][g.action]; ok {
+				// The view rebinds the action itself
+				continue
+			}
+			var sequences [][]string
+			var keys []string
+			for i, seq := range g.sequences {
+				if !used[strings.Join(seq, " ")] {
+					sequences = append(sequences, seq)
+					keys = append(keys, g.binding.Keys()[i])
+				}
+			}
+			merged = append(merged, newKeyBinding(g.action, keys, sequences))
+		}
+		k.views[view] = merged
+	}
+	return k
+}
+
+// makeBindings parses the keys bound to each action of one view
+func makeBindings(actions map[string][]string) []keyBinding {
+	names := make([]string, 0, len(actions))
+	for action := range actions {
+		names = append(names, action)
+	}
+	sort.Strings(names)
+
+	var bindings []keyBinding
+	for _, action := range names {
+		var sequences [][]string
+		var keys []string
+		for _, spec := range actions[action] {
+			seq, err := config.KeySequence(spec)
+			if err != nil {
+				continue
+			}
+			sequences = append(sequences, seq)
+			keys = append(keys, spec)
+		}
+		bindings = append(bindings, newKeyBinding(action, keys, sequences))
+	}
+	return bindings
+}
+
+func newKeyBinding(action string, keys []string, sequences [][]string) keyBinding {
+	b := key.NewBinding(key.WithKeys(keys...), key.WithHelp(displayKey(keys), actionHelp[action]))
+	if len(keys) == 0 {
+		// Unbound in the config
+		b.SetEnabled(false)
+	}
+	return keyBinding{action: action, binding: b, sequences: sequences}
+}
+
+// displayKey shows the first key of a binding the way the help line does
+func displayKey(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	switch keys[0] {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	case " ":
+		return "space"
+	}
+	return keys[0]
+}
+
+// Resolve feeds a key press to the keymap. It returns the action once a
+// binding is complete, with the count typed before it (0 if none), or ""
+// while a sequence or count is still being typed or the keys are unbound.
+// Digits start a count unless a binding starts with them. While keys are
+// pending, the caller schedules Timeout.
+func (k *Keymap) Resolve(view string, msg tea.KeyMsg) (string, int) {
+	k.presses++
+	s := msg.String()
+	if s == "esc" && (len(k.pending) > 0 || k.count > 0) {
+		k.Reset()
+		return "", 0
+	}
+	if len(k.pending) == 0 && len(s) == 1 && s[0] >= '0' && s[0] <= '9' && (s != "0" || k.count > 0) && !k.starts(view, s) {
+		k.count = k.count*10 + int(s[0]-'0')
+		return "", 0
+	}
+
+	k.pending = append(k.pending, s)
+	action, complete, prefix := k.match(view)
+	if !complete && !prefix && len(k.pending) > 1 {
+		// The key broke the sequence; try it on its own
+		k.pending = []string{s}
+		action, complete, prefix = k.match(view)
+	}
+	if prefix {
+		return "", 0
+	}
+
+	count := k.count
+	k.Reset()
+	if !complete {
+		return "", 0
+	}
+	return action, count
+}
+
+// Timeout returns a command that delivers a keyTimeoutMsg after
+// sequenceTimeout, or nil if no keys are pending. A count on its own waits.
+func (k *Keymap) Timeout() tea.Cmd {
+	if len(k.pending) == 0 {
+		return nil
+	}
+	press := k.presses
+	return tea.Tick(sequenceTimeout, func(time.Time) tea.Msg {
+		return keyTimeoutMsg{press: press}
+	})
+}
+
+// Flush ends a sequence that timed out: if no key was pressed since the
+// timeout was scheduled, the pending keys run as the binding they form on
+// their own, if any, and are discarded otherwise.
+func (k *Keymap) Flush(view string, msg keyTimeoutMsg) (string, int) {
+	if msg.press != k.presses || len(k.pending) == 0 {
+		return "", 0
+	}
+	action, complete, _ := k.match(view)
+	count := k.count
+	k.Reset()
+	if !complete {
+		return "", 0
+	}
+	return action, count
+}
+
+// starts reports whether a binding of the view starts with the key
+func (k *Keymap) starts(view, key string) bool {
+	for _, b := range k.views[view] {
+		if !b.binding.Enabled() {
+			continue
+		}
+		for _, seq := range b.sequences {
+			if len(seq) > 0 && seq[0] == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// match looks up the pending keys. complete is set when they form a whole
+// binding and prefix when they are the start of a longer one; a binding
+// that is also the start of a longer one waits for the next key.
+func (k *Keymap) match(view string) (action string, complete, prefix bool) {
+	for _, b := range k.views[view] {
+		if !b.binding.Enabled() {
+			continue
+		}
+		for _, seq := range b.sequences {
+			if len(seq) < len(k.pending) || !equalKeys(seq[:len(k.pending)], k.pending) {
+				continue
+			}
+			if len(seq) == len(k.pending) {
+				action, complete = b.action, true
+			} else {
+				prefix = true
+			}
+		}
+	}
+	return action, complete, prefix
+}
+
+func equalKeys(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Reset discards a partly typed sequence and count
+func (k *Keymap) Reset() {
+	k.pending = nil
+	k.count = 0
+}
+
+// Pending returns the count and keys typed so far, e.g. "10g"
+func (k *Keymap) Pending() string {
+	var sb strings.Builder
+	if k.count > 0 {
+		sb.WriteString(strconv.Itoa(k.count))
+	}
+	for _, s := range k.pending {
+		sb.WriteString(displayKey([]string{s}))
+	}
+	return sb.String()
+}
+
+// Binding returns the binding of an action in a view
+func (k *Keymap) Binding(view, action string) key.Binding {
+	for _, b := range k.views[view] {
+		if b.action == action {
+			return b.binding
+		}
+	}
+	return key.NewBinding(key.WithDisabled())
+}
+
+// Help returns "key: description" entries for the given actions of a view,
+// leaving out unbound ones
+func (k *Keymap) Help(view string, actions ...string) []string {
+	var help []string
+	for _, action := range actions {
+		b := k.Binding(view, action)
+		if b.Enabled() {
+			help = append(help, b.Help().Key+": "+b.Help().Desc)
+		}
+	}
+	return help
+}
+
+// HelpPair returns a single entry for two related actions, like "j/k: navigate"
+func (k *Keymap) HelpPair(view, a, b, desc string) []string {
+	ba, bb := k.Binding(view, a), k.Binding(view, b)
+	if !ba.Enabled() || !bb.Enabled() {
+		return k.Help(view, a, b)
+	}
+	return []string{ba.Help().Key + "/" + bb.Help().Key + ": " + desc}
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/JohanLi233/gorss/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// press feeds keys to the keymap and returns the last result
+func press(k *Keymap, keys ...string) (string, int) {
+	var action string
+	var count int
+	for _, s := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+		if s == "esc" {
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		}
+		action, count = k.Resolve("articles", msg)
+	}
+	return action, count
+}
+
+func TestResolveSequencesAndCounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []string
+		action string
+		count  int
+	}{
+		{"single key", []string{"j"}, actDown, 0},
+		{"sequence", []string{"g", "g"}, actTop, 0},
+		{"count", []string{"1", "0", "j"}, actDown, 10},
+		{"count and sequence", []string{"5", "g", "g"}, actTop, 5},
+		{"broken sequence runs the new key", []string{"g", "j"}, actDown, 0},
+		{"leading zero is not a count", []string{"0"}, "", 0},
+		{"esc cancels", []string{"3", "g", "esc", "j"}, actDown, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := NewKeymap(nil)
+			action, count := press(k, tt.keys...)
+			if action != tt.action || count != tt.count {
+				t.Errorf("keys %q = %q, %d; want %q, %d", tt.keys, action, count, tt.action, tt.count)
+			}
+			if k.Pending() != "" {
+				t.Errorf("keys %q left %q pending", tt.keys, k.Pending())
+			}
+		})
+	}
+}
+
+func TestTimeoutFlushesExactMatch(t *testing.T) {
+	// "g" bound on its own and "gg" as a sequence
+	k := NewKeymap(config.KeyConfig{"global": {"refresh": {"g"}}})
+
+	if action, _ := press(k, "g", "g"); action != actTop {
+		t.Errorf("gg = %q, want %q", action, actTop)
+	}
+
+	if action, _ := press(k, "3", "g"); action != "" {
+		t.Fatalf("g resolved to %q before the timeout", action)
+	}
+	if k.Timeout() == nil {
+		t.Fatal("no timeout scheduled while g is pending")
+	}
+	action, count := k.Flush("articles", keyTimeoutMsg{press: k.presses})
+	if action != actRefresh || count != 3 {
+		t.Errorf("flush = %q, %d; want %q, 3", action, count, actRefresh)
+	}
+	if k.Pending() != "" || k.Timeout() != nil {
+		t.Errorf("%q still pending after the flush", k.Pending())
+	}
+}
+
+func TestTimeoutIgnoredAfterNextKey(t *testing.T) {
+	k := NewKeymap(config.KeyConfig{"global": {"refresh": {"g"}}})
+	press(k, "g")
+	stale := keyTimeoutMsg{press: k.presses}
+	if action, _ := press(k, "g"); action != actTop {
+		t.Fatalf("gg = %q, want %q", action, actTop)
+	}
+	if action, _ := k.Flush("articles", stale); action != "" {
+		t.Errorf("stale timeout ran %q", action)
+	}
+
+	// A new sequence started after the timeout was scheduled is left alone too
+	press(k, "g")
+	stale = keyTimeoutMsg{press: k.presses}
+	press(k, "3")
+	if action, _ := k.Flush("articles", stale); action != "" {
+		t.Errorf("stale timeout ran %q", action)
+	}
+}
+
+func TestTimeoutDiscardsIncompleteSequence(t *testing.T) {
+	k := NewKeymap(nil) // "gg" only
+	press(k, "g")
+	if action, _ := k.Flush("articles", keyTimeoutMsg{press: k.presses}); action != "" {
+		t.Errorf("flush of a bare prefix = %q, want nothing", action)
+	}
+	if k.Pending() != "" {
+		t.Errorf("%q still pending", k.Pending())
+	}
+	if k.Timeout() != nil {
+		t.Error("timeout scheduled with nothing pending")
+	}
+}
+
+func TestDigitsBoundAsKeys(t *testing.T) {
+	k := NewKeymap(config.KeyConfig{"global": {"top": {"0"}, "theme": {"1"}}})
+	if action, count := press(k, "1"); action != actTheme || count != 0 {
+		t.Errorf("1 = %q, %d; want %q", action, count, actTheme)
+	}
+	if action, count := press(k, "0"); action != actTop || count != 0 {
+		t.Errorf("0 = %q, %d; want %q", action, count, actTop)
+	}
+	// Digits nothing starts with still count
+	if action, count := press(k, "2", "5", "j"); action != actDown || count != 25 {
+		t.Errorf("25j = %q, %d; want %q, 25", action, count, actDown)
+	}
+}
@@ -26,11 +26,15 @@ func (i Item) Title() string { return i.title }
 func (i Item) Description() string { return i.description }
 
 // NewArticleItem creates a new list item from an article
-func NewArticleItem(article feed.Article) Item {
+func NewArticleItem(article feed.Article, starred bool) Item {
 	pubTime := article.Published.Format("2006-01-02 15:04")
+	title := sanitizeLine(article.Title)
+	if starred {
+		title = "★ " + title
+	}
 	return Item{
-		title:       article.Title,
-		description: fmt.Sprintf("[%s] %s", pubTime, article.FeedName),
+		title:       title,
+		description: fmt.Sprintf("[%s] %s", pubTime, sanitizeLine(article.FeedName)),
 		data:        article,
 	}
 }
@@ -106,11 +110,11 @@ func CreateFeedsList(feeds []string, width, height int) list.Model {
 	return l
 }
 
-// CreateArticlesList creates a new list for articles
-func CreateArticlesList(articles []feed.Article, width, height int) list.Model {
+// CreateArticlesList creates a new list for articles, marking the starred ones
+func CreateArticlesList(articles []feed.Article, state *feed.State, width, height int) list.Model {
 	var items []list.Item
 	for _, a := range articles {
-		items = append(items, NewArticleItem(a))
+		items = append(items, NewArticleItem(a, state.IsStarred(a.ID)))
 	}
 
 	l := list.New(items, ItemDelegate{}, width, height)
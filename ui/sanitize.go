@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"strings"
+)
+
+// Feed content is untrusted: a feed can embed terminal escape sequences that
+// change the window title, write to the clipboard (OSC 52) or redraw parts of
+// the screen to spoof the UI. Every string that comes from a feed, a comment
+// thread or the LLM goes through sanitizeText or sanitizeLine before it is
+// handed to lipgloss.
+
+// sanitizeText removes terminal escape sequences, control characters and
+// bidirectional overrides from s. Newlines and tabs are kept.
+func sanitizeText(s string) string {
+	if isClean(s) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\x1b':
+			i = skipEscape(runes, i)
+		case r == 0x9b: // C1 CSI
+			i = skipCSI(runes, i+1)
+		case r == 0x90 || r == 0x98 || r == 0x9d || r == 0x9e || r == 0x9f: // C1 DCS, SOS, OSC, PM, APC
+			i = skipString(runes, i+1)
+		case r == '\n' || r == '\t':
+			b.WriteRune(r)
+		case r == '\r':
+			// A lone carriage return would move the cursor back over the line
+			if i+1 >= len(runes) || runes[i+1] != '\n' {
+				b.WriteRune('\n')
+			}
+		case isControl(r) || isBidiControl(r):
+			// dropped
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// sanitizeLine is sanitizeText for single-line fields such as titles and
+// author names: line breaks and tabs become spaces.
+func sanitizeLine(s string) string {
+	s = sanitizeText(s)
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// isClean reports whether s needs no sanitizing, which is the common case
+func isClean(s string) bool {
+	for _, r := range s {
+		if (isControl(r) && r != '\n' && r != '\t') || isBidiControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isControl reports whether r is a C0 or C1 control character or DEL
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f || (r >= 0x80 && r <= 0x9f)
+}
+
+// isBidiControl reports whether r is a bidirectional embedding, override or
+// isolate, which can make text display in a different order than it is stored
+func isBidiControl(r rune) bool {
+	return (r >= 0x202a && r <= 0x202e) || (r >= 0x2066 && r <= 0x2069) || r == 0x200e || r == 0x200f || r == 0x061c
+}
+
+// skipEscape skips the sequence introduced by the ESC at runes[i] and returns
+// the index of its last rune
+func skipEscape(runes []rune, i int) int {
+	if i+1 >= len(runes) {
+		return i
+	}
+	switch runes[i+1] {
+	case '[':
+		return skipCSI(runes, i+2)
+	case ']', 'P', 'X', '^', '_':
+		return skipString(runes, i+2)
+	}
+	// Other escapes: intermediate bytes followed by one final byte
+	j := i + 1
+	for j < len(runes) && runes[j] >= 0x20 && runes[j] <= 0x2f {
+		j++
+	}
+	if j < len(runes) && runes[j] >= 0x30 && runes[j] <= 0x7e {
+		return j
+	}
+	return j - 1
+}
+
+// skipCSI skips the parameters and final byte of a control sequence starting
+// at runes[start] and returns the index of its last rune
+func skipCSI(runes []rune, start int) int {
+	j := start
+	for j < len(runes) && runes[j] >= 0x20 && runes[j] <= 0x3f {
+		j++
+	}
+	if j < len(runes) && runes[j] >= 0x40 && runes[j] <= 0x7e {
+		return j
+	}
+	// Malformed: drop what was consumed and keep the rest as text
+	return j - 1
+}
+
+// skipString skips an OSC/DCS/SOS/PM/APC payload starting at runes[start]. The
+// string ends at BEL, ST (ESC \) or C1 ST; an unterminated string runs to the
+// end of the text, as it would in a terminal.
+func skipString(runes []rune, start int) int {
+	for j := start; j < len(runes); j++ {
+		switch runes[j] {
+		case '\a', 0x9c:
+			return j
+		case '\x1b':
+			if j+1 < len(runes) && runes[j+1] == '\\' {
+				return j + 1
+			}
+			return j
+		}
+	}
+	return len(runes) - 1
+}
+
+// SanitizeLine makes a single-line field from a feed safe to print to a
+// terminal outside the TUI, e.g. in the CLI's output
+func SanitizeLine(s string) string {
+	return sanitizeLine(s)
+}
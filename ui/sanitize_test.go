@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JohanLi233/gorss/feed"
+)
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "hello world", "hello world"},
+		{"newlines and tabs kept", "a\n\tb", "a\n\tb"},
+		{"multi-byte utf-8", "héllo 世界 🚀 ßü", "héllo 世界 🚀 ßü"},
+		{"csi color", "\x1b[31mred\x1b[0m", "red"},
+		{"csi cursor movement", "a\x1b[2Ab\x1b[10;20Hc", "abc"},
+		{"csi clear screen", "\x1b[2Jtext", "text"},
+		{"osc title bel", "\x1b]0;pwned\atitle", "title"},
+		{"osc title st", "\x1b]2;pwned\x1b\\title", "title"},
+		{"osc 52 clipboard", "x\x1b]52;c;cm0gLXJmIH4=\ay", "xy"},
+		{"osc 8 hyperlink", "\x1b]8;;https://evil.example\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"unterminated osc", "keep\x1b]0;rest of text", "keep"},
+		{"dcs", "\x1bPq#0;2;0;0;0\x1b\\after", "after"},
+		{"two-byte escape", "a\x1bcb", "ab"},
+		{"escape with intermediate", "a\x1b(Bb", "ab"},
+		{"trailing esc", "text\x1b", "text"},
+		{"c1 csi", "a\u009b31mb", "ab"},
+		{"c1 osc st", "a\u009d0;pwned\u009cb", "ab"},
+		{"c1 osc bel", "a\u009d52;c;eA==\ab", "ab"},
+		{"other c1", "a\u0085b\u0084c", "abc"},
+		{"bidi override", "file\u202egnp.exe", "filegnp.exe"},
+		{"bidi isolates and marks", "\u2066a\u2069\u200eb\u200f\u061c", "ab"},
+		{"lone cr", "safe\rEVIL", "safe\nEVIL"},
+		{"crlf", "a\r\nb", "a\nb"},
+		{"trailing cr", "a\r", "a\n"},
+		{"backspace", "abc\b\b\bxyz", "abcxyz"},
+		{"bell and nul", "a\a\x00b", "ab"},
+		{"del", "a\x7fb", "ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeText(tt.in); got != tt.want {
+				t.Errorf("sanitizeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Title", "Title"},
+		{"two\nlines", "two lines"},
+		{"tab\there", "tab here"},
+		{"cr\rhere", "cr here"},
+		{"\x1b[1mBold\x1b[0m title", "Bold title"},
+		{"\x1b]0;title\aSafe", "Safe"},
+		{"日本語\u202eタイトル", "日本語タイトル"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeLine(tt.in); got != tt.want {
+			t.Errorf("sanitizeLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if got := SanitizeLine(tt.in); got != tt.want {
+			t.Errorf("SanitizeLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsClean(t *testing.T) {
+	for _, s := range []string{"", "plain", "a\nb\tc", "émoji 🎉"} {
+		if !isClean(s) {
+			t.Errorf("isClean(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"\x1b[0m", "a\rb", "\u202e", "\u009b"} {
+		if isClean(s) {
+			t.Errorf("isClean(%q) = true", s)
+		}
+	}
+}
+
+func TestAskViewSanitizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		article feed.Article
+		want    string // in the prompt
+	}{
+		{"bidi override in title", feed.Article{Title: "invoice\u202efdp.exe", Content: "<p>text</p>"}, "Title: invoicefdp.exe"},
+		{"escape in title", feed.Article{Title: "\x1b]0;pwned\aNews", Content: "body"}, "Title: News"},
+		{"html content", feed.Article{Title: "T", Content: "<p>first</p><p>second &amp; <b>bold</b></p>"}, "second & bold"},
+		{"entity escape in content", feed.Article{Title: "T", Content: "<p>a&#27;]52;c;eA==&#7;b</p>"}, "ab"},
+		{"bidi in content", feed.Article{Title: "T", Content: "<p>left\u2067right</p>"}, "leftright"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := articlePrompt(tt.article)
+			if !strings.Contains(prompt, tt.want) {
+				t.Errorf("prompt %q doesn't contain %q", prompt, tt.want)
+			}
+			v := NewAskLLMView(80, 30)
+			v.SetPrompt(prompt)
+			for _, out := range []string{prompt, v.input.Value(), v.View(), v.View("answer")} {
+				if !isClean(out) || strings.Contains(out, "<p>") {
+					t.Errorf("unsanitized output: %q", out)
+				}
+			}
+		})
+	}
+
+	// Typed or pasted text is drawn back as the question
+	v := NewAskLLMView(80, 30)
+	v.input.SetValue("why\u202e?")
+	if out := v.View("answer"); strings.ContainsRune(out, '\u202e') {
+		t.Errorf("question drawn with a bidi override: %q", out)
+	}
+}
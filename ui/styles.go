@@ -4,107 +4,257 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Colors and styles are rebuilt from the palette of the active theme by
+// applyTheme (theme.go); the values here are only placeholders until then.
 var (
 	// Colors
-	subtle    = lipgloss.AdaptiveColor{Light: "#D9DCCF", Dark: "#383838"}
-	highlight = lipgloss.AdaptiveColor{Light: "#874BFD", Dark: "#7D56F4"}
-	special   = lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#73F59F"}
-	alert     = lipgloss.AdaptiveColor{Light: "#FD4659", Dark: "#FF5A6E"}
+	subtle    lipgloss.TerminalColor = lipgloss.NoColor{}
+	highlight lipgloss.TerminalColor = lipgloss.NoColor{}
+	special   lipgloss.TerminalColor = lipgloss.NoColor{}
+	alert     lipgloss.TerminalColor = lipgloss.NoColor{}
+	textColor lipgloss.TerminalColor = lipgloss.NoColor{}
+	mutedText lipgloss.TerminalColor = lipgloss.NoColor{}
+	barText   lipgloss.TerminalColor = lipgloss.NoColor{}
+	errorText lipgloss.TerminalColor = lipgloss.NoColor{}
+	frame     lipgloss.TerminalColor = lipgloss.NoColor{}
+	info      lipgloss.TerminalColor = lipgloss.NoColor{}
+	success   lipgloss.TerminalColor = lipgloss.NoColor{}
 
 	// 奶茶色（bubble tea）
-	bubbleTeaColor = lipgloss.AdaptiveColor{Light: "#EED9C4", Dark: "#BFA48A"}
+	bubbleTeaColor lipgloss.TerminalColor = lipgloss.NoColor{}
 
 	// Styles
+	titleStyle                lipgloss.Style
+	statusBarStyle            lipgloss.Style
+	statusErrorStyle          lipgloss.Style
+	statusMultiSelectStyle    lipgloss.Style
+	feedListStyle             lipgloss.Style
+	selectedFeedStyle         lipgloss.Style
+	normalFeedStyle           lipgloss.Style
+	articleListStyle          lipgloss.Style
+	selectedArticleStyle      lipgloss.Style
+	selectedMultiArticleStyle lipgloss.Style // 多选高亮：奶茶色
+	normalArticleStyle        lipgloss.Style
+	articleViewStyle          lipgloss.Style
+	articleTitleStyle         lipgloss.Style
+	articleMetaStyle          lipgloss.Style
+	enclosureStyle            lipgloss.Style
+	commentAuthorStyle        lipgloss.Style
+	commentGuideStyle         lipgloss.Style
+	helpStyle                 lipgloss.Style
+
+	// 配置界面样式
+	configViewStyle         lipgloss.Style
+	configTitleStyle        lipgloss.Style
+	configContentStyle      lipgloss.Style
+	selectedConfigItemStyle lipgloss.Style
+	normalConfigItemStyle   lipgloss.Style
+	selectedConfigFormStyle lipgloss.Style
+	normalConfigFormStyle   lipgloss.Style
+	configHelpStyle         lipgloss.Style
+	configMessageStyle      lipgloss.Style
+
+	// Gemtext 渲染样式
+	geminiHeading1Style lipgloss.Style
+	geminiHeading2Style lipgloss.Style
+	geminiHeading3Style lipgloss.Style
+	geminiLinkStyle     lipgloss.Style
+	geminiLinkURLStyle  lipgloss.Style
+	geminiQuoteStyle    lipgloss.Style
+	geminiPreStyle      lipgloss.Style
+
+	// Ask LLM 界面样式
+	askBoxStyle         lipgloss.Style
+	askPromptStyle      lipgloss.Style
+	askAnswerTitleStyle lipgloss.Style
+)
+
+// styleNames maps the style names used in themes to the styles
+var styleNames = map[string]*lipgloss.Style{
+	"title":                  &titleStyle,
+	"status_bar":             &statusBarStyle,
+	"status_error":           &statusErrorStyle,
+	"status_multi_select":    &statusMultiSelectStyle,
+	"feed_list":              &feedListStyle,
+	"selected_feed":          &selectedFeedStyle,
+	"normal_feed":            &normalFeedStyle,
+	"article_list":           &articleListStyle,
+	"selected_article":       &selectedArticleStyle,
+	"selected_multi_article": &selectedMultiArticleStyle,
+	"normal_article":         &normalArticleStyle,
+	"article_view":           &articleViewStyle,
+	"article_title":          &articleTitleStyle,
+	"article_meta":           &articleMetaStyle,
+	"enclosure":              &enclosureStyle,
+	"comment_author":         &commentAuthorStyle,
+	"comment_guide":          &commentGuideStyle,
+	"help":                   &helpStyle,
+	"config_view":            &configViewStyle,
+	"config_title":           &configTitleStyle,
+	"config_content":         &configContentStyle,
+	"selected_config_item":   &selectedConfigItemStyle,
+	"normal_config_item":     &normalConfigItemStyle,
+	"selected_config_form":   &selectedConfigFormStyle,
+	"normal_config_form":     &normalConfigFormStyle,
+	"config_help":            &configHelpStyle,
+	"config_message":         &configMessageStyle,
+	"gemini_heading1":        &geminiHeading1Style,
+	"gemini_heading2":        &geminiHeading2Style,
+	"gemini_heading3":        &geminiHeading3Style,
+	"gemini_link":            &geminiLinkStyle,
+	"gemini_link_url":        &geminiLinkURLStyle,
+	"gemini_quote":           &geminiQuoteStyle,
+	"gemini_pre":             &geminiPreStyle,
+	"ask_box":                &askBoxStyle,
+	"ask_prompt":             &askPromptStyle,
+	"ask_answer_title":       &askAnswerTitleStyle,
+}
+
+// buildStyles creates every style from the current colors
+func buildStyles() {
 	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(highlight).
-			Padding(0, 1)
+		Bold(true).
+		Foreground(barText).
+		Background(highlight).
+		Padding(0, 1)
 
 	statusBarStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(subtle).
-			Padding(0, 1)
+		Foreground(barText).
+		Background(subtle).
+		Padding(0, 1)
+
+	statusErrorStyle = statusBarStyle.Copy().
+		Foreground(errorText)
+
+	statusMultiSelectStyle = statusBarStyle.Copy().
+		Foreground(bubbleTeaColor)
 
 	feedListStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(subtle).
-			Padding(1, 2).
-			Width(30)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(subtle).
+		Padding(1, 2).
+		Width(30)
 
 	selectedFeedStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(special)
+		Bold(true).
+		Foreground(special)
 
 	normalFeedStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#1A1A1A", Dark: "#DDDDDD"})
+		Foreground(textColor)
 
 	articleListStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(subtle).
-			Padding(1, 2)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(subtle).
+		Padding(1, 2)
 
 	selectedArticleStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(special)
+		Bold(true).
+		Foreground(special)
 
-	// 多选高亮：奶茶色
 	selectedMultiArticleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(bubbleTeaColor)
+		Bold(true).
+		Foreground(bubbleTeaColor)
 
 	normalArticleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#1A1A1A", Dark: "#DDDDDD"})
+		Foreground(textColor)
 
 	articleViewStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(subtle).
-			Padding(1, 2)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(subtle).
+		Padding(1, 2)
 
 	articleTitleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(highlight).
-			MarginBottom(1)
+		Bold(true).
+		Foreground(highlight).
+		MarginBottom(1)
 
 	articleMetaStyle = lipgloss.NewStyle().
-			Foreground(subtle).
-			MarginBottom(1)
+		Foreground(subtle).
+		MarginBottom(1)
+
+	enclosureStyle = lipgloss.NewStyle().
+		Foreground(special).
+		MarginBottom(1)
+
+	commentAuthorStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(highlight)
+
+	commentGuideStyle = lipgloss.NewStyle().
+		Foreground(subtle)
 
 	helpStyle = lipgloss.NewStyle().
-			Foreground(subtle)
+		Foreground(subtle)
 
-	// 配置界面样式
 	configViewStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(subtle).
-			Padding(1, 2)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(subtle).
+		Padding(1, 2)
 
 	configTitleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(highlight).
-			MarginBottom(1)
+		Bold(true).
+		Foreground(highlight).
+		MarginBottom(1)
 
 	configContentStyle = lipgloss.NewStyle().
-			MarginTop(1).
-			MarginBottom(1)
+		MarginTop(1).
+		MarginBottom(1)
 
 	selectedConfigItemStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(special)
+		Bold(true).
+		Foreground(special)
 
 	normalConfigItemStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#1A1A1A", Dark: "#DDDDDD"})
+		Foreground(textColor)
 
 	selectedConfigFormStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(special)
+		Bold(true).
+		Foreground(special)
 
 	normalConfigFormStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#1A1A1A", Dark: "#DDDDDD"})
+		Foreground(textColor)
 
 	configHelpStyle = lipgloss.NewStyle().
-			Foreground(subtle)
+		Foreground(subtle)
 
 	configMessageStyle = lipgloss.NewStyle().
-			Foreground(alert)
-)
+		Foreground(alert)
+
+	geminiHeading1Style = lipgloss.NewStyle().
+		Bold(true).
+		Underline(true).
+		Foreground(highlight)
+
+	geminiHeading2Style = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(highlight)
+
+	geminiHeading3Style = lipgloss.NewStyle().
+		Bold(true)
+
+	geminiLinkStyle = lipgloss.NewStyle().
+		Foreground(special)
+
+	geminiLinkURLStyle = lipgloss.NewStyle().
+		Foreground(subtle)
+
+	geminiQuoteStyle = lipgloss.NewStyle().
+		Italic(true).
+		Foreground(bubbleTeaColor)
+
+	geminiPreStyle = lipgloss.NewStyle().
+		Foreground(mutedText)
+
+	askBoxStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(frame).
+		Padding(1, 2)
+
+	askPromptStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(info)
+
+	askAnswerTitleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(success)
+}
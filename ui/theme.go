@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JohanLi233/gorss/config"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func init() {
+	// Usable styles before the config is loaded
+	applyPalette(config.BuiltinThemes()["dark"])
+}
+
+// applyTheme makes a theme the active one and returns its name. With no
+// theme configured, NO_COLOR selects "mono" and otherwise the terminal
+// background picks "dark" or "light". An unknown or broken theme falls back
+// to that default, and the error says why.
+func applyTheme(cfg *config.Config, name string) (string, error) {
+	if name == "" {
+		name = defaultTheme()
+	}
+	theme, err := config.ResolveTheme(cfg, name)
+	if err != nil {
+		name = defaultTheme()
+		theme = config.BuiltinThemes()[name]
+	}
+	applyPalette(theme)
+	return name, err
+}
+
+// defaultTheme picks the theme used when none is configured
+func defaultTheme() string {
+	if os.Getenv("NO_COLOR") != "" {
+		return "mono"
+	}
+	if !lipgloss.HasDarkBackground() {
+		return "light"
+	}
+	return "dark"
+}
+
+// applyPalette rebuilds every style from a resolved theme
+func applyPalette(theme config.ThemeConfig) {
+	colors := map[string]*lipgloss.TerminalColor{
+		"subtle":    &subtle,
+		"highlight": &highlight,
+		"special":   &special,
+		"alert":     &alert,
+		"accent":    &bubbleTeaColor,
+		"text":      &textColor,
+		"muted":     &mutedText,
+		"bar_text":  &barText,
+		"error":     &errorText,
+		"frame":     &frame,
+		"info":      &info,
+		"success":   &success,
+	}
+	for name, c := range colors {
+		*c = themeColor(theme.Colors[name])
+	}
+	buildStyles()
+
+	for name, override := range theme.Styles {
+		style, ok := styleNames[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		s := *style
+		if override.Foreground != "" {
+			s = s.Foreground(themeColor(override.Foreground))
+		}
+		if override.Background != "" {
+			s = s.Background(themeColor(override.Background))
+		}
+		if override.BorderForeground != "" {
+			s = s.BorderForeground(themeColor(override.BorderForeground))
+		}
+		if override.Bold != nil {
+			s = s.Bold(*override.Bold)
+		}
+		if override.Italic != nil {
+			s = s.Italic(*override.Italic)
+		}
+		if override.Underline != nil {
+			s = s.Underline(*override.Underline)
+		}
+		if override.Reverse != nil {
+			s = s.Reverse(*override.Reverse)
+		}
+		*style = s
+	}
+}
+
+// themeColor converts a theme color; an unset color means the terminal's default
+func themeColor(c string) lipgloss.TerminalColor {
+	if c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
+
+// ThemePicker lists the available themes and previews the one under the cursor
+type ThemePicker struct {
+	names    []string
+	cursor   int
+	original string // theme to go back to when the picker is cancelled
+	dir      string // where theme files are looked up
+}
+
+// NewThemePicker creates a picker with the cursor on the current theme
+func NewThemePicker(names []string, current, dir string) *ThemePicker {
+	tp := &ThemePicker{names: names, original: current, dir: dir}
+	for i, name := range names {
+		if name == current {
+			tp.cursor = i
+		}
+	}
+	return tp
+}
+
+// MoveTo moves the cursor to the i-th theme, clamped to the list
+func (tp *ThemePicker) MoveTo(i int) {
+	if i >= len(tp.names) {
+		i = len(tp.names) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	tp.cursor = i
+}
+
+// Selected returns the theme under the cursor
+func (tp *ThemePicker) Selected() string {
+	if tp.cursor < len(tp.names) {
+		return tp.names[tp.cursor]
+	}
+	return tp.original
+}
+
+// Render renders the theme list
+func (tp *ThemePicker) Render(width, height int) string {
+	var lines []string
+	lines = append(lines, articleTitleStyle.Render("Themes"))
+	for i, name := range tp.names {
+		line := "  " + name
+		if name == tp.original {
+			line += " (current)"
+		}
+		if i == tp.cursor {
+			lines = append(lines, selectedArticleStyle.Render("> "+line[2:]))
+		} else {
+			lines = append(lines, normalArticleStyle.Render(line))
+		}
+	}
+	lines = append(lines, "", articleMetaStyle.Render(fmt.Sprintf("Theme files: %s", filepath.Join(tp.dir, "<name>.yaml"))))
+	return articleViewStyle.Width(width).Height(height).Render(strings.Join(lines, "\n"))
+}
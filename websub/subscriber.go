@@ -0,0 +1,437 @@
+// Package websub implements the subscriber side of WebSub (formerly
+// PubSubHubbub): subscribing at hubs, answering verification requests,
+// checking signed content deliveries and renewing leases before they expire.
+// With a store (see SetStore) verified leases survive restarts; without one
+// every restart subscribes at every hub again.
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JohanLi233/gorss/internal/fsutil"
+)
+
+const (
+	// maxContentSize limits the size of a pushed feed document
+	maxContentSize = 16 << 20
+	// retryInterval is how long to wait before retrying a subscription the hub never verified
+	retryInterval = 10 * time.Minute
+)
+
+// DeliverFunc receives the body of a verified content distribution for a topic
+type DeliverFunc func(topic string, body []byte)
+
+// Subscription is one topic subscribed at a hub
+type Subscription struct {
+	ID        string    // path segment of the callback URL
+	Hub       string    // hub URL
+	Topic     string    // feed URL the hub publishes
+	Verified  bool      // the hub confirmed the subscription
+	Expires   time.Time // end of the lease granted by the hub
+	Requested time.Time // when the last (re)subscription request was sent
+
+	secret string
+	lease  time.Duration
+	mode   string // pending hub.mode awaiting verification
+}
+
+// Subscriber manages WebSub subscriptions and serves the callback endpoint.
+// It implements http.Handler; mount it under the path of CallbackBase.
+type Subscriber struct {
+	callbackBase string
+	secret       string
+	lease        time.Duration
+	deliver      DeliverFunc
+	client       *http.Client
+	Logf         func(format string, args ...any)
+
+	mu    sync.Mutex
+	subs  map[string]*Subscription // keyed by ID
+	store string                   // file the subscriptions are kept in, "" for none
+}
+
+// NewSubscriber creates a subscriber whose callback URLs are callbackBase/<id>.
+// If secret is empty a random secret is generated for every subscription.
+func NewSubscriber(callbackBase, secret string, lease time.Duration, deliver DeliverFunc) *Subscriber {
+	return &Subscriber{
+		callbackBase: strings.TrimSuffix(callbackBase, "/"),
+		secret:       secret,
+		lease:        lease,
+		deliver:      deliver,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		Logf:         func(string, ...any) {},
+		subs:         make(map[string]*Subscription),
+	}
+}
+
+// storedSubscription is a Subscription as kept in the store. The secret is
+// kept too: the hub signs deliveries with the one it was given.
+type storedSubscription struct {
+	ID           string    `json:"id"`
+	Hub          string    `json:"hub"`
+	Topic        string    `json:"topic"`
+	Verified     bool      `json:"verified"`
+	Expires      time.Time `json:"expires"`
+	Requested    time.Time `json:"requested"`
+	Secret       string    `json:"secret"`
+	LeaseSeconds int64     `json:"lease_seconds"`
+}
+
+// SetStore keeps the subscriptions in path, a file only the user can read
+// since it holds the secrets, and loads the ones saved there. Loaded leases
+// count as active until they expire; Run renews them as usual.
+func (s *Subscriber) SetStore(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var stored []storedSubscription
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	for _, st := range stored {
+		s.subs[st.ID] = &Subscription{
+			ID:        st.ID,
+			Hub:       st.Hub,
+			Topic:     st.Topic,
+			Verified:  st.Verified,
+			Expires:   st.Expires,
+			Requested: st.Requested,
+			secret:    st.Secret,
+			lease:     time.Duration(st.LeaseSeconds) * time.Second,
+			mode:      "subscribe",
+		}
+	}
+	return nil
+}
+
+// save writes the subscriptions to the store, if there is one. Callers hold s.mu.
+func (s *Subscriber) save() {
+	if s.store == "" {
+		return
+	}
+	stored := make([]storedSubscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		if sub.mode == "unsubscribe" {
+			continue
+		}
+		stored = append(stored, storedSubscription{
+			ID:           sub.ID,
+			Hub:          sub.Hub,
+			Topic:        sub.Topic,
+			Verified:     sub.Verified,
+			Expires:      sub.Expires,
+			Requested:    sub.Requested,
+			Secret:       sub.secret,
+			LeaseSeconds: int64(sub.lease / time.Second),
+		})
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err == nil {
+		err = fsutil.WriteFile(s.store, data, 0600)
+	}
+	if err != nil {
+		s.Logf("websub: failed to save subscriptions: %v", err)
+	}
+}
+
+// topicID derives a stable callback path segment from a topic URL
+func topicID(topic string) string {
+	sum := sha256.Sum256([]byte(topic))
+	return hex.EncodeToString(sum[:8])
+}
+
+// subscriptionSecret returns the HMAC secret handed to the hub for a topic
+func (s *Subscriber) subscriptionSecret(topic string) (string, error) {
+	if s.secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.secret))
+		mac.Write([]byte(topic))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Subscribe asks a hub to push updates for a topic. The subscription becomes
+// active once the hub calls back to verify it.
+func (s *Subscriber) Subscribe(hub, topic string) error {
+	return s.request("subscribe", hub, topic)
+}
+
+// Unsubscribe asks the hub to stop pushing updates for a topic
+func (s *Subscriber) Unsubscribe(topic string) error {
+	s.mu.Lock()
+	sub, ok := s.subs[topicID(topic)]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return s.request("unsubscribe", sub.Hub, topic)
+}
+
+// request sends a subscribe or unsubscribe request to a hub
+func (s *Subscriber) request(mode, hub, topic string) error {
+	id := topicID(topic)
+
+	s.mu.Lock()
+	sub, ok := s.subs[id]
+	if !ok {
+		secret, err := s.subscriptionSecret(topic)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		sub = &Subscription{ID: id, Hub: hub, Topic: topic, secret: secret, lease: s.lease}
+		s.subs[id] = sub
+	}
+	sub.Hub = hub
+	sub.mode = mode
+	sub.Requested = time.Now()
+	secret := sub.secret
+	s.mu.Unlock()
+
+	form := url.Values{}
+	form.Set("hub.mode", mode)
+	form.Set("hub.topic", topic)
+	form.Set("hub.callback", s.callbackBase+"/"+id)
+	if mode == "subscribe" {
+		form.Set("hub.secret", secret)
+		if s.lease > 0 {
+			form.Set("hub.lease_seconds", strconv.Itoa(int(s.lease.Seconds())))
+		}
+	}
+
+	resp, err := s.client.PostForm(hub, form)
+	if err != nil {
+		return fmt.Errorf("%s request to hub %s failed: %w", mode, hub, err)
+	}
+	defer resp.Body.Close()
+
+	// Hubs answer 202 Accepted and verify asynchronously
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub %s rejected %s for %s: %s %s", hub, mode, topic, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Active reports whether a topic has a verified, unexpired subscription
+func (s *Subscriber) Active(topic string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subs[topicID(topic)]
+	return ok && sub.Verified && time.Now().Before(sub.Expires)
+}
+
+// Subscriptions returns a snapshot of all known subscriptions
+func (s *Subscriber) Subscriptions() []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]Subscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		result = append(result, *sub)
+	}
+	return result
+}
+
+// Run renews subscriptions before their lease runs out and retries
+// subscriptions that were never verified. It returns when ctx is done.
+func (s *Subscriber) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, sub := range s.dueForRenewal(now) {
+				if err := s.Subscribe(sub.Hub, sub.Topic); err != nil {
+					s.Logf("websub: renewing %s: %v", sub.Topic, err)
+				}
+			}
+		}
+	}
+}
+
+// dueForRenewal lists subscriptions whose lease is in its last tenth
+// (or last five minutes) and subscriptions stuck waiting for verification
+func (s *Subscriber) dueForRenewal(now time.Time) []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []Subscription
+	for _, sub := range s.subs {
+		if sub.mode == "unsubscribe" {
+			continue
+		}
+		if !sub.Verified {
+			if now.Sub(sub.Requested) > retryInterval {
+				due = append(due, *sub)
+			}
+			continue
+		}
+		margin := sub.lease / 10
+		if margin < 5*time.Minute {
+			margin = 5 * time.Minute
+		}
+		if now.Add(margin).After(sub.Expires) && now.Sub(sub.Requested) > time.Minute {
+			due = append(due, *sub)
+		}
+	}
+	return due
+}
+
+// ServeHTTP handles hub verification (GET) and content distribution (POST)
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	s.mu.Lock()
+	sub, ok := s.subs[id]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.verify(w, r, sub)
+	case http.MethodPost:
+		s.receive(w, r, sub)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify answers the hub's intent verification or denial notification
+func (s *Subscriber) verify(w http.ResponseWriter, r *http.Request, sub *Subscription) {
+	q := r.URL.Query()
+	mode := q.Get("hub.mode")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if q.Get("hub.topic") != sub.Topic {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch mode {
+	case "denied":
+		sub.Verified = false
+		s.save()
+		s.Logf("websub: hub %s denied subscription to %s: %s", sub.Hub, sub.Topic, q.Get("hub.reason"))
+		w.WriteHeader(http.StatusOK)
+		return
+	case "subscribe", "unsubscribe":
+		if mode != sub.mode {
+			http.NotFound(w, r)
+			return
+		}
+	default:
+		http.Error(w, "unknown hub.mode", http.StatusBadRequest)
+		return
+	}
+
+	if mode == "subscribe" {
+		lease := sub.lease
+		if secs, err := strconv.Atoi(q.Get("hub.lease_seconds")); err == nil && secs > 0 {
+			lease = time.Duration(secs) * time.Second
+		}
+		sub.lease = lease
+		sub.Verified = true
+		sub.Expires = time.Now().Add(lease)
+		s.Logf("websub: subscribed to %s via %s (lease %s)", sub.Topic, sub.Hub, lease)
+	} else {
+		delete(s.subs, sub.ID)
+		s.Logf("websub: unsubscribed from %s", sub.Topic)
+	}
+	s.save()
+
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, q.Get("hub.challenge"))
+}
+
+// receive accepts a content distribution, checking its HMAC signature
+func (s *Subscriber) receive(w http.ResponseWriter, r *http.Request, sub *Subscription) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxContentSize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	secret := sub.secret
+	topic := sub.Topic
+	s.mu.Unlock()
+
+	// Per the spec the hub still gets a 2xx for bad signatures, but the content is dropped
+	w.WriteHeader(http.StatusAccepted)
+
+	if !validSignature(r.Header.Get("X-Hub-Signature"), secret, body) {
+		s.Logf("websub: dropping delivery for %s with missing or invalid signature", topic)
+		return
+	}
+	s.deliver(topic, body)
+}
+
+// validSignature checks an X-Hub-Signature header of the form "method=hexdigest"
+func validSignature(header, secret string, body []byte) bool {
+	method, digest, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+
+	var h func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
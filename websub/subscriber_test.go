@@ -0,0 +1,366 @@
+package websub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testTopic = "https://example.com/feed.xml"
+
+// testHub is a stand-in hub: it records subscription requests and verifies
+// intent by calling the callback with a challenge before answering 202, as a
+// synchronous hub would
+type testHub struct {
+	t      *testing.T
+	server *httptest.Server
+	lease  string // hub.lease_seconds granted on verification, "" to leave it out
+
+	mu       sync.Mutex
+	requests []url.Values
+	secret   string // hub.secret of the last subscribe request
+	callback string
+}
+
+func newTestHub(t *testing.T) *testHub {
+	h := &testHub{t: t}
+	h.server = httptest.NewServer(http.HandlerFunc(h.serve))
+	t.Cleanup(h.server.Close)
+	return h
+}
+
+func (h *testHub) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.mu.Lock()
+	h.requests = append(h.requests, r.PostForm)
+	h.callback = r.PostForm.Get("hub.callback")
+	if r.PostForm.Get("hub.mode") == "subscribe" {
+		h.secret = r.PostForm.Get("hub.secret")
+	}
+	h.mu.Unlock()
+
+	q := url.Values{
+		"hub.mode":      {r.PostForm.Get("hub.mode")},
+		"hub.topic":     {r.PostForm.Get("hub.topic")},
+		"hub.challenge": {"challenge-123"},
+	}
+	if h.lease != "" {
+		q.Set("hub.lease_seconds", h.lease)
+	}
+	resp, err := http.Get(h.callback + "?" + q.Encode())
+	if err != nil {
+		h.t.Errorf("verification request: %v", err)
+		return
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "challenge-123" {
+		h.t.Errorf("verification answered %d %q, want 200 with the challenge", resp.StatusCode, body)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// deliver posts content to the callback signed with secret
+func (h *testHub) deliver(t *testing.T, secret string, body string) {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	req, _ := http.NewRequest(http.MethodPost, h.callback, strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("delivery answered %d, want 202", resp.StatusCode)
+	}
+}
+
+// deliveries collects what a Subscriber delivers
+type deliveries struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (d *deliveries) deliver(topic string, body []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.bodies = append(d.bodies, topic+" "+string(body))
+}
+
+func (d *deliveries) got() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.bodies...)
+}
+
+// newTestSubscriber creates a subscriber with its callbacks served by an httptest server
+func newTestSubscriber(t *testing.T, secret string, d *deliveries) *Subscriber {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	sub := NewSubscriber(server.URL+"/websub", secret, time.Hour, d.deliver)
+	mux.Handle("/websub/", sub)
+	return sub
+}
+
+func TestSubscribeVerifiesIntent(t *testing.T) {
+	hub := newTestHub(t)
+	hub.lease = "600"
+	sub := newTestSubscriber(t, "", &deliveries{})
+
+	if sub.Active(testTopic) {
+		t.Fatal("active before subscribing")
+	}
+	if err := sub.Subscribe(hub.server.URL, testTopic); err != nil {
+		t.Fatal(err)
+	}
+	if !sub.Active(testTopic) {
+		t.Fatal("not active after the hub verified the subscription")
+	}
+
+	req := hub.requests[0]
+	if req.Get("hub.mode") != "subscribe" || req.Get("hub.topic") != testTopic {
+		t.Errorf("request = %v", req)
+	}
+	if req.Get("hub.lease_seconds") != "3600" {
+		t.Errorf("hub.lease_seconds = %q, want 3600", req.Get("hub.lease_seconds"))
+	}
+	if req.Get("hub.secret") == "" {
+		t.Error("no hub.secret sent")
+	}
+
+	// The lease the hub grants wins over the requested one
+	subs := sub.Subscriptions()
+	if len(subs) != 1 {
+		t.Fatalf("%d subscriptions, want 1", len(subs))
+	}
+	if left := time.Until(subs[0].Expires); left > 10*time.Minute || left < 9*time.Minute {
+		t.Errorf("lease expires in %s, want the granted 10m", left)
+	}
+}
+
+func TestVerifyRejectsUnknownIntent(t *testing.T) {
+	hub := newTestHub(t)
+	sub := newTestSubscriber(t, "", &deliveries{})
+	if err := sub.Subscribe(hub.server.URL, testTopic); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query url.Values
+		code  int
+	}{
+		{"wrong topic", url.Values{"hub.mode": {"subscribe"}, "hub.topic": {"https://other.example/"}, "hub.challenge": {"x"}}, http.StatusNotFound},
+		{"mode not requested", url.Values{"hub.mode": {"unsubscribe"}, "hub.topic": {testTopic}, "hub.challenge": {"x"}}, http.StatusNotFound},
+		{"unknown mode", url.Values{"hub.mode": {"bogus"}, "hub.topic": {testTopic}, "hub.challenge": {"x"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(hub.callback + "?" + tt.query.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.code)
+			}
+		})
+	}
+
+	resp, err := http.Get(strings.TrimSuffix(hub.callback, "/"+topicID(testTopic)) + "/unknown?hub.mode=subscribe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown callback: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestDeliverySignature(t *testing.T) {
+	hub := newTestHub(t)
+	d := &deliveries{}
+	sub := newTestSubscriber(t, "configured secret", d)
+	if err := sub.Subscribe(hub.server.URL, testTopic); err != nil {
+		t.Fatal(err)
+	}
+
+	hub.deliver(t, hub.secret, "<feed>signed</feed>")
+	hub.deliver(t, "wrong secret", "<feed>forged</feed>")
+
+	req, _ := http.NewRequest(http.MethodPost, hub.callback, strings.NewReader("<feed>unsigned</feed>"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("unsigned delivery answered %d, want 202", resp.StatusCode)
+	}
+
+	got := d.got()
+	if len(got) != 1 || got[0] != testTopic+" <feed>signed</feed>" {
+		t.Errorf("delivered %q, want only the signed content", got)
+	}
+}
+
+func TestValidSignature(t *testing.T) {
+	body := []byte("content")
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"sha256=" + sign("s"), true},
+		{"SHA256=" + sign("s"), true},
+		{"sha256=" + sign("other"), false},
+		{"sha256=zz", false},
+		{"md5=" + sign("s"), false},
+		{sign("s"), false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validSignature(tt.header, "s", body); got != tt.want {
+			t.Errorf("validSignature(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestDueForRenewal(t *testing.T) {
+	sub := NewSubscriber("http://localhost/websub", "", 24*time.Hour, nil)
+	now := time.Now()
+	add := func(topic string, verified bool, requested, expires time.Time) {
+		id := topicID(topic)
+		sub.subs[id] = &Subscription{ID: id, Topic: topic, Verified: verified, Requested: requested, Expires: expires, lease: 24 * time.Hour, mode: "subscribe"}
+	}
+	add("fresh", true, now.Add(-time.Hour), now.Add(23*time.Hour))
+	add("expiring", true, now.Add(-22*time.Hour), now.Add(2*time.Hour))     // inside the last tenth
+	add("expired", true, now.Add(-25*time.Hour), now.Add(-time.Hour))       // lapsed, e.g. while stopped
+	add("just renewed", true, now.Add(-10*time.Second), now.Add(time.Hour)) // renewal in flight
+	add("pending", false, now.Add(-time.Minute), time.Time{})
+	add("stuck", false, now.Add(-retryInterval-time.Minute), time.Time{})
+	sub.subs[topicID("leaving")] = &Subscription{ID: topicID("leaving"), Topic: "leaving", Verified: true, Expires: now, mode: "unsubscribe"}
+
+	due := make(map[string]bool)
+	for _, s := range sub.dueForRenewal(now) {
+		due[s.Topic] = true
+	}
+	for topic, want := range map[string]bool{
+		"fresh": false, "expiring": true, "expired": true, "just renewed": false,
+		"pending": false, "stuck": true, "leaving": false,
+	} {
+		if due[topic] != want {
+			t.Errorf("%s: due = %v, want %v", topic, due[topic], want)
+		}
+	}
+}
+
+// TestPollingFallback checks that Active, which decides whether the daemon
+// still polls a feed, turns false when the hub denies or the lease runs out
+func TestPollingFallback(t *testing.T) {
+	hub := newTestHub(t)
+	sub := newTestSubscriber(t, "", &deliveries{})
+	if err := sub.Subscribe(hub.server.URL, testTopic); err != nil {
+		t.Fatal(err)
+	}
+	if !sub.Active(testTopic) {
+		t.Fatal("not active after verification")
+	}
+
+	sub.mu.Lock()
+	sub.subs[topicID(testTopic)].Expires = time.Now().Add(-time.Second)
+	sub.mu.Unlock()
+	if sub.Active(testTopic) {
+		t.Error("active after the lease expired")
+	}
+
+	if err := sub.Subscribe(hub.server.URL, testTopic); err != nil {
+		t.Fatal(err)
+	}
+	q := url.Values{"hub.mode": {"denied"}, "hub.topic": {testTopic}, "hub.reason": {"no"}}
+	resp, err := http.Get(hub.callback + "?" + q.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if sub.Active(testTopic) {
+		t.Error("active after the hub denied the subscription")
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	hub := newTestHub(t)
+	sub := newTestSubscriber(t, "", &deliveries{})
+	if err := sub.Subscribe(hub.server.URL, testTopic); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Unsubscribe(testTopic); err != nil {
+		t.Fatal(err)
+	}
+	if sub.Active(testTopic) || len(sub.Subscriptions()) != 0 {
+		t.Error("subscription kept after unsubscribing")
+	}
+	if got := hub.requests[1].Get("hub.mode"); got != "unsubscribe" {
+		t.Errorf("hub.mode = %q, want unsubscribe", got)
+	}
+}
+
+func TestStoreKeepsLeases(t *testing.T) {
+	hub := newTestHub(t)
+	store := filepath.Join(t.TempDir(), "websub.json")
+
+	first := newTestSubscriber(t, "", &deliveries{})
+	if err := first.SetStore(store); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Subscribe(hub.server.URL, testTopic); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart the lease is active without asking the hub again, and
+	// deliveries signed with the random secret the hub was given still pass
+	d := &deliveries{}
+	second := newTestSubscriber(t, "", d)
+	if err := second.SetStore(store); err != nil {
+		t.Fatal(err)
+	}
+	if !second.Active(testTopic) {
+		t.Fatal("stored lease not active after a restart")
+	}
+	if due := second.dueForRenewal(time.Now()); len(due) != 0 {
+		t.Errorf("%d subscriptions due right after a restart", len(due))
+	}
+	callback := strings.Replace(hub.callback, hubHost(hub.callback), hubHost(second.callbackBase), 1)
+	hub.callback = callback
+	hub.deliver(t, hub.secret, "<feed/>")
+	if got := d.got(); len(got) != 1 {
+		t.Errorf("delivered %q after a restart, want the signed content", got)
+	}
+	if len(hub.requests) != 1 {
+		t.Errorf("hub got %d requests, want 1", len(hub.requests))
+	}
+}
+
+func hubHost(u string) string {
+	parsed, _ := url.Parse(u)
+	return parsed.Host
+}